@@ -0,0 +1,81 @@
+package helpers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/buildlogs"
+	"github.com/jenkins-x/bdd-jx/test/utils/runner"
+	"github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// GetBuildLog waits for the given build of the job to complete and returns its log, split into the steps of its
+// stages. The latest build is used if the build number is 0.
+func (t *TestOptions) GetBuildLog(jobName string, buildNumber int, maxDuration time.Duration) (*buildlogs.Log, error) {
+	args := []string{"get", "build", "logs", "--wait", jobName}
+	if buildNumber != 0 {
+		args = append(args, "--build", strconv.Itoa(buildNumber))
+	}
+	argsStr := strings.Join(args, " ")
+	var out string
+	var err error
+	By(fmt.Sprintf("capturing the build log by calling jx %s", argsStr), func() {
+		out, err = runner.New(t.WorkDir, &maxDuration, 0).RunWithBufferedOutput(args...)
+	})
+	log := buildlogs.Parse(jobName, buildNumber, out)
+	if err != nil {
+		t.AttachBuildLog(log)
+		return nil, errors.Wrapf(err, "getting the build log of %s", jobName)
+	}
+	return log, nil
+}
+
+// ExpectBuildLog waits for the given build of the job to complete and asserts that its log meets the expectation,
+// attaching the log to the report if it does not. The log is checked for DefaultForbidden patterns if the expectation
+// is nil.
+func (t *TestOptions) ExpectBuildLog(jobName string, buildNumber int, expect *buildlogs.Expectation) *buildlogs.Log {
+	log, err := t.GetBuildLog(jobName, buildNumber, TimeoutBuildCompletes)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	t.VerifyBuildLog(log, expect)
+	return log
+}
+
+// VerifyBuildLog asserts that a log which has already been captured meets the expectation, attaching the log to the
+// report if it does not
+func (t *TestOptions) VerifyBuildLog(log *buildlogs.Log, expect *buildlogs.Expectation) {
+	By(fmt.Sprintf("checking the build log of build %d of %s", log.Build, log.Job), func() {
+		err := log.Verify(expect)
+		if err != nil {
+			t.AttachBuildLog(log)
+		}
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+}
+
+// AttachBuildLog attaches the build log to the report of the current spec, writing it to the output Ginkgo reports
+// for failed specs and to a file in the reports directory
+func (t *TestOptions) AttachBuildLog(log *buildlogs.Log) {
+	text := log.String()
+	_, _ = fmt.Fprintf(GinkgoWriter, "\n%s\n", text)
+
+	fileName := filepath.Join(ReportsDir(), "build-logs", fmt.Sprintf("%s-%d.log", strings.ReplaceAll(log.Job, "/", "-"), log.Build))
+	err := os.MkdirAll(filepath.Dir(fileName), 0700)
+	if err == nil {
+		err = ioutil.WriteFile(fileName, []byte(text), utils.DefaultWritePermissions)
+	}
+	if err != nil {
+		utils.LogInfof("WARNING: failed to write the build log to %s: %s\n", fileName, err)
+		return
+	}
+	utils.LogInfof("wrote the build log of build %d of %s to %s\n", log.Build, log.Job, utils.ColorInfo(fileName))
+}
@@ -0,0 +1,32 @@
+package helpers
+
+import (
+	"os"
+	"strings"
+
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/charts"
+)
+
+// StartChartRepository serves a Helm chart repository on ChartRepositoryAddress holding each chart in the given
+// fixture directory, packaged once in each of the given versions. The caller must Close the returned server.
+func StartChartRepository(fixtureDir string, versions ...string) (*charts.Server, error) {
+	server, err := charts.Serve(ChartRepositoryAddress, ChartRepositoryURL)
+	if err != nil {
+		return nil, err
+	}
+	err = server.AddAll(fixtureDir, versions...)
+	if err != nil {
+		_ = server.Close()
+		return nil, err
+	}
+	utils.LogInfof("serving charts %s in versions %s on %s\n", strings.Join(server.Charts(), ", "), strings.Join(versions, ", "), utils.ColorInfo(server.URL))
+	return server, nil
+}
+
+// ChartRepositoryConfigured returns true if BDD_CHART_REPOSITORY_URL or BDD_CHART_REPOSITORY_ADDRESS is set. The
+// default address is only reachable from the host running the tests, so specs installing the fixture charts in the
+// cluster need one of them to be set explicitly.
+func ChartRepositoryConfigured() bool {
+	return ChartRepositoryURL != "" || os.Getenv("BDD_CHART_REPOSITORY_ADDRESS") != ""
+}
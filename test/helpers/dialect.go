@@ -0,0 +1,198 @@
+package helpers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+)
+
+// ProviderDialect describes the ways in which a git provider differs when driving Lighthouse through ChatOps, so that
+// specs can ask the dialect rather than switching on the provider kind inline.
+type ProviderDialect struct {
+	// Kind is the git provider kind this dialect applies to
+	Kind string
+	// CommandPrefix is prepended to ChatOps commands which the provider handles itself
+	CommandPrefix string
+	// HijackedCommands are the ChatOps commands which the provider intercepts unless prefixed with CommandPrefix
+	HijackedCommands []string
+	// HijackedFeatures are the Lighthouse features which the provider intercepts and does not send webhooks for
+	HijackedFeatures []string
+	// NewestStatusLast is true if the provider lists commit statuses oldest first
+	NewestStatusLast bool
+	// InvitationsRequireAcceptance is true if a new collaborator has to accept an invitation before they can act on a repo
+	InvitationsRequireAcceptance bool
+	// SupportsReviewRequests is true if /cc and /uncc result in reviewers or assignees being updated on the PR
+	SupportsReviewRequests bool
+	// IssueCommands are the ChatOps commands on issues which are tested against the provider
+	IssueCommands []string
+	// SupportsLabelsAPI is true if labels can be added to pull requests through the git provider API
+	SupportsLabelsAPI bool
+	// KeeperStatusOmitsReason is true if keeper leaves the reason out of its "Not mergeable." status description
+	KeeperStatusOmitsReason bool
+	// WebhookDriver is the name of the go-scm driver which parses the webhooks the provider sends, which
+	// NewWebhookVerifier resolves
+	WebhookDriver string
+	// PullRequestCommentWebhook is the kind of webhook the provider sends for a comment on a pull request
+	PullRequestCommentWebhook scm.WebhookKind
+	// MergeDelay is how long to wait after creating a pull request before it can be merged through the API
+	MergeDelay time.Duration
+
+	blobURL        func(serverURL, owner, repo, branch, path string) string
+	pullRequestURL func(serverURL, owner, repo string, number int) string
+}
+
+const (
+	// FeatureWIPTitle is the Lighthouse feature of labelling a pull request as work in progress from its title
+	FeatureWIPTitle = "wip-title"
+
+	// collaboratorExistsMessage is the error text GitLab returns when adding a user who is already a member. It is
+	// checked whatever the provider, as no other provider returns it for any other reason.
+	collaboratorExistsMessage = "Member already exists"
+)
+
+var (
+	githubDialect = &ProviderDialect{
+		Kind:                         gits.KindGitHub,
+		InvitationsRequireAcceptance: true,
+		SupportsReviewRequests:       true,
+		IssueCommands:                []string{"assign", "unassign", "kind", "priority", "lifecycle", "close", "reopen"},
+		SupportsLabelsAPI:            true,
+		WebhookDriver:                "github",
+		PullRequestCommentWebhook:    scm.WebhookKindIssueComment,
+		blobURL: func(serverURL, owner, repo, branch, path string) string {
+			return fmt.Sprintf("%s/%s/%s/blob/%s/%s", serverURL, owner, repo, branch, path)
+		},
+		pullRequestURL: func(serverURL, owner, repo string, number int) string {
+			return fmt.Sprintf("%s/%s/%s/pull/%d", serverURL, owner, repo, number)
+		},
+	}
+
+	providerDialects = map[string]*ProviderDialect{
+		gits.KindGitHub: githubDialect,
+		gits.KindGitlab: {
+			Kind:          gits.KindGitlab,
+			CommandPrefix: "lh-",
+			// the quick actions of GitLab, which Lighthouse also accepts with the lh- prefix
+			HijackedCommands:             []string{"approve", "assign", "unassign", "close", "reopen", "label", "unlabel", "lock", "unlock"},
+			HijackedFeatures:             []string{FeatureWIPTitle},
+			NewestStatusLast:             true,
+			InvitationsRequireAcceptance: true,
+			SupportsReviewRequests:       true,
+			// the git provider does not report the assignees of issues
+			IssueCommands:             []string{"kind", "priority", "lifecycle", "close", "reopen"},
+			SupportsLabelsAPI:         true,
+			KeeperStatusOmitsReason:   true,
+			WebhookDriver:             "gitlab",
+			PullRequestCommentWebhook: scm.WebhookKindPullRequestComment,
+			MergeDelay:                30 * time.Second,
+			blobURL: func(serverURL, owner, repo, branch, path string) string {
+				return fmt.Sprintf("%s/%s/%s/-/blob/%s/%s", serverURL, owner, repo, branch, path)
+			},
+			pullRequestURL: func(serverURL, owner, repo string, number int) string {
+				return fmt.Sprintf("%s/%s/%s/-/merge_requests/%d", serverURL, owner, repo, number)
+			},
+		},
+		gits.KindBitBucketServer: {
+			Kind:                      gits.KindBitBucketServer,
+			WebhookDriver:             "stash",
+			PullRequestCommentWebhook: scm.WebhookKindPullRequestComment,
+			blobURL: func(serverURL, owner, repo, branch, path string) string {
+				// Bitbucket Server browses the default branch unless told otherwise
+				u := fmt.Sprintf("%s/projects/%s/repos/%s/browse/%s", serverURL, strings.ToUpper(owner), repo, path)
+				if branch != "master" {
+					u += "?at=" + url.QueryEscape("refs/heads/"+branch)
+				}
+				return u
+			},
+			pullRequestURL: func(serverURL, owner, repo string, number int) string {
+				return fmt.Sprintf("%s/projects/%s/repos/%s/pull-requests/%d", serverURL, strings.ToUpper(owner), repo, number)
+			},
+		},
+	}
+)
+
+// DialectForProvider returns the dialect for the given git provider
+func DialectForProvider(provider gits.GitProvider) *ProviderDialect {
+	return DialectForKind(provider.Kind())
+}
+
+// DialectForKind returns the dialect for the given git provider kind, falling back to GitHub for unknown kinds
+func DialectForKind(kind string) *ProviderDialect {
+	if d, ok := providerDialects[kind]; ok {
+		return d
+	}
+	return githubDialect
+}
+
+// Command returns the ChatOps comment for the given command and arguments, prefixed if the provider hijacks it
+func (d *ProviderDialect) Command(name string, args ...string) string {
+	for _, c := range d.HijackedCommands {
+		if c == name {
+			name = d.CommandPrefix + name
+			break
+		}
+	}
+	return strings.TrimSpace(fmt.Sprintf("/%s %s", name, strings.Join(args, " ")))
+}
+
+// HijacksFeature returns true if the provider intercepts the given Lighthouse feature
+func (d *ProviderDialect) HijacksFeature(feature string) bool {
+	for _, f := range d.HijackedFeatures {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// BlobURL returns the URL at which the provider displays a file on a branch
+func (d *ProviderDialect) BlobURL(serverURL, owner, repo, branch, path string) string {
+	return d.blobURL(strings.TrimSuffix(serverURL, "/"), owner, repo, branch, path)
+}
+
+// OwnersURL returns the URL that Lighthouse links to for the OWNERS file in the root of a repo
+func (d *ProviderDialect) OwnersURL(serverURL, owner, repo string) string {
+	return d.BlobURL(serverURL, owner, repo, "master", "OWNERS")
+}
+
+// PullRequestURL returns the web URL of a pull request
+func (d *ProviderDialect) PullRequestURL(serverURL, owner, repo string, number int) string {
+	return d.pullRequestURL(strings.TrimSuffix(serverURL, "/"), owner, repo, number)
+}
+
+// NewestStatusesFirst returns the given commit statuses ordered newest first
+func (d *ProviderDialect) NewestStatusesFirst(statuses []*gits.GitRepoStatus) []*gits.GitRepoStatus {
+	var ordered []*gits.GitRepoStatus
+	if d.NewestStatusLast {
+		for i := len(statuses) - 1; i >= 0; i-- {
+			ordered = append(ordered, statuses[i])
+		}
+	} else {
+		ordered = append(ordered, statuses...)
+	}
+	return ordered
+}
+
+// SupportsIssueCommand returns true if the given ChatOps command on issues is tested against the provider
+func (d *ProviderDialect) SupportsIssueCommand(name string) bool {
+	for _, c := range d.IssueCommands {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsIssues returns true if any ChatOps commands on issues are tested against the provider
+func (d *ProviderDialect) SupportsIssues() bool {
+	return len(d.IssueCommands) > 0
+}
+
+// IsCollaboratorExistsError returns true if the error from adding a collaborator means the user already is one
+func IsCollaboratorExistsError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), collaboratorExistsMessage)
+}
@@ -0,0 +1,109 @@
+package helpers_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/helpers"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialectForKind(t *testing.T) {
+	assert.Equal(t, gits.KindGitHub, helpers.DialectForKind(gits.KindGitHub).Kind)
+	assert.Equal(t, gits.KindGitlab, helpers.DialectForKind(gits.KindGitlab).Kind)
+	assert.Equal(t, gits.KindBitBucketServer, helpers.DialectForKind(gits.KindBitBucketServer).Kind)
+	assert.Equal(t, gits.KindGitHub, helpers.DialectForKind("gitea").Kind, "unknown kinds fall back to GitHub")
+}
+
+func TestCommand(t *testing.T) {
+	github := helpers.DialectForKind(gits.KindGitHub)
+	gitlab := helpers.DialectForKind(gits.KindGitlab)
+
+	assert.Equal(t, "/approve", github.Command("approve"))
+	assert.Equal(t, "/assign bdd-bot", github.Command("assign", "bdd-bot"))
+	assert.Equal(t, "/lh-approve", gitlab.Command("approve"))
+	assert.Equal(t, "/lh-assign bdd-bot", gitlab.Command("assign", "bdd-bot"))
+	assert.Equal(t, "/lh-close", gitlab.Command("close"))
+	assert.Equal(t, "/kind bug", gitlab.Command("kind", "bug"), "commands GitLab does not hijack are not prefixed")
+	assert.Equal(t, "/retest", helpers.DialectForKind(gits.KindBitBucketServer).Command("retest"))
+}
+
+func TestHijacksFeature(t *testing.T) {
+	assert.True(t, helpers.DialectForKind(gits.KindGitlab).HijacksFeature(helpers.FeatureWIPTitle))
+	assert.False(t, helpers.DialectForKind(gits.KindGitHub).HijacksFeature(helpers.FeatureWIPTitle))
+}
+
+func TestInvitationsRequireAcceptance(t *testing.T) {
+	assert.True(t, helpers.DialectForKind(gits.KindGitHub).InvitationsRequireAcceptance)
+	assert.True(t, helpers.DialectForKind(gits.KindGitlab).InvitationsRequireAcceptance)
+	assert.False(t, helpers.DialectForKind(gits.KindBitBucketServer).InvitationsRequireAcceptance)
+}
+
+func TestIsCollaboratorExistsError(t *testing.T) {
+	exists := errors.New(`POST https://gitlab.com/api/v4/projects/1/members: 409 {message: Member already exists}`)
+	assert.True(t, helpers.IsCollaboratorExistsError(exists))
+	assert.False(t, helpers.IsCollaboratorExistsError(errors.New("403 Forbidden")))
+	assert.False(t, helpers.IsCollaboratorExistsError(nil))
+}
+
+func TestURLs(t *testing.T) {
+	tests := []struct {
+		kind           string
+		serverURL      string
+		owners         string
+		blob           string
+		pullRequestURL string
+	}{
+		{
+			kind:           gits.KindGitHub,
+			serverURL:      "https://github.com/",
+			owners:         "https://github.com/jenkins-x-tests/bdd-app/blob/master/OWNERS",
+			blob:           "https://github.com/jenkins-x-tests/bdd-app/blob/feature/a/OWNERS",
+			pullRequestURL: "https://github.com/jenkins-x-tests/bdd-app/pull/3",
+		},
+		{
+			kind:           gits.KindGitlab,
+			serverURL:      "https://gitlab.com",
+			owners:         "https://gitlab.com/jenkins-x-tests/bdd-app/-/blob/master/OWNERS",
+			blob:           "https://gitlab.com/jenkins-x-tests/bdd-app/-/blob/feature/a/OWNERS",
+			pullRequestURL: "https://gitlab.com/jenkins-x-tests/bdd-app/-/merge_requests/3",
+		},
+		{
+			kind:           gits.KindBitBucketServer,
+			serverURL:      "https://bitbucket.example.com",
+			owners:         "https://bitbucket.example.com/projects/JENKINS-X-TESTS/repos/bdd-app/browse/OWNERS",
+			blob:           "https://bitbucket.example.com/projects/JENKINS-X-TESTS/repos/bdd-app/browse/OWNERS?at=refs%2Fheads%2Ffeature%2Fa",
+			pullRequestURL: "https://bitbucket.example.com/projects/JENKINS-X-TESTS/repos/bdd-app/pull-requests/3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			d := helpers.DialectForKind(tt.kind)
+			assert.Equal(t, tt.owners, d.OwnersURL(tt.serverURL, "jenkins-x-tests", "bdd-app"))
+			assert.Equal(t, tt.blob, d.BlobURL(tt.serverURL, "jenkins-x-tests", "bdd-app", "feature/a", "OWNERS"))
+			assert.Equal(t, tt.pullRequestURL, d.PullRequestURL(tt.serverURL, "jenkins-x-tests", "bdd-app", 3))
+		})
+	}
+}
+
+func TestNewestStatusesFirst(t *testing.T) {
+	older := &gits.GitRepoStatus{Context: "pr-build", State: "pending"}
+	newer := &gits.GitRepoStatus{Context: "pr-build", State: "success"}
+
+	assert.Equal(t, []*gits.GitRepoStatus{newer, older}, helpers.DialectForKind(gits.KindGitHub).NewestStatusesFirst([]*gits.GitRepoStatus{newer, older}))
+	assert.Equal(t, []*gits.GitRepoStatus{newer, older}, helpers.DialectForKind(gits.KindGitlab).NewestStatusesFirst([]*gits.GitRepoStatus{older, newer}))
+	assert.Empty(t, helpers.DialectForKind(gits.KindGitlab).NewestStatusesFirst(nil))
+}
+
+func TestSupportsIssueCommand(t *testing.T) {
+	github := helpers.DialectForKind(gits.KindGitHub)
+	gitlab := helpers.DialectForKind(gits.KindGitlab)
+	bitbucket := helpers.DialectForKind(gits.KindBitBucketServer)
+
+	assert.True(t, github.SupportsIssueCommand("assign"))
+	assert.False(t, gitlab.SupportsIssueCommand("assign"))
+	assert.True(t, gitlab.SupportsIssueCommand("close"))
+	assert.True(t, gitlab.SupportsIssues())
+	assert.False(t, bitbucket.SupportsIssues())
+}
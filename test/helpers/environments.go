@@ -0,0 +1,139 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/parsers"
+	"github.com/jenkins-x/bdd-jx/test/utils/promotion"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+	"github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// ExpectDevEnvironmentPullRequest finds the pull request a jx command opened on the dev environment repository of a
+// GitOps cluster in the output of the command, and checks the changes it makes to env/requirements.yaml with the
+// verify function. The pull request is approved if there is an approver, otherwise the command must have been run
+// with --auto-merge. Once it merges, the given build of the dev environment pipeline it triggers must succeed.
+func (t *TestOptions) ExpectDevEnvironmentPullRequest(devRepo *gits.GitRepository, build string, output string, verify func(*promotion.RequirementsDiff) error) {
+	createdPR, err := parsers.ParseJxCreatePullRequestFromFullLog(output)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	provider, err := t.GetGitProvider()
+	Expect(err).ShouldNot(HaveOccurred())
+
+	By(fmt.Sprintf("checking the changes pull request %s makes to %s", createdPR.Url, promotion.RequirementsFile), func() {
+		diff, err := t.RequirementsDiffOfPullRequest(provider, createdPR.Owner, createdPR.Repository, createdPR.PullRequestNumber)
+		Expect(err).ShouldNot(HaveOccurred())
+		utils.LogInfof("pull request %s %s\n", createdPR.Url, diff)
+		Expect(verify(diff)).Should(Succeed())
+	})
+
+	if PullRequestApproverUsername != "" {
+		approverProvider, err := t.GetApproverGitProvider()
+		Expect(err).ShouldNot(HaveOccurred())
+		t.ApprovePullRequestFromLogOutput(provider, approverProvider, devRepo, output)
+	}
+	By(fmt.Sprintf("waiting for pull request %s to merge", createdPR.Url), func() {
+		t.WaitForPullRequestToMerge(provider, createdPR.Owner, createdPR.Repository, createdPR.PullRequestNumber, createdPR.Url)
+	})
+
+	buildNumber, err := strconv.Atoi(build)
+	Expect(err).ShouldNot(HaveOccurred(), "parsing build number %s", build)
+	t.ExpectBuildLog(fmt.Sprintf("%s/%s/master", devRepo.Organisation, devRepo.Name), buildNumber, nil)
+}
+
+// RequirementsDiffOfPullRequest returns the changes the given pull request makes to the env/requirements.yaml of an
+// environment repository
+func (t *TestOptions) RequirementsDiffOfPullRequest(provider gits.GitProvider, owner string, repo string, number int) (*promotion.RequirementsDiff, error) {
+	scmClient, _, err := t.GetLighthouseSCMClient(provider)
+	if err != nil {
+		return nil, err
+	}
+	fullName := scm.Join(owner, repo)
+	pr, _, err := scmClient.PullRequests.Find(context.Background(), fullName, number)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting pull request %d of %s", number, fullName)
+	}
+	base := pr.Base.Sha
+	if base == "" {
+		base = pr.Base.Ref
+	}
+	head := pr.Head.Sha
+	if head == "" {
+		head = pr.Sha
+	}
+	before, err := readRequirements(scmClient, fullName, base)
+	if err != nil {
+		return nil, err
+	}
+	after, err := readRequirements(scmClient, fullName, head)
+	if err != nil {
+		return nil, err
+	}
+	return promotion.DiffRequirements(before, after), nil
+}
+
+// readRequirements reads the env/requirements.yaml of a repository at the given ref, which is empty if the file
+// does not exist
+func readRequirements(client *scm.Client, fullName string, ref string) (*promotion.Requirements, error) {
+	content, _, err := client.Contents.Find(context.Background(), fullName, promotion.RequirementsFile, ref)
+	if err == scm.ErrNotFound {
+		return &promotion.Requirements{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting %s of %s at %s", promotion.RequirementsFile, fullName, ref)
+	}
+	requirements, err := promotion.ParseRequirements(content.Data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s of %s at %s", promotion.RequirementsFile, fullName, ref)
+	}
+	return requirements, nil
+}
+
+// DevEnvironmentPullRequestFiles returns the contents of the files added or changed by the pull request a jx command
+// opened on the dev environment repository of a GitOps cluster, found in the output of the command, keyed by path
+func (t *TestOptions) DevEnvironmentPullRequestFiles(output string) (map[string]string, error) {
+	createdPR, err := parsers.ParseJxCreatePullRequestFromFullLog(output)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := t.GetGitProvider()
+	if err != nil {
+		return nil, err
+	}
+	scmClient, _, err := t.GetLighthouseSCMClient(provider)
+	if err != nil {
+		return nil, err
+	}
+	fullName := scm.Join(createdPR.Owner, createdPR.Repository)
+	pr, _, err := scmClient.PullRequests.Find(context.Background(), fullName, createdPR.PullRequestNumber)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting pull request %d of %s", createdPR.PullRequestNumber, fullName)
+	}
+	head := pr.Head.Sha
+	if head == "" {
+		head = pr.Sha
+	}
+	changes, _, err := scmClient.PullRequests.ListChanges(context.Background(), fullName, createdPR.PullRequestNumber, scm.ListOptions{Size: 100})
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing the changes of pull request %d of %s", createdPR.PullRequestNumber, fullName)
+	}
+	files := map[string]string{}
+	for _, change := range changes {
+		if change.Deleted {
+			continue
+		}
+		content, _, err := scmClient.Contents.Find(context.Background(), fullName, change.Path, head)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting %s of %s at %s", change.Path, fullName, head)
+		}
+		files[change.Path] = string(content.Data)
+	}
+	return files, nil
+}
@@ -0,0 +1,254 @@
+package helpers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/buildlogs"
+	"github.com/jenkins-x/bdd-jx/test/utils/pipelines"
+	"github.com/jenkins-x/bdd-jx/test/utils/promotion"
+	"github.com/jenkins-x/bdd-jx/test/utils/scenarios"
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+	cmd "github.com/jenkins-x/jx/v2/pkg/cmd/clients"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+)
+
+// failedCommitStatuses are the commit statuses git providers report for a build which did not succeed
+var failedCommitStatuses = []string{"failure", "error"}
+
+// ExpectReleaseFailure pushes a commit to master replacing the jenkins-x.yml with one which makes the release pipeline
+// of the build pack go wrong as described by the failure. It then asserts that the PipelineActivity of the build
+// finishes with the expected status, that Tekton timed out its PipelineRun if the failure is a timeout, that the commit
+// status on the git provider reports the failure, and that the build promoted nothing to the given environments.
+func (t *TestOptions) ExpectReleaseFailure(failure *scenarios.Failure, buildPack string, environments []string, verifier *PromotionVerifier) error {
+	c := failure.Pipeline(buildPack)
+	sha, build, err := t.pushToMaster(fmt.Sprintf("Release pipeline with %s", failure.Description()), func(workDir string) (string, error) {
+		return c.WriteTo(workDir)
+	})
+	if err != nil {
+		return err
+	}
+
+	if failure.Kind == scenarios.FailureStop {
+		err = t.stopReleasePipeline(build, verifier)
+		if err != nil {
+			return err
+		}
+	}
+
+	expected := failure.StatusOrDefault()
+	var activity *v1.PipelineActivity
+	By(fmt.Sprintf("waiting for build %s of master to finish with status %s", build, expected), func() {
+		activity, err = verifier.waitForActivity(build, TimeoutBuildCompletes, func(a *v1.PipelineActivity) (bool, error) {
+			return pipelines.VerifyTerminalStatus(a, expected)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if failure.Kind == scenarios.FailureTimeout {
+		err = t.ExpectPipelineRunTimedOut("master", build)
+		if err != nil {
+			return err
+		}
+	}
+
+	if step := failure.FailedStep(); step != "" {
+		buildNumber, err := strconv.Atoi(build)
+		if err != nil {
+			return errors.Wrapf(err, "parsing build number %s", build)
+		}
+		jobName := t.GetGitOrganisation() + "/" + t.GetApplicationName() + "/master"
+		t.ExpectBuildLog(jobName, buildNumber, &buildlogs.Expectation{Failed: []string{step}})
+	}
+
+	err = pipelines.VerifyNotPromoted(activity)
+	if err != nil {
+		return err
+	}
+	if version := promotion.ActivityVersion(activity); version != "" {
+		for _, environment := range environments {
+			err = verifier.ExpectNotPromoted(environment, version)
+			if err != nil {
+				return errors.Wrapf(err, "build %s of master", build)
+			}
+			utils.LogInfof("version %s of failed build %s was not promoted to %s\n", version, build, environment)
+		}
+	}
+
+	provider, err := t.GetGitProvider()
+	if err != nil {
+		return err
+	}
+	var contexts []string
+	if activity.Spec.Context != "" {
+		contexts = []string{activity.Spec.Context}
+	}
+	By(fmt.Sprintf("waiting for commit %s to report the failure", sha), func() {
+		err = t.WaitForCommitStatus(provider, t.GetGitOrganisation(), t.GetApplicationName(), sha, contexts, failedCommitStatuses...)
+	})
+	return err
+}
+
+// ExpectReleaseRecovered pushes a commit to master restoring the given jenkins-x.yml and asserts that its build
+// releases a version greater than the previous version, returning it
+func (t *TestOptions) ExpectReleaseRecovered(projectConfig []byte, previousVersion string, verifier *PromotionVerifier) (string, error) {
+	_, build, err := t.pushToMaster("Restore the release pipeline", func(workDir string) (string, error) {
+		return pipelines.ProjectConfigFileName, ioutil.WriteFile(filepath.Join(workDir, pipelines.ProjectConfigFileName), projectConfig, utils.DefaultWritePermissions)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	buildNumber, err := strconv.Atoi(build)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing build number %s", build)
+	}
+	jobName := t.GetGitOrganisation() + "/" + t.GetApplicationName() + "/master"
+	t.ExpectBuildLog(jobName, buildNumber, nil)
+
+	version, err := verifier.ReleasedVersionOfBuild(build)
+	if err != nil {
+		return "", err
+	}
+	err = promotion.ExpectVersionIncremented(previousVersion, version)
+	if err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// pushToMaster commits the change made by the given function, which returns the path it wrote relative to the work
+// dir, to the latest master and pushes it, returning the commit SHA and the number of the build it triggers
+func (t *TestOptions) pushToMaster(message string, makeChange func(workDir string) (string, error)) (string, string, error) {
+	owner := t.GetGitOrganisation()
+	app := t.GetApplicationName()
+	workDir := filepath.Join(t.WorkDir, app)
+
+	t.ExpectCommandExecution(workDir, time.Minute, 0, "git", "checkout", "master")
+	t.ExpectCommandExecution(workDir, time.Minute, 0, "git", "pull")
+	path, err := makeChange(workDir)
+	if err != nil {
+		return "", "", err
+	}
+	t.ExpectCommandExecution(workDir, time.Minute, 0, "git", "add", path)
+	t.ExpectCommandExecution(workDir, time.Minute, 0, "git", "commit", "-m", message)
+	sha, err := gits.NewGitCLI().GetLatestCommitSha(workDir)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "getting the commit SHA in %s", workDir)
+	}
+
+	// read the build number before pushing, as the push triggers the build
+	build := t.NextBuildNumber(&gits.GitRepository{Organisation: owner, Name: app})
+	By(fmt.Sprintf("pushing commit %s '%s' to master, triggering build %s", sha, message, build), func() {
+		t.ExpectCommandExecution(workDir, time.Minute, 0, "git", "push", "origin", "master")
+	})
+	return sha, build, nil
+}
+
+// stopReleasePipeline waits for the given build of master to run and stops it with jx stop pipeline
+func (t *TestOptions) stopReleasePipeline(build string, verifier *PromotionVerifier) error {
+	var activity *v1.PipelineActivity
+	var err error
+	By(fmt.Sprintf("waiting for build %s of master to run", build), func() {
+		activity, err = verifier.waitForActivity(build, TimeoutBuildCompletes, func(a *v1.PipelineActivity) (bool, error) {
+			switch {
+			case a.Spec.Status == v1.ActivityStatusTypeRunning:
+				return true, nil
+			case pipelines.IsTerminal(a.Spec.Status):
+				return true, fmt.Errorf("PipelineActivity %s finished with status %s before it could be stopped", a.Name, a.Spec.Status)
+			default:
+				return false, fmt.Errorf("PipelineActivity %s is not running yet", a.Name)
+			}
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	// jx stop pipeline names pipelines as owner/repo/branch #build, followed by the context if there is one
+	name := fmt.Sprintf("%s/%s/master #%s", t.GetGitOrganisation(), t.GetApplicationName(), build)
+	if activity.Spec.Context != "" {
+		name += "-" + activity.Spec.Context
+	}
+	By(fmt.Sprintf("calling jx stop pipeline %s", name), func() {
+		t.ExpectJxExecution(t.WorkDir, TimeoutSessionWait, 0, "stop", "pipeline", "-b", name)
+	})
+	return nil
+}
+
+// waitForActivity waits for the PipelineActivity of the given build of master to pass the check, which also returns
+// true once waiting longer cannot change its outcome
+func (v *PromotionVerifier) waitForActivity(build string, timeout time.Duration, check func(*v1.PipelineActivity) (bool, error)) (*v1.PipelineActivity, error) {
+	owner := v.t.GetGitOrganisation()
+	app := v.t.GetApplicationName()
+	var activity *v1.PipelineActivity
+	f := func() error {
+		activities, err := v.jxClient.JenkinsV1().PipelineActivities(v.ns).List(metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		activity = promotion.ActivityForBuild(activities.Items, owner, app, "master", build)
+		if activity == nil {
+			err = fmt.Errorf("no PipelineActivity found for build %s of master of %s/%s", build, owner, app)
+			utils.LogInfof("WARNING: %s\n", err)
+			return err
+		}
+		done, err := check(activity)
+		if err != nil {
+			utils.LogInfof("WARNING: %s\n", err)
+			if done {
+				return backoff.Permanent(err)
+			}
+		}
+		return err
+	}
+	err := RetryExponentialBackoff(timeout, f)
+	if err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// ExpectPipelineRunTimedOut waits for Tekton to time out a PipelineRun of the given build of a branch of the
+// application, or of any of its builds if the build is empty, as jx reports timed out builds with the same Failed status
+// as failing ones
+func (t *TestOptions) ExpectPipelineRunTimedOut(branch string, build string) error {
+	tektonClient, ns, err := cmd.NewFactory().CreateTektonClient()
+	if err != nil {
+		return errors.Wrap(err, "creating the Tekton client")
+	}
+	description := branch
+	if build != "" {
+		description = fmt.Sprintf("build %s of %s", build, branch)
+	}
+	selector := pipelines.BuildSelector(t.GetGitOrganisation(), t.GetApplicationName(), branch, build)
+	By(fmt.Sprintf("waiting for a PipelineRun of %s to time out", description), func() {
+		err = RetryExponentialBackoff(TimeoutBuildCompletes, func() error {
+			runs, err := tektonClient.TektonV1alpha1().PipelineRuns(ns).List(metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return err
+			}
+			done, err := pipelines.VerifyTimedOut(runs.Items)
+			if err != nil {
+				err = errors.Wrap(err, description)
+				utils.LogInfof("WARNING: %s\n", err)
+				if done {
+					return backoff.Permanent(err)
+				}
+			}
+			return err
+		})
+	})
+	return err
+}
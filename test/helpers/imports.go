@@ -0,0 +1,88 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/imports"
+	"github.com/jenkins-x/go-scm/scm"
+	cmd "github.com/jenkins-x/jx/v2/pkg/cmd/clients"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// repoFiles reads the files of a git repository through the git provider
+type repoFiles struct {
+	client   *scm.Client
+	fullName string
+	ref      string
+}
+
+func (r *repoFiles) Read(path string) ([]byte, bool, error) {
+	content, _, err := r.client.Contents.Find(context.Background(), r.fullName, path, r.ref)
+	if err == scm.ErrNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return content.Data, true, nil
+}
+
+func (r *repoFiles) String() string {
+	return fmt.Sprintf("repository %s", r.fullName)
+}
+
+// ExpectImported returns an error unless the project jx import created the application from meets the expectation,
+// both in the work directory and in the repository it pushed. Untouched files are compared with the original project.
+// The SourceRepository of the application must also be for the repository.
+func (t *TestOptions) ExpectImported(expect *imports.Expectation, original imports.Files) error {
+	owner := t.GetGitOrganisation()
+	app := t.GetApplicationName()
+
+	local := imports.Dir(filepath.Join(t.WorkDir, app))
+	err := imports.Verify(local, original, expect, app)
+	if err != nil {
+		return err
+	}
+
+	provider, err := t.GetGitProvider()
+	if err != nil {
+		return err
+	}
+	scmClient, _, err := t.GetLighthouseSCMClient(provider)
+	if err != nil {
+		return err
+	}
+	remote := &repoFiles{client: scmClient, fullName: scm.Join(owner, app), ref: "master"}
+	f := func() error {
+		err := imports.Verify(remote, original, expect, app)
+		if err != nil {
+			utils.LogInfof("WARNING: %s\n", err)
+		}
+		return err
+	}
+	err = RetryExponentialBackoff(TimeoutCmdLine, f)
+	if err != nil {
+		return err
+	}
+
+	jxClient, ns, err := cmd.NewFactory().CreateJXClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to create jxClient")
+	}
+	name := strings.ToLower(fmt.Sprintf("%s-%s", owner, app))
+	sr, err := jxClient.JenkinsV1().SourceRepositories(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "getting SourceRepository %s", name)
+	}
+	err = imports.VerifySourceRepository(sr, provider.ServerURL(), owner, app)
+	if err != nil {
+		return err
+	}
+	utils.LogInfof("jx import of %s produced the expected %s and %s\n", utils.ColorInfo(app), local, remote)
+	return nil
+}
@@ -0,0 +1,185 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+	"github.com/pkg/errors"
+)
+
+// testIssue is an issue created by a test which is closed again on teardown
+type testIssue struct {
+	repo   string
+	number int
+}
+
+// CreateIssue creates an issue using the default credentials and registers it to be closed by CloseCreatedIssues
+func (t *TestOptions) CreateIssue(provider gits.GitProvider, owner string, repo string, title string, body string) (*scm.Issue, error) {
+	scmClient, _, err := t.GetLighthouseSCMClient(provider)
+	if err != nil {
+		return nil, err
+	}
+	fullName := scm.Join(owner, repo)
+	issue, _, err := scmClient.Issues.Create(context.Background(), fullName, &scm.IssueInput{Title: title, Body: body})
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating issue '%s' on %s", title, fullName)
+	}
+	t.registerIssue(owner, repo, issue.Number)
+	utils.LogInfof("created issue %s\n", issue.Link)
+	return issue, nil
+}
+
+func (t *TestOptions) registerIssue(owner string, repo string, number int) {
+	t.createdIssues = append(t.createdIssues, testIssue{repo: scm.Join(owner, repo), number: number})
+}
+
+// CloseCreatedIssues closes the issues created by this test which are still open
+func (t *TestOptions) CloseCreatedIssues(provider gits.GitProvider) error {
+	if len(t.createdIssues) == 0 {
+		return nil
+	}
+	scmClient, _, err := t.GetLighthouseSCMClient(provider)
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for _, created := range t.createdIssues {
+		issue, _, err := scmClient.Issues.Find(context.Background(), created.repo, created.number)
+		if err == nil && issue.Closed {
+			continue
+		}
+		_, err = scmClient.Issues.Close(context.Background(), created.repo, created.number)
+		if err != nil {
+			utils.LogInfof("WARNING: failed to close issue %d on %s: %s\n", created.number, created.repo, err)
+			failed = append(failed, fmt.Sprintf("%s#%d", created.repo, created.number))
+			continue
+		}
+		utils.LogInfof("closed issue %d on %s\n", created.number, created.repo)
+	}
+	t.createdIssues = nil
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to close issues %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// EnsureRepoLabels makes sure the given labels exist in the repo, so that Lighthouse commands such as /kind can add
+// them. Git providers create labels which do not exist yet when they are added to an issue, so a throwaway issue is
+// labelled and closed.
+func (t *TestOptions) EnsureRepoLabels(provider gits.GitProvider, owner string, repo string, labels ...string) error {
+	issue, err := t.CreateIssue(provider, owner, repo, "Create labels", "Creates the labels used by the tests")
+	if err != nil {
+		return err
+	}
+	scmClient, _, err := t.GetLighthouseSCMClient(provider)
+	if err != nil {
+		return err
+	}
+	fullName := scm.Join(owner, repo)
+	for _, label := range labels {
+		_, err = scmClient.Issues.AddLabel(context.Background(), fullName, issue.Number, label)
+		if err != nil {
+			return errors.Wrapf(err, "adding label %s to issue %s", label, issue.Link)
+		}
+	}
+	_, err = scmClient.Issues.Close(context.Background(), fullName, issue.Number)
+	return err
+}
+
+// ExpectThatIssueMatches returns an error if the issue does not satisfy the provided function
+func (t *TestOptions) ExpectThatIssueMatches(provider gits.GitProvider, owner string, repo string, number int, matchFunc func(issue *scm.Issue) error) error {
+	scmClient, _, err := t.GetLighthouseSCMClient(provider)
+	if err != nil {
+		return err
+	}
+	f := func() error {
+		issue, _, err := scmClient.Issues.Find(context.Background(), scm.Join(owner, repo), number)
+		if err != nil {
+			return err
+		}
+		err = matchFunc(issue)
+		if err != nil {
+			utils.LogInfof("WARNING: %s\n", err)
+		}
+		return err
+	}
+	return RetryExponentialBackoff(TimeoutProwActionWait, f)
+}
+
+// ExpectThatIssueHasLabel returns an error if the issue does not have the given label
+func (t *TestOptions) ExpectThatIssueHasLabel(provider gits.GitProvider, owner string, repo string, number int, label string) error {
+	return t.ExpectThatIssueMatches(provider, owner, repo, number, func(issue *scm.Issue) error {
+		if !issueHasLabel(issue, label) {
+			return fmt.Errorf("expected issue %s to have label %s but has %v", issue.Link, label, issue.Labels)
+		}
+		return nil
+	})
+}
+
+// ExpectThatIssueDoesNotHaveLabel returns an error if the issue has the given label
+func (t *TestOptions) ExpectThatIssueDoesNotHaveLabel(provider gits.GitProvider, owner string, repo string, number int, label string) error {
+	return t.ExpectThatIssueMatches(provider, owner, repo, number, func(issue *scm.Issue) error {
+		if issueHasLabel(issue, label) {
+			return fmt.Errorf("expected issue %s not to have label %s", issue.Link, label)
+		}
+		return nil
+	})
+}
+
+// ExpectThatIssueIsClosed returns an error unless the issue is closed if closed is true, or open otherwise
+func (t *TestOptions) ExpectThatIssueIsClosed(provider gits.GitProvider, owner string, repo string, number int, closed bool) error {
+	return t.ExpectThatIssueMatches(provider, owner, repo, number, func(issue *scm.Issue) error {
+		if issue.Closed != closed {
+			return fmt.Errorf("expected issue %s to have closed %t but was %s", issue.Link, closed, issue.State)
+		}
+		return nil
+	})
+}
+
+// ExpectThatIssueIsNotAssignedToUser returns an error if the issue is assigned to the given user
+func (t *TestOptions) ExpectThatIssueIsNotAssignedToUser(provider gits.GitProvider, owner string, repo string, number int, username string) error {
+	return t.ExpectThatIssueMatches(provider, owner, repo, number, func(issue *scm.Issue) error {
+		for _, assignee := range issue.Assignees {
+			if strings.EqualFold(assignee.Login, username) {
+				return fmt.Errorf("expected issue %s not to be assigned to %s", issue.Link, username)
+			}
+		}
+		return nil
+	})
+}
+
+// ExpectThatIssueHasCommentContaining returns an error if no comment on the issue by the given user contains the text
+func (t *TestOptions) ExpectThatIssueHasCommentContaining(provider gits.GitProvider, owner string, repo string, number int, author string, text string) error {
+	_, lhClient, err := t.GetLighthouseSCMClient(provider)
+	if err != nil {
+		return err
+	}
+	f := func() error {
+		comments, err := lhClient.ListIssueComments(owner, repo, number)
+		if err != nil {
+			return err
+		}
+		for _, c := range comments {
+			if strings.EqualFold(c.Author.Login, author) && strings.Contains(c.Body, text) {
+				return nil
+			}
+		}
+		err = fmt.Errorf("no comment by %s containing '%s' found on issue %d of %s/%s", author, text, number, owner, repo)
+		utils.LogInfof("WARNING: %s\n", err)
+		return err
+	}
+	return RetryExponentialBackoff(TimeoutProwActionWait, f)
+}
+
+func issueHasLabel(issue *scm.Issue, label string) bool {
+	for _, l := range issue.Labels {
+		if strings.EqualFold(l, label) {
+			return true
+		}
+	}
+	return false
+}
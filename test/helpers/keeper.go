@@ -0,0 +1,174 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/keeper"
+	cmd "github.com/jenkins-x/jx/v2/pkg/cmd/clients"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/gomega"
+)
+
+const (
+	// KeeperStatusInPool is the keeper status description for a pull request in the merge pool
+	KeeperStatusInPool = "In merge pool."
+	// KeeperStatusNotInPool is the start of the keeper status description for a pull request outside the merge pool
+	KeeperStatusNotInPool = "Not mergeable."
+)
+
+// MergePoolRecord records what was observed about a pull request while waiting for keeper to merge it
+type MergePoolRecord struct {
+	PullRequest *gits.GitPullRequest
+	// MergedAt is when the provider reports the pull request was merged
+	MergedAt time.Time
+	// MergeOrder is the position in which the merge was observed, starting at 1
+	MergeOrder int
+}
+
+// KeeperStatusNotInPoolForLabel returns the keeper status description expected for a pull request kept out of the
+// merge pool by the given label
+func KeeperStatusNotInPoolForLabel(provider gits.GitProvider, label string) string {
+	if DialectForProvider(provider).KeeperStatusOmitsReason {
+		return KeeperStatusNotInPool
+	}
+	return fmt.Sprintf("%s Should not have %s label.", KeeperStatusNotInPool, label)
+}
+
+// ExpectThatPullRequestHasStatusDescription returns an error if the latest status for the context on the PR head does
+// not have the given state and a description starting with the given text
+func (t *TestOptions) ExpectThatPullRequestHasStatusDescription(provider gits.GitProvider, pr *gits.GitPullRequest, context string, state string, description string) error {
+	dialect := DialectForProvider(provider)
+	f := func() error {
+		status, err := t.latestCommitStatus(provider, dialect, pr, context)
+		if err != nil {
+			return err
+		}
+		if status == nil {
+			return fmt.Errorf("no %s status found for PR %s/%s/%d", context, pr.Owner, pr.Repo, *pr.Number)
+		}
+		if status.State != state || !strings.HasPrefix(status.Description, description) {
+			err = fmt.Errorf("expected %s status on PR %s/%s/%d to be %s '%s' but was %s '%s'", context, pr.Owner, pr.Repo, *pr.Number, state, description, status.State, status.Description)
+			utils.LogInfof("WARNING: %s\n", err)
+			return err
+		}
+		return nil
+	}
+	return RetryExponentialBackoff(TimeoutProwActionWait, f)
+}
+
+// ExpectThatPullRequestIsInMergePool returns an error if keeper does not report the PR as being in the merge pool
+func (t *TestOptions) ExpectThatPullRequestIsInMergePool(provider gits.GitProvider, pr *gits.GitPullRequest) error {
+	return t.ExpectThatPullRequestHasStatusDescription(provider, pr, KeeperStatusContext, "success", KeeperStatusInPool)
+}
+
+// ExpectThatPullRequestIsNotInMergePool returns an error if keeper does not report the PR as being kept out of the merge
+// pool for the given description
+func (t *TestOptions) ExpectThatPullRequestIsNotInMergePool(provider gits.GitProvider, pr *gits.GitPullRequest, description string) error {
+	return t.ExpectThatPullRequestHasStatusDescription(provider, pr, KeeperStatusContext, "pending", description)
+}
+
+// WaitForPullRequestsToMergeFromPool waits for keeper to merge all the given pull requests, recording the order they
+// merged in
+func (t *TestOptions) WaitForPullRequestsToMergeFromPool(provider gits.GitProvider, pullRequests []*gits.GitPullRequest) []*MergePoolRecord {
+	records := make([]*MergePoolRecord, len(pullRequests))
+	for i, pr := range pullRequests {
+		records[i] = &MergePoolRecord{PullRequest: pr}
+	}
+	merged := 0
+
+	f := func() error {
+		for _, r := range records {
+			if r.MergeOrder > 0 {
+				continue
+			}
+			pr, err := t.GetPullRequestByNumber(provider, r.PullRequest.Owner, r.PullRequest.Repo, *r.PullRequest.Number)
+			if err != nil {
+				utils.LogInfof("WARNING: Error getting pull request: %s\n", err)
+				return err
+			}
+			if pr.Merged != nil && *pr.Merged {
+				merged++
+				r.MergeOrder = merged
+				if pr.MergedAt != nil {
+					r.MergedAt = *pr.MergedAt
+				}
+				utils.LogInfof("PR %s merged %d of %d\n", pr.URL, merged, len(records))
+			}
+		}
+		if merged < len(records) {
+			err := fmt.Errorf("%d of %d pull requests merged from the pool", merged, len(records))
+			utils.LogInfof("WARNING: %s, sleeping and retrying\n", err)
+			return err
+		}
+		return nil
+	}
+
+	err := RetryExponentialBackoff(TimeoutKeeperMerge, f)
+	Expect(err).ShouldNot(HaveOccurred())
+	return records
+}
+
+// ExpectPullRequestsMergedInBatchOrRetested returns an error unless each merged pull request was either built by a
+// successful batch build, or was retested after the base branch moved, according to the PipelineActivities Lighthouse
+// created for the repository
+func (t *TestOptions) ExpectPullRequestsMergedInBatchOrRetested(records []*MergePoolRecord) error {
+	if len(records) == 0 {
+		return errors.New("no pull requests were merged")
+	}
+	var merges []keeper.Merge
+	for _, r := range records {
+		if r.MergeOrder == 0 {
+			return fmt.Errorf("PR %s was not merged", r.PullRequest.URL)
+		}
+		merges = append(merges, keeper.Merge{Number: *r.PullRequest.Number, URL: r.PullRequest.URL, MergedAt: r.MergedAt})
+	}
+	owner := records[0].PullRequest.Owner
+	repo := records[0].PullRequest.Repo
+
+	jxClient, ns, err := cmd.NewFactory().CreateJXClient()
+	if err != nil {
+		return err
+	}
+	activities, err := jxClient.JenkinsV1().PipelineActivities(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "listing the PipelineActivities in namespace %s", ns)
+	}
+	err = keeper.VerifyMerges(activities.Items, owner, repo, merges)
+	if err != nil {
+		return err
+	}
+	batched := keeper.BatchedPullRequests(activities.Items, owner, repo)
+	for _, m := range merges {
+		if batch, ok := batched[m.Number]; ok {
+			utils.LogInfof("PR %s merged in batch build %s\n", m.URL, batch)
+		}
+	}
+	return nil
+}
+
+func (t *TestOptions) latestCommitStatus(provider gits.GitProvider, dialect *ProviderDialect, pr *gits.GitPullRequest, context string) (*gits.GitRepoStatus, error) {
+	sha := pr.LastCommitSha
+	if sha == "" {
+		latest, err := t.GetPullRequestByNumber(provider, pr.Owner, pr.Repo, *pr.Number)
+		if err != nil {
+			return nil, err
+		}
+		sha = latest.LastCommitSha
+	}
+	statuses, err := provider.ListCommitStatus(pr.Owner, pr.Repo, sha)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range dialect.NewestStatusesFirst(statuses) {
+		if status != nil && status.Context == context {
+			return status, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,48 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cenkalti/backoff"
+	"github.com/jenkins-x/bdd-jx/test/utils/parsers"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+)
+
+// ExpectThatPullRequestHasApprovalNotifierMatching returns an error if the PR does not have an APPROVALNOTIFIER comment
+// satisfying the provided function. The most recent APPROVALNOTIFIER comment on the PR is the one checked.
+func (t *TestOptions) ExpectThatPullRequestHasApprovalNotifierMatching(provider gits.GitProvider, pullRequestNumber int, owner, repo string, matchFunc func(notifier *parsers.ApprovalNotifier) error) error {
+	return t.ExpectThatPullRequestHasCommentMatching(provider, pullRequestNumber, owner, repo, func(comments []*scm.Comment) error {
+		var latest *scm.Comment
+		for _, c := range comments {
+			if strings.Contains(c.Body, parsers.ApprovalNotifierPrefix) {
+				latest = c
+			}
+		}
+		if latest == nil {
+			return fmt.Errorf("couldn't find comment containing %s", parsers.ApprovalNotifierPrefix)
+		}
+		notifier, err := parsers.ParseApprovalNotifier(latest.Body)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		return matchFunc(notifier)
+	})
+}
+
+// ExpectThatPullRequestNeedsApprovalFor returns an error unless the APPROVALNOTIFIER comment on the PR lists exactly the
+// given OWNERS files as still needing approval
+func (t *TestOptions) ExpectThatPullRequestNeedsApprovalFor(provider gits.GitProvider, pullRequestNumber int, owner, repo string, ownersFiles ...string) error {
+	return t.ExpectThatPullRequestHasApprovalNotifierMatching(provider, pullRequestNumber, owner, repo, func(notifier *parsers.ApprovalNotifier) error {
+		needed := notifier.FilesNeedingApproval()
+		expected := append([]string{}, ownersFiles...)
+		sort.Strings(needed)
+		sort.Strings(expected)
+		if strings.Join(needed, ",") != strings.Join(expected, ",") {
+			return fmt.Errorf("expected approval to be needed for %v but was needed for %v", ownersFiles, needed)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,147 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/parsers"
+	"github.com/jenkins-x/bdd-jx/test/utils/previews"
+	"github.com/jenkins-x/bdd-jx/test/utils/runner"
+	"github.com/jenkins-x/go-scm/scm"
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+	cmd "github.com/jenkins-x/jx/v2/pkg/cmd/clients"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PreviewEnvironmentForPullRequest waits for the preview Environment of the pull request at the given URL and returns it
+func (t *TestOptions) PreviewEnvironmentForPullRequest(pullRequestURL string) (*v1.Environment, error) {
+	jxClient, ns, err := cmd.NewFactory().CreateJXClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create jxClient")
+	}
+	var env *v1.Environment
+	f := func() error {
+		environments, err := jxClient.JenkinsV1().Environments(ns).List(metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		env = previews.FindForPullRequest(environments.Items, pullRequestURL)
+		if env == nil {
+			err = fmt.Errorf("no preview Environment found for %s", pullRequestURL)
+			utils.LogInfof("WARNING: %s\n", err)
+			return err
+		}
+		return nil
+	}
+	err = RetryExponentialBackoff(TimeoutPreviewUrlReturns, f)
+	if err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// ExpectPreviewEnvironment waits for the preview Environment of the pull request at the given URL and returns it once it
+// and its namespace have the labels and annotations jx preview gives them
+func (t *TestOptions) ExpectPreviewEnvironment(pullRequestURL string) (*v1.Environment, error) {
+	env, err := t.PreviewEnvironmentForPullRequest(pullRequestURL)
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, ns, err := cmd.NewFactory().CreateKubeClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubeClient")
+	}
+	err = previews.VerifyEnvironment(env, pullRequestURL, t.GetApplicationName())
+	if err != nil {
+		return nil, err
+	}
+	f := func() error {
+		namespace, err := kubeClient.CoreV1().Namespaces().Get(env.Spec.Namespace, metav1.GetOptions{})
+		if err == nil {
+			err = previews.VerifyNamespace(namespace, env, ns)
+		}
+		if err != nil {
+			utils.LogInfof("WARNING: %s\n", err)
+		}
+		return err
+	}
+	err = RetryExponentialBackoff(TimeoutPreviewUrlReturns, f)
+	if err != nil {
+		return nil, err
+	}
+	utils.LogInfof("found preview Environment %s with namespace %s for %s\n", utils.ColorInfo(env.Name), utils.ColorInfo(env.Spec.Namespace), pullRequestURL)
+	return env, nil
+}
+
+// ClosePullRequest closes the pull request without merging it
+func (t *TestOptions) ClosePullRequest(pr *parsers.CreatePullRequest) error {
+	provider, err := t.GetGitProvider()
+	if err != nil {
+		return err
+	}
+	scmClient, _, err := t.GetLighthouseSCMClient(provider)
+	if err != nil {
+		return err
+	}
+	_, err = scmClient.PullRequests.Close(context.Background(), scm.Join(pr.Owner, pr.Repository), pr.PullRequestNumber)
+	if err != nil {
+		return errors.Wrapf(err, "closing pull request %s", pr.Url)
+	}
+	utils.LogInfof("closed pull request %s\n", utils.ColorInfo(pr.Url))
+	return nil
+}
+
+// ExpectPreviewGarbageCollected returns an error unless the preview Environment and its namespace are removed once the
+// pull request is merged or closed, waiting for a terminating namespace to be gone. If RunPreviewGC is true jx gc
+// previews is run first rather than waiting for its cron job.
+func (t *TestOptions) ExpectPreviewGarbageCollected(preview *v1.Environment) error {
+	factory := cmd.NewFactory()
+	jxClient, ns, err := factory.CreateJXClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to create jxClient")
+	}
+	kubeClient, _, err := factory.CreateKubeClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to create kubeClient")
+	}
+
+	if strings.ToLower(RunPreviewGC) == "true" {
+		r := runner.New(t.WorkDir, &TimeoutSessionWait, 0)
+		_, err = r.RunWithOutput("gc", "previews", "-b")
+		if err != nil {
+			return errors.Wrap(err, "running jx gc previews")
+		}
+	}
+
+	f := func() error {
+		_, err := jxClient.JenkinsV1().Environments(ns).Get(preview.Name, metav1.GetOptions{})
+		if err == nil {
+			err = fmt.Errorf("preview Environment %s still exists", preview.Name)
+			utils.LogInfof("WARNING: %s\n", err)
+			return err
+		}
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		namespace, err := kubeClient.CoreV1().Namespaces().Get(preview.Spec.Namespace, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = fmt.Errorf("preview namespace %s still exists in phase %s", namespace.Name, namespace.Status.Phase)
+		utils.LogInfof("WARNING: %s\n", err)
+		return err
+	}
+	err = RetryExponentialBackoff(TimeoutPreviewGC, f)
+	if err != nil {
+		return err
+	}
+	utils.LogInfof("preview Environment %s and namespace %s have been garbage collected\n", utils.ColorInfo(preview.Name), utils.ColorInfo(preview.Spec.Namespace))
+	return nil
+}
@@ -0,0 +1,260 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/promotion"
+	"github.com/jenkins-x/go-scm/scm"
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx-api/pkg/client/clientset/versioned"
+	cmd "github.com/jenkins-x/jx/v2/pkg/cmd/clients"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PromotionVerifier verifies that versions of the application of a test are promoted to environments, using the
+// Environment, Release and PipelineActivity resources and the git repositories of the environments
+type PromotionVerifier struct {
+	t        *TestOptions
+	jxClient versioned.Interface
+	ns       string
+	provider gits.GitProvider
+}
+
+// NewPromotionVerifier returns a verifier for the promotions of the application of the test
+func (t *TestOptions) NewPromotionVerifier() (*PromotionVerifier, error) {
+	jxClient, ns, err := cmd.NewFactory().CreateJXClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create jxClient")
+	}
+	provider, err := t.GetGitProvider()
+	if err != nil {
+		return nil, err
+	}
+	return &PromotionVerifier{t: t, jxClient: jxClient, ns: ns, provider: provider}, nil
+}
+
+// ReleasedVersion waits for the latest build of master of the application to release a version and returns it
+func (v *PromotionVerifier) ReleasedVersion() (string, error) {
+	owner := v.t.GetGitOrganisation()
+	app := v.t.GetApplicationName()
+	return v.releasedVersion(func(activities []v1.PipelineActivity) *v1.PipelineActivity {
+		return promotion.LatestActivity(activities, owner, app, "master")
+	}, "master")
+}
+
+// ReleasedVersionOfBuild waits for the given build of master of the application to release a version and returns it
+func (v *PromotionVerifier) ReleasedVersionOfBuild(build string) (string, error) {
+	owner := v.t.GetGitOrganisation()
+	app := v.t.GetApplicationName()
+	return v.releasedVersion(func(activities []v1.PipelineActivity) *v1.PipelineActivity {
+		return promotion.ActivityForBuild(activities, owner, app, "master", build)
+	}, "build "+build+" of master")
+}
+
+func (v *PromotionVerifier) releasedVersion(find func([]v1.PipelineActivity) *v1.PipelineActivity, description string) (string, error) {
+	owner := v.t.GetGitOrganisation()
+	app := v.t.GetApplicationName()
+	version := ""
+	f := func() error {
+		activities, err := v.jxClient.JenkinsV1().PipelineActivities(v.ns).List(metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		activity := find(activities.Items)
+		if activity == nil {
+			err = fmt.Errorf("no PipelineActivity found for %s of %s/%s", description, owner, app)
+		} else if version = promotion.ActivityVersion(activity); version == "" {
+			err = fmt.Errorf("PipelineActivity %s has not released a version yet", activity.Name)
+		}
+		if err != nil {
+			utils.LogInfof("WARNING: %s\n", err)
+		}
+		return err
+	}
+	err := RetryExponentialBackoff(TimeoutPipelineActivityComplete, f)
+	if err != nil {
+		return "", err
+	}
+	utils.LogInfof("%s of %s/%s released version %s\n", description, owner, app, utils.ColorInfo(version))
+	return version, nil
+}
+
+// Environment returns the Environment resource of the given name
+func (v *PromotionVerifier) Environment(name string) (*v1.Environment, error) {
+	env, err := v.jxClient.JenkinsV1().Environments(v.ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting Environment %s in namespace %s", name, v.ns)
+	}
+	return env, nil
+}
+
+// PromotionStrategy returns how versions are promoted to the environment
+func (v *PromotionVerifier) PromotionStrategy(environment string) (v1.PromotionStrategyType, error) {
+	env, err := v.Environment(environment)
+	if err != nil {
+		return "", err
+	}
+	return env.Spec.PromotionStrategy, nil
+}
+
+// ExpectPromoted returns an error unless the version of the application was promoted to the environment. The promote
+// step of the PipelineActivity which released the version must have succeeded, unless PipelineActivity checks are
+// disabled, and its pull request on the environment repository must be merged. The environment repository must
+// require the version, and a Release for it must exist in the namespace of the environment.
+func (v *PromotionVerifier) ExpectPromoted(environment string, version string) error {
+	env, err := v.Environment(environment)
+	if err != nil {
+		return err
+	}
+	app := v.t.GetApplicationName()
+
+	if v.t.ShouldTestPipelineActivityUpdate() {
+		var step *v1.PromoteActivityStep
+		f := func() error {
+			step, err = v.promoteStep(environment, version)
+			if err == nil {
+				err = promotion.VerifyPromoteStep(step)
+			}
+			if err != nil {
+				utils.LogInfof("WARNING: %s\n", err)
+			}
+			return err
+		}
+		err = RetryExponentialBackoff(TimeoutBuildIsRunningInStaging, f)
+		if err != nil {
+			return err
+		}
+		err = v.expectPullRequestMerged(env, step.PullRequest.PullRequestURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	f := func() error {
+		err := v.expectEnvironmentRepoVersion(env, version, true)
+		if err == nil {
+			err = v.expectRelease(env, version, true)
+		}
+		if err != nil {
+			utils.LogInfof("WARNING: %s\n", err)
+		}
+		return err
+	}
+	err = RetryExponentialBackoff(TimeoutBuildIsRunningInStaging, f)
+	if err != nil {
+		return err
+	}
+	utils.LogInfof("version %s of %s is promoted to %s\n", utils.ColorInfo(version), app, utils.ColorInfo(environment))
+	return nil
+}
+
+// ExpectNotPromoted returns an error if the version of the application has been promoted to the environment
+func (v *PromotionVerifier) ExpectNotPromoted(environment string, version string) error {
+	env, err := v.Environment(environment)
+	if err != nil {
+		return err
+	}
+	err = v.expectEnvironmentRepoVersion(env, version, false)
+	if err != nil {
+		return err
+	}
+	return v.expectRelease(env, version, false)
+}
+
+func (v *PromotionVerifier) promoteStep(environment string, version string) (*v1.PromoteActivityStep, error) {
+	owner := v.t.GetGitOrganisation()
+	app := v.t.GetApplicationName()
+	activities, err := v.jxClient.JenkinsV1().PipelineActivities(v.ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	activity := promotion.ActivityForVersion(activities.Items, owner, app, "master", version)
+	if activity == nil {
+		return nil, fmt.Errorf("no PipelineActivity found for version %s of %s/%s", version, owner, app)
+	}
+	step := promotion.FindPromoteStep(activity, environment)
+	if step == nil {
+		return nil, fmt.Errorf("PipelineActivity %s has no step promoting to %s", activity.Name, environment)
+	}
+	return step, nil
+}
+
+func (v *PromotionVerifier) expectPullRequestMerged(env *v1.Environment, pullRequestURL string) error {
+	ref, err := promotion.ParsePullRequestURL(pullRequestURL)
+	if err != nil {
+		return err
+	}
+	gitInfo, err := gits.ParseGitURL(env.Spec.Source.URL)
+	if err != nil {
+		return errors.Wrapf(err, "parsing the git URL of environment %s", env.Name)
+	}
+	if ref.Repo != gitInfo.Name {
+		return fmt.Errorf("promotion pull request %s is not on the repository %s of environment %s", pullRequestURL, env.Spec.Source.URL, env.Name)
+	}
+	pr, err := v.t.GetPullRequestByNumber(v.provider, ref.Owner, ref.Repo, ref.Number)
+	if err != nil {
+		return errors.Wrapf(err, "getting promotion pull request %s", pullRequestURL)
+	}
+	if pr.Merged == nil || !*pr.Merged {
+		return fmt.Errorf("promotion pull request %s is not merged", pullRequestURL)
+	}
+	utils.LogInfof("promotion pull request %s to %s is merged\n", utils.ColorInfo(pullRequestURL), env.Name)
+	return nil
+}
+
+func (v *PromotionVerifier) expectEnvironmentRepoVersion(env *v1.Environment, version string, promoted bool) error {
+	gitInfo, err := gits.ParseGitURL(env.Spec.Source.URL)
+	if err != nil {
+		return errors.Wrapf(err, "parsing the git URL of environment %s", env.Name)
+	}
+	ref := env.Spec.Source.Ref
+	if ref == "" {
+		ref = "master"
+	}
+	scmClient, _, err := v.t.GetLighthouseSCMClient(v.provider)
+	if err != nil {
+		return err
+	}
+	fullName := scm.Join(gitInfo.Organisation, gitInfo.Name)
+	content, _, err := scmClient.Contents.Find(context.Background(), fullName, promotion.RequirementsFile, ref)
+	if err != nil {
+		return errors.Wrapf(err, "getting %s of %s", promotion.RequirementsFile, fullName)
+	}
+	requirements, err := promotion.ParseRequirements(content.Data)
+	if err != nil {
+		return errors.Wrapf(err, "%s of %s", promotion.RequirementsFile, fullName)
+	}
+	app := v.t.GetApplicationName()
+	actual, _ := requirements.VersionOf(app)
+	if promoted && actual != version {
+		return fmt.Errorf("expected %s of %s to require version %s of %s but found '%s'", promotion.RequirementsFile, fullName, version, app, actual)
+	}
+	if !promoted && actual == version {
+		return fmt.Errorf("expected %s of %s not to require version %s of %s", promotion.RequirementsFile, fullName, version, app)
+	}
+	return nil
+}
+
+func (v *PromotionVerifier) expectRelease(env *v1.Environment, version string, promoted bool) error {
+	if env.Spec.RemoteCluster {
+		utils.LogInfof("not checking the Release of environment %s as it is in a remote cluster\n", env.Name)
+		return nil
+	}
+	releases, err := v.jxClient.JenkinsV1().Releases(env.Spec.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "listing Releases in namespace %s", env.Spec.Namespace)
+	}
+	app := v.t.GetApplicationName()
+	release := promotion.FindRelease(releases.Items, app, version)
+	if promoted && release == nil {
+		return fmt.Errorf("no Release found for version %s of %s in namespace %s", version, app, env.Spec.Namespace)
+	}
+	if !promoted && release != nil {
+		return fmt.Errorf("found Release %s for version %s of %s in namespace %s", release.Name, version, app, env.Spec.Namespace)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/promotion"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+	"github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+)
+
+// MergePullRequestAndExpectNextRelease merges the pull request and asserts that the next build of master releases a
+// semantic version greater than the previous version, returning it
+func (t *TestOptions) MergePullRequestAndExpectNextRelease(preview *PreviewPullRequest, previousVersion string, verifier *PromotionVerifier) (string, error) {
+	owner := t.GetGitOrganisation()
+	app := t.GetApplicationName()
+	prNumber := preview.PullRequest.PullRequestNumber
+	prURL := preview.PullRequest.Url
+
+	provider, err := t.GetGitProvider()
+	if err != nil {
+		return "", err
+	}
+
+	// read the build number before merging, as the merge triggers the build
+	nextBuild := t.NextBuildNumber(&gits.GitRepository{Organisation: owner, Name: app})
+
+	By(fmt.Sprintf("merging pull request %s", prURL), func() {
+		pr, err := t.GetPullRequestByNumber(provider, owner, app, prNumber)
+		if err == nil {
+			err = provider.MergePullRequest(pr, "Merged by the BDD tests")
+		}
+		utils.ExpectNoError(err)
+		t.WaitForPullRequestToMerge(provider, owner, app, prNumber, prURL)
+	})
+
+	buildNumber, err := strconv.Atoi(nextBuild)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing next build number %s", nextBuild)
+	}
+	jobName := owner + "/" + app + "/master"
+	t.ExpectBuildLog(jobName, buildNumber, nil)
+
+	version, err := verifier.ReleasedVersionOfBuild(nextBuild)
+	if err != nil {
+		return "", err
+	}
+	err = promotion.ExpectVersionIncremented(previousVersion, version)
+	if err != nil {
+		return "", err
+	}
+	utils.LogInfof("build %s of %s released version %s after %s\n", nextBuild, jobName, utils.ColorInfo(version), previousVersion)
+	return version, nil
+}
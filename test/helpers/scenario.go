@@ -0,0 +1,459 @@
+package helpers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/buildlogs"
+	"github.com/jenkins-x/bdd-jx/test/utils/fixtures"
+	"github.com/jenkins-x/bdd-jx/test/utils/imports"
+	"github.com/jenkins-x/bdd-jx/test/utils/pipelines"
+	"github.com/jenkins-x/bdd-jx/test/utils/probes"
+	"github.com/jenkins-x/bdd-jx/test/utils/projects"
+	"github.com/jenkins-x/bdd-jx/test/utils/scenarios"
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/v2/pkg/util"
+	"github.com/pkg/errors"
+	git "gopkg.in/src-d/go-git.v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// ScenarioTestsFromDir creates the specs for each of the scenario files in the given directory
+func ScenarioTestsFromDir(dir string) []bool {
+	loaded, err := scenarios.LoadDir(dir)
+	if err != nil {
+		panic(errors.Wrapf(err, "loading scenarios from %s", dir))
+	}
+	var tests []bool
+	for _, scenario := range loaded {
+		tests = append(tests, ScenarioTests(scenario))
+	}
+	return tests
+}
+
+// ScenarioTests creates the specs which create the application described by the scenario, wait for its pipelines and
+// check it returns the expected responses in each environment. The steps of the scenario are composed from
+// buildScenario, expectScenarioBuildLog, discoverScenarioRelease, probeScenarioEnvironments, pullRequestScenario,
+// promoteScenario and runScenarioFailures, and the application is deleted after each spec by deleteScenarioApplication.
+func ScenarioTests(scenario *scenarios.Scenario) bool {
+	return Describe(scenarioDescription(scenario), func() {
+		var T TestOptions
+
+		BeforeEach(func() {
+			T = TestOptions{}
+			if reason := scenario.SkipReason(); reason != "" {
+				Skip(fmt.Sprintf("skipping scenario %s because %s", scenario.Name, reason))
+			}
+			applicationName := TempDirPrefix + scenarioAbbreviation(scenario) + "-" + strconv.FormatInt(GinkgoRandomSeed(), 10)
+			T = TestOptions{
+				ApplicationName: applicationName,
+				WorkDir:         WorkDir,
+			}
+			T.GitProviderURL()
+
+			utils.LogInfof("Creating application %s in dir %s\n", util.ColorInfo(applicationName), util.ColorInfo(WorkDir))
+		})
+
+		AfterEach(func() {
+			if scenario.ShouldCleanup() {
+				T.deleteScenarioApplication(scenario)
+			}
+		})
+
+		Context(fmt.Sprintf("by running jx %s", scenario.SourceKind()), func() {
+			It("creates the application and promotes it", func() {
+				T.createScenarioApplication(scenario)
+
+				log := T.buildScenario()
+				T.expectScenarioBuildLog(scenario, log)
+				verifier, releasedVersion := T.discoverScenarioRelease()
+
+				// imports have always been checked to be promoted, whereas created applications are only checked once
+				// their first release is waited for
+				if T.WaitForFirstRelease() || scenario.SourceKind() == scenarios.SourceImport {
+					T.probeScenarioEnvironments(scenario, verifier, releasedVersion)
+				}
+				// pull requests are only tested once the first release has been waited for
+				if scenario.PullRequest != nil && T.WaitForFirstRelease() && T.TestPullRequest() {
+					T.pullRequestScenario(scenario, verifier, releasedVersion)
+				}
+				T.promoteScenario(scenario, verifier, releasedVersion)
+				if len(scenario.Failures) > 0 {
+					T.runScenarioFailures(scenario, releasedVersion, verifier)
+				}
+			})
+		})
+	})
+}
+
+// buildScenario waits for the first build of master of the application to complete successfully, returning the log
+// tailed while waiting for it
+func (t *TestOptions) buildScenario() *buildlogs.Log {
+	applicationName := t.GetApplicationName()
+	jobName := t.GetGitOrganisation() + "/" + applicationName + "/master"
+	if t.WaitForFirstRelease() {
+		//FIXME Need to wait a little here to ensure that the build has started before asking for the log as the jx create command returns slightly before the build log is available
+		time.Sleep(30 * time.Second)
+	}
+
+	var log *buildlogs.Log
+	By(fmt.Sprintf("waiting for the first successful build of master of %s", applicationName), func() {
+		_, log = t.ThereShouldBeAJobThatCompletesSuccessfullyWithLog(jobName, TimeoutBuildCompletes)
+	})
+	return log
+}
+
+// expectScenarioBuildLog checks the log of the first build against the build log expectation of the scenario
+func (t *TestOptions) expectScenarioBuildLog(scenario *scenarios.Scenario, log *buildlogs.Log) {
+	t.VerifyBuildLog(log, scenario.BuildLog)
+}
+
+// discoverScenarioRelease returns a verifier of the promotions of the application and the version released by master
+func (t *TestOptions) discoverScenarioRelease() (*PromotionVerifier, string) {
+	var verifier *PromotionVerifier
+	var releasedVersion string
+	By("discovering the version released by master", func() {
+		var err error
+		verifier, err = t.NewPromotionVerifier()
+		Expect(err).ShouldNot(HaveOccurred())
+		releasedVersion, err = verifier.ReleasedVersion()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+	return verifier, releasedVersion
+}
+
+// probeScenarioEnvironments checks that the released version was promoted to each environment of the scenario and
+// that the application passes the probe of the environment there
+func (t *TestOptions) probeScenarioEnvironments(scenario *scenarios.Scenario, verifier *PromotionVerifier, releasedVersion string) {
+	for _, environment := range scenario.Environments {
+		By(fmt.Sprintf("checking that the application is running in %s", environment.Name), func() {
+			t.TheApplicationIsRunningAndPasses(environment.Name, environment.Expect.Probe())
+		})
+		By(fmt.Sprintf("verifying that version %s was promoted to %s", releasedVersion, environment.Name), func() {
+			err := verifier.ExpectPromoted(environment.Name, releasedVersion)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	}
+}
+
+// pullRequestScenario creates the pull request of the scenario, asserting its preview environment passes the preview
+// probe, then merges or closes it if the scenario says so
+func (t *TestOptions) pullRequestScenario(scenario *scenarios.Scenario, verifier *PromotionVerifier, releasedVersion string) {
+	var preview *PreviewPullRequest
+	if change := scenario.PullRequest.ChangeResponse; change != nil {
+		By("performing a pull request changing the response of the application and asserting that a preview environment serves it", func() {
+			var err error
+			preview, err = t.CreatePullRequestChangingResponseAndGetPreviewEnvironment(change.Match, change.Path, change.EnvironmentOrDefault(), scenario.PullRequest.Preview.Probe())
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	} else if values := scenario.PullRequest.Set; len(values) > 0 {
+		By("performing a pull request setting values in the project files and asserting that a preview environment is created", func() {
+			var err error
+			preview, err = t.CreatePullRequestSettingValuesAndGetPreviewEnvironment(values, scenario.PullRequest.Preview.Probe())
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	} else {
+		By("performing a pull request on the source and asserting that a preview environment is created", func() {
+			var err error
+			preview, err = t.CreatePullRequestAndGetPreviewEnvironmentPassing(scenario.PullRequest.Preview.Probe())
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	}
+
+	if scenario.PullRequest.Merge {
+		t.mergeScenarioPullRequest(scenario, preview, releasedVersion, verifier)
+	} else if scenario.PullRequest.Close {
+		t.closeScenarioPullRequest(preview)
+	}
+}
+
+// promoteScenario promotes the application to the environments of the promotions of the scenario with jx promote,
+// unless they are promoted to automatically, and checks it passes the probe of the promotion there
+func (t *TestOptions) promoteScenario(scenario *scenarios.Scenario, verifier *PromotionVerifier, releasedVersion string) {
+	for _, promotion := range scenario.Promotions {
+		if reason := promotion.SkipReason(); reason != "" {
+			utils.LogInfof("skipping promotion to %s because %s\n", promotion.Environment, reason)
+			continue
+		}
+		version := promotion.Version
+		if version == "" {
+			version = releasedVersion
+		}
+		strategy, err := verifier.PromotionStrategy(promotion.Environment)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		if strategy == v1.PromotionStrategyTypeAutomatic {
+			utils.LogInfof("not running jx promote as %s is promoted to automatically\n", promotion.Environment)
+		} else {
+			By(fmt.Sprintf("verifying that version %s has not been promoted to the %s environment with %s promotion", version, promotion.Environment, strategy), func() {
+				err := verifier.ExpectNotPromoted(promotion.Environment, version)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+			args := []string{"promote", "--env", promotion.Environment, "--version", version, t.ApplicationName}
+			By(fmt.Sprintf("manually promoting the application to the %s environment", promotion.Environment), func() {
+				t.ExpectJxExecution(t.WorkDir, TimeoutSessionWait, 0, args...)
+			})
+		}
+		By(fmt.Sprintf("verifying that version %s was promoted to %s", version, promotion.Environment), func() {
+			err := verifier.ExpectPromoted(promotion.Environment, version)
+			Expect(err).ShouldNot(HaveOccurred())
+			t.TheApplicationIsRunningAndPasses(promotion.Environment, promotion.Expect.Probe())
+		})
+	}
+}
+
+// mergeScenarioPullRequest merges the pull request of the scenario and asserts master releases a greater version which
+// is promoted to staging with the change, and that the preview environment is garbage collected
+func (t *TestOptions) mergeScenarioPullRequest(scenario *scenarios.Scenario, preview *PreviewPullRequest, previousVersion string, verifier *PromotionVerifier) {
+	previewEnv := t.expectScenarioPreviewEnvironment(preview)
+
+	var nextVersion string
+	By(fmt.Sprintf("merging the pull request and waiting for master to release a version greater than %s", previousVersion), func() {
+		var err error
+		nextVersion, err = t.MergePullRequestAndExpectNextRelease(preview, previousVersion, verifier)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	environment := "staging"
+	probe := probes.StatusProbe(http.StatusOK)
+	for _, e := range scenario.Environments {
+		if e.Name == environment {
+			probe = e.Expect.Probe()
+		}
+	}
+	if preview.Change != nil {
+		probe.Checks = append(probe.Checks, probes.Check{Path: scenario.PullRequest.ChangeResponse.Path, BodyContains: preview.Change.Marker})
+	}
+	By(fmt.Sprintf("verifying that version %s was promoted to %s with the change", nextVersion, environment), func() {
+		err := verifier.ExpectPromoted(environment, nextVersion)
+		Expect(err).ShouldNot(HaveOccurred())
+		t.TheApplicationIsRunningAndPasses(environment, probe)
+	})
+
+	By(fmt.Sprintf("verifying that the preview environment %s is garbage collected", previewEnv.Name), func() {
+		err := t.ExpectPreviewGarbageCollected(previewEnv)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+}
+
+// runScenarioFailures pushes the commits of the scenario which make the release pipeline go wrong, asserting each is
+// reported as failed without promoting anything, then pushes a commit restoring the jenkins-x.yml and asserts master
+// releases a greater version which is promoted to the environments of the scenario again
+func (t *TestOptions) runScenarioFailures(scenario *scenarios.Scenario, previousVersion string, verifier *PromotionVerifier) {
+	workDir := filepath.Join(t.WorkDir, t.GetApplicationName())
+	var projectConfig []byte
+	var buildPack string
+	By(fmt.Sprintf("reading the %s of master to restore after the failures", pipelines.ProjectConfigFileName), func() {
+		t.ExpectCommandExecution(workDir, time.Minute, 0, "git", "checkout", "master")
+		t.ExpectCommandExecution(workDir, time.Minute, 0, "git", "pull")
+		var err error
+		projectConfig, err = ioutil.ReadFile(filepath.Join(workDir, pipelines.ProjectConfigFileName))
+		Expect(err).ShouldNot(HaveOccurred())
+		buildPack, err = imports.ReadBuildPack(imports.Dir(workDir))
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	var environments []string
+	for _, e := range scenario.Environments {
+		environments = append(environments, e.Name)
+	}
+	for i := range scenario.Failures {
+		failure := &scenario.Failures[i]
+		By(fmt.Sprintf("pushing a commit to master with %s and asserting the release pipeline fails", failure.Description()), func() {
+			err := t.ExpectReleaseFailure(failure, buildPack, environments, verifier)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	}
+
+	var version string
+	By(fmt.Sprintf("restoring the %s and waiting for master to release a version greater than %s", pipelines.ProjectConfigFileName, previousVersion), func() {
+		var err error
+		version, err = t.ExpectReleaseRecovered(projectConfig, previousVersion, verifier)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+	for _, environment := range scenario.Environments {
+		By(fmt.Sprintf("verifying that version %s was promoted to %s after the failures", version, environment.Name), func() {
+			err := verifier.ExpectPromoted(environment.Name, version)
+			Expect(err).ShouldNot(HaveOccurred())
+			t.TheApplicationIsRunningAndPasses(environment.Name, environment.Expect.Probe())
+		})
+	}
+}
+
+// closeScenarioPullRequest closes the pull request of the scenario without merging it and asserts that the preview
+// environment is garbage collected
+func (t *TestOptions) closeScenarioPullRequest(preview *PreviewPullRequest) {
+	previewEnv := t.expectScenarioPreviewEnvironment(preview)
+
+	By(fmt.Sprintf("closing pull request %s without merging it", preview.PullRequest.Url), func() {
+		err := t.ClosePullRequest(preview.PullRequest)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	By(fmt.Sprintf("verifying that the preview environment %s is garbage collected", previewEnv.Name), func() {
+		err := t.ExpectPreviewGarbageCollected(previewEnv)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+}
+
+func (t *TestOptions) expectScenarioPreviewEnvironment(preview *PreviewPullRequest) *v1.Environment {
+	var previewEnv *v1.Environment
+	By("verifying the preview environment of the pull request and its namespace", func() {
+		var err error
+		previewEnv, err = t.ExpectPreviewEnvironment(preview.PullRequest.Url)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+	return previewEnv
+}
+
+// createScenarioApplication creates the application from the source of the scenario
+func (t *TestOptions) createScenarioApplication(scenario *scenarios.Scenario) {
+	gitProviderUrl, err := t.GitProviderURL()
+	Expect(err).NotTo(HaveOccurred())
+
+	var args []string
+	switch scenario.SourceKind() {
+	case scenarios.SourceImport:
+		destDir := filepath.Join(t.WorkDir, t.ApplicationName)
+		if scenario.Source.Fixture != "" {
+			fixtureDir := filepath.Join(ImportFixtureDir, scenario.Source.Fixture)
+			By(fmt.Sprintf("copying fixture project %s", fixtureDir), func() {
+				err := fixtures.Copy(fixtureDir, destDir, fixtures.Values{ApplicationName: t.ApplicationName})
+				Expect(err).NotTo(HaveOccurred())
+			})
+		} else {
+			t.cloneScenarioImport(scenario.Source.Import, destDir)
+		}
+		args = []string{"import", destDir, "-b", "--org", t.GetGitOrganisation()}
+	case scenarios.SourceSpring:
+		args = []string{"create", "spring", "-b", "--org", t.GetGitOrganisation(), "--artifact", t.ApplicationName, "--name", t.ApplicationName}
+		for _, dependency := range scenario.Source.Spring.Dependencies {
+			args = append(args, "-d", dependency)
+		}
+	default:
+		args = []string{"create", "quickstart", "-b", "--org", t.GetGitOrganisation(), "-p", t.ApplicationName, "-f", scenario.Source.Quickstart}
+	}
+
+	if gitProviderUrl != "" {
+		utils.LogInfof("Using Git provider URL %s\n", gitProviderUrl)
+		args = append(args, "--git-provider-url", gitProviderUrl)
+	}
+	argsStr := strings.Join(args, " ")
+	By(fmt.Sprintf("calling jx %s", argsStr), func() {
+		t.ExpectJxExecution(t.WorkDir, TimeoutSessionWait, 0, args...)
+	})
+
+	if scenario.SourceKind() == scenarios.SourceSpring {
+		By("verifying jx named the spring project after the application", func() {
+			// renaming a project already named after the application edits nothing
+			edits, err := projects.Rename(filepath.Join(t.WorkDir, t.ApplicationName), t.ApplicationName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(edits).Should(BeEmpty(), "the project created by jx create spring is not named %s", t.ApplicationName)
+		})
+	}
+
+	if scenario.Imported != nil {
+		var original imports.Files
+		if scenario.Source.Fixture != "" {
+			original = fixtures.Project{Dir: filepath.Join(ImportFixtureDir, scenario.Source.Fixture), Values: fixtures.Values{ApplicationName: t.ApplicationName}}
+		}
+		By("verifying what jx import produced in the work directory and the repository", func() {
+			err := t.ExpectImported(scenario.Imported, original)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
+
+// cloneScenarioImport clones the git repository to import into the directory, removing its history and naming the
+// project after the application
+func (t *TestOptions) cloneScenarioImport(url string, destDir string) {
+	By(fmt.Sprintf("calling git clone %s", url), func() {
+		_, err := git.PlainClone(destDir, false, &git.CloneOptions{
+			URL:      url,
+			Progress: GinkgoWriter,
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	By("removing the .git directory", func() {
+		err := os.RemoveAll(filepath.Join(destDir, ".git"))
+		utils.ExpectNoError(err)
+		Expect(filepath.Join(destDir, ".git")).ToNot(BeADirectory())
+	})
+
+	By("renaming the project after the application", func() {
+		edits, err := projects.Rename(destDir, t.ApplicationName)
+		Expect(err).NotTo(HaveOccurred())
+		for _, edit := range edits {
+			utils.LogInfof("changed %s\n", edit)
+		}
+	})
+}
+
+// deleteScenarioApplication deletes the application and its repository unless disabled. It is meant to be called from
+// an AfterEach so nothing is left behind when a scenario fails, and deletes nothing if the application was never
+// created.
+func (t *TestOptions) deleteScenarioApplication(scenario *scenarios.Scenario) {
+	if t.ApplicationName == "" {
+		return
+	}
+	if _, err := os.Stat(filepath.Join(t.WorkDir, t.ApplicationName)); os.IsNotExist(err) {
+		utils.LogInfof("not deleting %s as it was not created\n", t.ApplicationName)
+		return
+	}
+
+	if t.DeleteApplications() {
+		args := []string{"delete", "application", "-b", t.ApplicationName}
+		argsStr := strings.Join(args, " ")
+		By(fmt.Sprintf("calling jx %s to delete the application", argsStr), func() {
+			t.ExpectJxExecution(t.WorkDir, TimeoutSessionWait, 0, args...)
+		})
+	}
+
+	if t.DeleteRepos() {
+		args := []string{"delete", "repo", "-b", "--github", "-o", t.GetGitOrganisation(), "-n", t.ApplicationName}
+		if scenario.SourceKind() == scenarios.SourceImport {
+			gitProviderUrl, err := t.GitProviderURL()
+			Expect(err).NotTo(HaveOccurred())
+			args = []string{"delete", "repo", "-b", "-g", gitProviderUrl, "-o", t.GetGitOrganisation(), "-n", t.ApplicationName}
+		}
+		argsStr := strings.Join(args, " ")
+		By(fmt.Sprintf("calling jx %s to delete the repository", argsStr), func() {
+			t.ExpectJxExecution(t.WorkDir, TimeoutSessionWait, 0, args...)
+		})
+	}
+}
+
+func scenarioDescription(scenario *scenarios.Scenario) string {
+	switch scenario.SourceKind() {
+	case scenarios.SourceImport:
+		return "Creating application " + scenario.Name
+	case scenarios.SourceSpring:
+		return "create spring " + scenario.Name + "\n"
+	default:
+		return "quickstart " + scenario.Name + "\n"
+	}
+}
+
+// scenarioAbbreviation abbreviates the scenario name to its initials to keep application names short
+func scenarioAbbreviation(scenario *scenarios.Scenario) string {
+	abbr := ""
+	for _, part := range strings.Split(scenario.Name, "-") {
+		if part != "" {
+			abbr += part[:1]
+		}
+	}
+	if scenario.SourceKind() == scenarios.SourceImport {
+		abbr += "-import"
+	}
+	return abbr
+}
@@ -25,11 +25,17 @@ import (
 	. "github.com/onsi/gomega"
 )
 
-func RunWithReporters(t *testing.T, suiteId string) {
+// ReportsDir returns the directory the reports of the suites are written to, which can be set with REPORTS_DIR
+func ReportsDir() string {
 	reportsDir := os.Getenv("REPORTS_DIR")
 	if reportsDir == "" {
 		reportsDir = filepath.Join("../", "build", "reports")
 	}
+	return reportsDir
+}
+
+func RunWithReporters(t *testing.T, suiteId string) {
+	reportsDir := ReportsDir()
 	err := os.MkdirAll(reportsDir, 0700)
 	if err != nil {
 		t.Errorf("cannot create %s because %v", reportsDir, err)
@@ -212,6 +218,10 @@ func ensureConfiguration() error {
 	utils.LogInfof("GHE_TOKEN:                                          %s\n", os.Getenv("GHE_TOKEN"))
 	utils.LogInfof("GHE_PROVIDER_URL:                                   %s\n", os.Getenv("GHE_PROVIDER_URL"))
 	utils.LogInfof("BDD_LIGHTHOUSE_BASE_REPORT_URL:                     %s\n", LighthouseBaseReportURL)
+	utils.LogInfof("BDD_KEEPER_STATUS_CONTEXT:                          %s\n", KeeperStatusContext)
+	utils.LogInfof("BDD_LIGHTHOUSE_PIPELINE_QUICKSTARTS:                %s\n", LighthousePipelineQuickstarts)
+	utils.LogInfof("BDD_WEBHOOK_RECEIVER_URL:                           %s\n", WebhookReceiverURL)
+	utils.LogInfof("BDD_CHART_REPOSITORY_URL:                           %s\n", ChartRepositoryURL)
 	return nil
 }
 
@@ -2,12 +2,10 @@ package helpers
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -31,6 +29,10 @@ import (
 
 	"github.com/jenkins-x/bdd-jx/test/utils"
 
+	"github.com/jenkins-x/bdd-jx/test/utils/buildlogs"
+	"github.com/jenkins-x/bdd-jx/test/utils/changes"
+	"github.com/jenkins-x/bdd-jx/test/utils/probes"
+	"github.com/jenkins-x/bdd-jx/test/utils/projects"
 	"github.com/jenkins-x/bdd-jx/test/utils/runner"
 
 	. "github.com/onsi/ginkgo"
@@ -47,6 +49,10 @@ const (
 	BDDPullRequestApproverTokenEnvVar = "BDD_APPROVER_ACCESS_TOKEN"
 	// BDDLighthouseBaseReportURLEnvVar is the environment variable we look at to find the possible base URL for status reports in Lighthouse.
 	BDDLighthouseBaseReportURLEnvVar = "BDD_LIGHTHOUSE_BASE_REPORT_URL"
+	// BDDKeeperStatusContextEnvVar is the environment variable we look at for the status context used by Lighthouse keeper.
+	BDDKeeperStatusContextEnvVar = "BDD_KEEPER_STATUS_CONTEXT"
+	// BDDWebhookReceiverURLEnvVar is the environment variable we look at for the public URL of the local webhook receiver.
+	BDDWebhookReceiverURLEnvVar = "BDD_WEBHOOK_RECEIVER_URL"
 )
 
 var (
@@ -72,6 +78,9 @@ var (
 	// TimeoutPreviewUrlReturns Timeout for a preview URL to be available
 	TimeoutPreviewUrlReturns = utils.GetTimeoutFromEnv("BDD_TIMEOUT_PREVIEW_URL_RETURNS", 15)
 
+	// TimeoutPreviewGC Timeout for a preview environment to be garbage collected after its pull request is merged or closed
+	TimeoutPreviewGC = utils.GetTimeoutFromEnv("BDD_TIMEOUT_PREVIEW_GC", 10)
+
 	// TimeoutCmdLine Timeout to wait for a command line execution to complete
 	TimeoutCmdLine = utils.GetTimeoutFromEnv("BDD_TIMEOUT_CMD_LINE", 1)
 
@@ -81,6 +90,12 @@ var (
 	// TimeoutDeploymentRollout defines the timeout waiting for a deployment rollout
 	TimeoutDeploymentRollout = utils.GetTimeoutFromEnv("", 3)
 
+	// RunPreviewGC runs jx gc previews when checking preview environments are garbage collected, rather than waiting for its cron job
+	RunPreviewGC = utils.GetEnv("BDD_RUN_PREVIEW_GC", "false")
+
+	// ImportFixtureDir is the directory of the fixture projects imported by scenarios, relative to the suite
+	ImportFixtureDir = utils.GetEnv("BDD_IMPORT_FIXTURE_DIR", "fixtures")
+
 	// InsecureURLSkipVerify skips the TLS verify when checking URLs of deployed applications
 	InsecureURLSkipVerify = utils.GetEnv("BDD_URL_INSECURE_SKIP_VERIFY", "false")
 	// TimeoutProwActionWait defines the timeout for waiting for a prow action to complete
@@ -104,6 +119,33 @@ var (
 	// LighthouseBaseReportURL is the base URL used by Lighthouse for status reporting, if set.
 	LighthouseBaseReportURL = utils.GetEnv(BDDLighthouseBaseReportURLEnvVar, "")
 
+	// KeeperStatusContext is the status context Lighthouse keeper reports merge pool membership on.
+	KeeperStatusContext = utils.GetEnv(BDDKeeperStatusContextEnvVar, "keeper")
+
+	// LighthousePipelineQuickstarts is the comma separated list of quickstarts whose pull request pipelines the
+	// lighthouse suite overrides to check failed, retried and slow builds with each build pack
+	LighthousePipelineQuickstarts = utils.GetEnv("BDD_LIGHTHOUSE_PIPELINE_QUICKSTARTS", "golang-http,node-http")
+
+	// TimeoutKeeperMerge defines the timeout for keeper to merge the pull requests in its merge pool
+	TimeoutKeeperMerge = utils.GetTimeoutFromEnv("BDD_TIMEOUT_KEEPER_MERGE", 30)
+
+	// WebhookReceiverURL is the URL git providers can reach the local webhook receiver on, if set. When it is not set
+	// webhook deliveries are verified from the Lighthouse webhooks logs instead.
+	WebhookReceiverURL = utils.GetEnv(BDDWebhookReceiverURLEnvVar, "")
+
+	// WebhookReceiverAddress is the local address the webhook receiver listens on
+	WebhookReceiverAddress = utils.GetEnv("BDD_WEBHOOK_RECEIVER_ADDRESS", ":8888")
+
+	// ChartRepositoryURL is the URL helm can reach the local chart repository the apps suite installs its fixture
+	// charts from on, if it is not the address the repository listens on
+	ChartRepositoryURL = utils.GetEnv("BDD_CHART_REPOSITORY_URL", "")
+
+	// ChartRepositoryAddress is the local address the chart repository listens on, by default a free port
+	ChartRepositoryAddress = utils.GetEnv("BDD_CHART_REPOSITORY_ADDRESS", "127.0.0.1:0")
+
+	// TimeoutWebhookDelivery defines the timeout for a webhook to be delivered after the action which triggers it
+	TimeoutWebhookDelivery = utils.GetTimeoutFromEnv("BDD_TIMEOUT_WEBHOOK_DELIVERY", 2)
+
 	// JenkinsBasicAuthPassword is the basic auth configured for Jenkins or the UI, if set.
 	JenkinsBasicAuthPassword = utils.GetEnv("JENKINS_PASSWORD", "")
 
@@ -117,6 +159,8 @@ type TestOptions struct {
 	WorkDir         string
 	ApplicationName string
 	Organisation    string
+
+	createdIssues []testIssue
 }
 
 func AssignWorkDirValue(generatedWorkDir string) {
@@ -370,6 +414,12 @@ func (t *TestOptions) TheApplicationIsRunningInStaging(statusCode int) {
 
 // TheApplicationIsRunning lets assert that the application is deployed into the passed environment
 func (t *TestOptions) TheApplicationIsRunning(statusCode int, environment string) {
+	t.TheApplicationIsRunningAndPasses(environment, probes.StatusProbe(statusCode))
+}
+
+// TheApplicationIsRunningAndPasses lets assert that the application is deployed into the passed environment and the
+// checks of the probe pass against its URL
+func (t *TestOptions) TheApplicationIsRunningAndPasses(environment string, probe *probes.Probe) {
 	u := ""
 	args := []string{"get", "applications", "-e", environment}
 	r := runner.New(t.WorkDir, nil, 0)
@@ -418,8 +468,8 @@ func (t *TestOptions) TheApplicationIsRunning(statusCode int, environment string
 
 	By(fmt.Sprintf("getting %s", u), func() {
 		Expect(u).ShouldNot(BeEmpty(), "no URL for environment %s", environment)
-		err := t.ExpectUrlReturns(u, statusCode, TimeoutUrlReturns)
-		Expect(err).ShouldNot(HaveOccurred(), fmt.Sprintf("request application URL should return %d", statusCode))
+		err := t.ExpectUrlPasses(u, probe, TimeoutUrlReturns)
+		Expect(err).ShouldNot(HaveOccurred(), "application URL should pass the probe")
 	})
 }
 
@@ -520,14 +570,21 @@ func (t *TestOptions) CreatePullRequestWithLocalChange(prTitle string, makeLocal
 // CreatePullRequestAndGetPreviewEnvironment asserts that a pull request can be created
 // on the application and the PR goes green and a preview environment is available
 func (t *TestOptions) CreatePullRequestAndGetPreviewEnvironment(statusCode int) error {
-	applicationName := t.GetApplicationName()
-	workDir := filepath.Join(t.WorkDir, applicationName)
-	owner := t.GetGitOrganisation()
-	r := runner.New(workDir, nil, 0)
+	_, err := t.CreatePullRequestAndGetPreviewEnvironmentPassing(probes.StatusProbe(statusCode))
+	return err
+}
 
-	prTitle := "My First PR commit"
+// PreviewPullRequest is a pull request whose preview environment has been verified
+type PreviewPullRequest struct {
+	PullRequest *parsers.CreatePullRequest
+	// Change is the change to the response of the application made by the pull request, if it made one
+	Change *changes.ResponseChange
+}
 
-	pr := t.CreatePullRequestWithLocalChange(prTitle, func(workDir string) {
+// CreatePullRequestAndGetPreviewEnvironmentPassing asserts that a pull request can be created on the application and
+// the PR goes green and a preview environment is available which passes the checks of the probe
+func (t *TestOptions) CreatePullRequestAndGetPreviewEnvironmentPassing(probe *probes.Probe) (*PreviewPullRequest, error) {
+	pr, err := t.createPullRequestAndGetPreviewEnvironment(func(workDir string) {
 		// now lets make a code change
 		fileName := "README.md"
 		readme := filepath.Join(workDir, fileName)
@@ -539,7 +596,79 @@ func (t *TestOptions) CreatePullRequestAndGetPreviewEnvironment(statusCode int)
 		}
 
 		t.ExpectCommandExecution(workDir, time.Minute, 0, "git", "add", fileName)
+	}, probe)
+	if err != nil {
+		return nil, err
+	}
+	return &PreviewPullRequest{PullRequest: pr}, nil
+}
+
+// CreatePullRequestSettingValuesAndGetPreviewEnvironment asserts that a pull request which sets values in the XML, JSON
+// or YAML files of the application goes green and a preview environment is available which passes the checks of the
+// probe. Each value is set at the path of keys or elements in its file, and must already exist.
+func (t *TestOptions) CreatePullRequestSettingValuesAndGetPreviewEnvironment(values []projects.Value, probe *probes.Probe) (*PreviewPullRequest, error) {
+	pr, err := t.createPullRequestAndGetPreviewEnvironment(func(workDir string) {
+		for _, value := range values {
+			edit, err := projects.Set(workDir, value.File, value.Value, value.Path...)
+			Expect(err).ShouldNot(HaveOccurred())
+			utils.LogInfof("changed %s\n", edit)
+
+			t.ExpectCommandExecution(workDir, time.Minute, 0, "git", "add", value.File)
+		}
+	}, probe)
+	if err != nil {
+		return nil, err
+	}
+	return &PreviewPullRequest{PullRequest: pr}, nil
+}
+
+// CreatePullRequestChangingResponseAndGetPreviewEnvironment asserts that a pull request which changes a string the
+// application responds with on the given path goes green, and that its preview environment passes the checks of the
+// probe and serves the new string while the given environment still serves the old one. This proves the preview is
+// built from the head of the pull request. The string changed is the first in the source matching the regular
+// expression, or changes.DefaultResponseMatch if it is empty.
+func (t *TestOptions) CreatePullRequestChangingResponseAndGetPreviewEnvironment(match string, path string, environment string, probe *probes.Probe) (*PreviewPullRequest, error) {
+	marker := TempDirPrefix + "pr-" + rand.String(5)
+	var change *changes.ResponseChange
+	makeChange := func(workDir string) {
+		var err error
+		change, err = changes.ChangeResponse(workDir, marker, match)
+		Expect(err).ShouldNot(HaveOccurred())
+		utils.LogInfof("changed the %s response '%s' in %s to '%s'\n", change.Language, change.Old, change.File, change.New)
+
+		for _, test := range change.Tests {
+			utils.LogInfof("changed the responses expected by %s to match\n", test)
+		}
+
+		t.ExpectCommandExecution(workDir, time.Minute, 0, "git", append([]string{"add", change.File}, change.Tests...)...)
+	}
+
+	previewProbe := *probe
+	previewProbe.Checks = append(append([]probes.Check{}, probe.Checks...), probes.Check{Path: path, BodyContains: marker})
+	pr, err := t.createPullRequestAndGetPreviewEnvironment(makeChange, &previewProbe)
+	if err != nil {
+		return nil, err
+	}
+
+	By(fmt.Sprintf("checking that the application in %s still serves the old response", environment), func() {
+		t.TheApplicationIsRunningAndPasses(environment, &probes.Probe{Checks: []probes.Check{{
+			Path:            path,
+			BodyMatches:     change.OldPattern(),
+			BodyNotContains: marker,
+		}}})
 	})
+	return &PreviewPullRequest{PullRequest: pr, Change: change}, nil
+}
+
+func (t *TestOptions) createPullRequestAndGetPreviewEnvironment(makeLocalChange func(workDir string), probe *probes.Probe) (*parsers.CreatePullRequest, error) {
+	applicationName := t.GetApplicationName()
+	workDir := filepath.Join(t.WorkDir, applicationName)
+	owner := t.GetGitOrganisation()
+	r := runner.New(workDir, nil, 0)
+
+	prTitle := "My First PR commit"
+
+	pr := t.CreatePullRequestWithLocalChange(prTitle, makeLocalChange)
 
 	prNumber := pr.PullRequestNumber
 	buildNumber := 0
@@ -601,7 +730,7 @@ func (t *TestOptions) CreatePullRequestAndGetPreviewEnvironment(statusCode int)
 
 		utils.LogInfof("Running Preview Environment application at: %s\n", util.ColorInfo(applicationUrl))
 
-		err = t.ExpectUrlReturns(applicationUrl, statusCode, TimeoutUrlReturns)
+		err = t.ExpectUrlPasses(applicationUrl, probe, TimeoutUrlReturns)
 		if err != nil {
 			return logError(fmt.Errorf("preview URL at %s not working: %s", applicationUrl, err.Error()))
 		}
@@ -612,8 +741,7 @@ func (t *TestOptions) CreatePullRequestAndGetPreviewEnvironment(statusCode int)
 		err := Retry(TimeoutPreviewUrlReturns, f)
 		Expect(err).ShouldNot(HaveOccurred(), "preview environment visible at a URL")
 	})
-	return nil
-
+	return pr, nil
 }
 
 // SetGitHubToken runs jx create git token using the values of GIT_ORGANISATION & GH_ACCESS_TOKEN
@@ -677,16 +805,17 @@ func (t *TestOptions) ApprovePullRequestFromLogOutput(provider gits.GitProvider,
 
 // AddApproverAsCollaborator adds the approver user as a collaborator to the given repo, and accepts the invitation.
 func (t *TestOptions) AddApproverAsCollaborator(provider gits.GitProvider, approverProvider gits.GitProvider, repoOwner string, repoName string) error {
+	dialect := DialectForProvider(provider)
 	err := provider.AddCollaborator(PullRequestApproverUsername, repoOwner, repoName)
 	if err != nil {
-		// Ignore the error and just return if the provider is gitlab and the error contains "Member already exists"
-		if strings.Contains(err.Error(), "Member already exists") {
+		// Ignore the error and just return if the provider tells us the user is already a member
+		if IsCollaboratorExistsError(err) {
 			return nil
 		}
 		return err
 	}
-	// If the provider is BBS, just return
-	if provider.IsBitbucketServer() {
+	// Only some providers send an invitation which has to be accepted
+	if !dialect.InvitationsRequireAcceptance {
 		return nil
 	}
 	// Sleep a few seconds since the invitation doesn't seem to always show up promptly.
@@ -732,18 +861,8 @@ func (t *TestOptions) WaitForPullRequestCommitStatus(provider gits.GitProvider,
 			return err
 		}
 		contextStatuses := make(map[string]*gits.GitRepoStatus)
-		// For GitHub, only set the status if it's the first one we see for the context, which is always the newest
-		// For GitLab, ordering is actually the inverse,
-
-		var orderedStatuses []*gits.GitRepoStatus
-		if provider.Kind() == "gitlab" {
-			for i := len(statuses) - 1; i >= 0; i-- {
-				orderedStatuses = append(orderedStatuses, statuses[i])
-			}
-		} else {
-			orderedStatuses = append(orderedStatuses, statuses...)
-		}
-		for _, status := range orderedStatuses {
+		// Only set the status if it's the first one we see for the context, which is the newest once ordered
+		for _, status := range DialectForProvider(provider).NewestStatusesFirst(statuses) {
 			if status == nil {
 				return err
 			}
@@ -795,6 +914,61 @@ func (t *TestOptions) WaitForPullRequestCommitStatus(provider gits.GitProvider,
 	Expect(err).ShouldNot(HaveOccurred())
 }
 
+// WaitForCommitStatus checks a commit until the newest status of each of the given contexts is one of the desired
+// statuses, or a timeout is reached. If no contexts are given every context reported on the commit must have one of
+// the desired statuses.
+func (t *TestOptions) WaitForCommitStatus(provider gits.GitProvider, owner string, repo string, sha string, contexts []string, desiredStatuses ...string) error {
+	checkStatuses := func() error {
+		statuses, err := provider.ListCommitStatus(owner, repo, sha)
+		if err != nil {
+			utils.LogInfof("error fetching commit statuses for %s/%s commit %s: %s\n", owner, repo, sha, err)
+			return err
+		}
+		contextStatuses := make(map[string]*gits.GitRepoStatus)
+		var reported []string
+		for _, status := range DialectForProvider(provider).NewestStatusesFirst(statuses) {
+			if status == nil {
+				continue
+			}
+			if _, exists := contextStatuses[status.Context]; !exists {
+				contextStatuses[status.Context] = status
+				reported = append(reported, status.Context)
+			}
+		}
+		expected := contexts
+		if len(expected) == 0 {
+			expected = reported
+		}
+		if len(expected) == 0 {
+			err = fmt.Errorf("no statuses reported on %s/%s commit %s", owner, repo, sha)
+			utils.LogInfof("WARNING: %s\n", err)
+			return err
+		}
+
+		var wrongStatuses []string
+		for _, c := range expected {
+			status, ok := contextStatuses[c]
+			if !ok {
+				wrongStatuses = append(wrongStatuses, fmt.Sprintf("%s: missing", c))
+			} else if !isADesiredStatus(status.State, desiredStatuses) {
+				wrongStatuses = append(wrongStatuses, fmt.Sprintf("%s: %s", c, status.State))
+			}
+		}
+		if len(wrongStatuses) > 0 {
+			err = fmt.Errorf("wrong or missing status for %s/%s commit %s context(s): %s, expected %s", owner, repo, sha, strings.Join(wrongStatuses, ", "), strings.Join(desiredStatuses, ","))
+			utils.LogInfof("WARNING: %s\n", err)
+			return err
+		}
+		return nil
+	}
+
+	exponentialBackOff := backoff.NewExponentialBackOff()
+	exponentialBackOff.MaxElapsedTime = TimeoutPipelineActivityComplete
+	exponentialBackOff.MaxInterval = 10 * time.Second
+	exponentialBackOff.Reset()
+	return backoff.Retry(checkStatuses, exponentialBackOff)
+}
+
 func isADesiredStatus(status string, desiredStatuses []string) bool {
 	for _, s := range desiredStatuses {
 		if status == s {
@@ -805,26 +979,37 @@ func isADesiredStatus(status string, desiredStatuses []string) bool {
 }
 
 // TailSpecificBuildLog tails the logs of the specified job and number, not passing a specific build number to "jx get build logs"
-// if the build number is 0.
-func (t *TestOptions) TailSpecificBuildLog(jobName string, buildNumber int, maxDuration time.Duration) {
+// if the build number is 0. The tailed log is returned.
+func (t *TestOptions) TailSpecificBuildLog(jobName string, buildNumber int, maxDuration time.Duration) string {
 	args := []string{"get", "build", "logs", "--wait", jobName}
 	if buildNumber != 0 {
 		args = append(args, "--build", strconv.Itoa(buildNumber))
 	}
 	argsStr := strings.Join(args, " ")
+	out := ""
 	By(fmt.Sprintf("checking that there is a job built successfully by calling jx %s", argsStr), func() {
-		t.ExpectJxExecution(t.WorkDir, maxDuration, 0, args...)
+		var err error
+		out, err = runner.New(t.WorkDir, &maxDuration, 0).RunWithBufferedOutput(args...)
+		utils.ExpectNoError(err)
 	})
+	return out
 }
 
-// TailBuildLog tails the logs of the specified job, getting the latest build.
-func (t *TestOptions) TailBuildLog(jobName string, maxDuration time.Duration) {
-	t.TailSpecificBuildLog(jobName, 0, maxDuration)
+// TailBuildLog tails the logs of the specified job, getting the latest build. The tailed log is returned.
+func (t *TestOptions) TailBuildLog(jobName string, maxDuration time.Duration) string {
+	return t.TailSpecificBuildLog(jobName, 0, maxDuration)
 }
 
 // ThereShouldBeAJobThatCompletesSuccessfully asserts that the given job name completes within the given duration
 func (t *TestOptions) ThereShouldBeAJobThatCompletesSuccessfully(jobName string, maxDuration time.Duration) int {
-	t.TailBuildLog(jobName, maxDuration)
+	buildNumber, _ := t.ThereShouldBeAJobThatCompletesSuccessfullyWithLog(jobName, maxDuration)
+	return buildNumber
+}
+
+// ThereShouldBeAJobThatCompletesSuccessfullyWithLog asserts that the given job name completes within the given
+// duration, returning the build number and the log tailed while waiting for it so it need not be fetched again
+func (t *TestOptions) ThereShouldBeAJobThatCompletesSuccessfullyWithLog(jobName string, maxDuration time.Duration) (int, *buildlogs.Log) {
+	logOutput := t.TailBuildLog(jobName, maxDuration)
 
 	r := runner.New(t.WorkDir, nil, 0)
 	// TODO the current --build 1 breaks as it can be number 2 these days!
@@ -884,7 +1069,7 @@ func (t *TestOptions) ThereShouldBeAJobThatCompletesSuccessfully(jobName string,
 		}
 	})
 
-	return buildNumber
+	return buildNumber, buildlogs.Parse(jobName, buildNumber, logOutput)
 }
 
 // Retry retries the given function up to the maximum duration
@@ -942,6 +1127,15 @@ func (t *TestOptions) ExpectJxExecutionWithOutput(dir string, commandTimeout tim
 	return out
 }
 
+// ExpectJxExecutionWithEnv runs a jx command with the given environment variables added to those of the process,
+// returning its output
+func (t *TestOptions) ExpectJxExecutionWithEnv(dir string, commandTimeout time.Duration, exitCode int, env []string, args ...string) string {
+	r := runner.New(dir, &commandTimeout, exitCode).WithEnv(env...)
+	out, err := r.RunWithOutput(args...)
+	utils.ExpectNoError(err)
+	return out
+}
+
 // DeleteApplications should we delete applications after the quickstart has run
 func (t *TestOptions) DeleteApplications() bool {
 	text := os.Getenv("JX_DISABLE_DELETE_APP")
@@ -978,36 +1172,21 @@ func (t *TestOptions) WeShouldTestChatOpsCommands() bool {
 
 // ExpectUrlReturns expects that the given URL returns the given status code within the given time period
 func (t *TestOptions) ExpectUrlReturns(url string, expectedStatusCode int, maxDuration time.Duration) error {
-	lastLoggedStatus := -1
-	f := func() error {
-		skipVerify := false
-		if strings.ToLower(InsecureURLSkipVerify) == "true" {
-			skipVerify = true
-		}
-		transport := &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: skipVerify,
-			},
-		}
-		var httpClient = &http.Client{
-			Timeout:   time.Second * 30,
-			Transport: transport,
-		}
-		response, err := httpClient.Get(url)
-		if err != nil {
-			return err
-		}
-		actualStatusCode := response.StatusCode
-		if actualStatusCode != lastLoggedStatus {
-			lastLoggedStatus = actualStatusCode
-			utils.LogInfof("Invoked %s and got return code: %s\n", util.ColorInfo(url), util.ColorInfo(strconv.Itoa(actualStatusCode)))
-		}
-		if actualStatusCode == expectedStatusCode {
-			return nil
-		}
-		return fmt.Errorf("invalid HTTP status code for %s expected %d but got %d", url, expectedStatusCode, actualStatusCode)
+	return t.ExpectUrlPasses(url, probes.StatusProbe(expectedStatusCode), maxDuration)
+}
+
+// ExpectUrlPasses expects that the checks of the probe pass against the given URL within the given time period, unless
+// the retry policy of the probe has its own timeout. TLS certificates are verified unless BDD_URL_INSECURE_SKIP_VERIFY
+// is true.
+func (t *TestOptions) ExpectUrlPasses(url string, probe *probes.Probe, maxDuration time.Duration) error {
+	p := *probe
+	if strings.ToLower(InsecureURLSkipVerify) == "true" {
+		p.InsecureSkipVerify = true
+	}
+	if p.Retry.Timeout == nil {
+		p.Retry.Timeout = &probes.Duration{Duration: maxDuration}
 	}
-	return RetryExponentialBackoff(maxDuration, f)
+	return p.Run(url)
 }
 
 func (t *TestOptions) CreateChatOpsCommands(commands []string) error {
@@ -1030,17 +1209,13 @@ func (t *TestOptions) CreateIssueAndAssignToUserWithChatOpsCommand(issue *gits.G
 	}
 
 	utils.LogInfof("created issue with number %d\n", *createdIssue.Number)
+	t.registerIssue(issue.Owner, issue.Repo, *createdIssue.Number)
 
-	cmd := "assign"
-	// Deal with GitLab hijacking /assign
-	if provider.Kind() == "gitlab" {
-		cmd = "lh-" + cmd
-	}
 	err = provider.CreateIssueComment(
 		issue.Owner,
 		issue.Repo,
 		*createdIssue.Number,
-		fmt.Sprintf("/%s %s", cmd, provider.CurrentUsername()),
+		DialectForProvider(provider).Command("assign", provider.CurrentUsername()),
 	)
 	if err != nil {
 		return err
@@ -1110,12 +1285,7 @@ func (t *TestOptions) ApprovePullRequest(defaultProvider gits.GitProvider, appro
 	Expect(err).ShouldNot(HaveOccurred())
 
 	By("approving the PR")
-	approveCmd := "approve"
-	if approverProvider.Kind() == "gitlab" {
-		approveCmd = "lh-" + approveCmd
-	}
-
-	err = approverProvider.AddPRComment(pullRequest, fmt.Sprintf("/%s", approveCmd))
+	err = approverProvider.AddPRComment(pullRequest, DialectForProvider(approverProvider).Command("approve"))
 	Expect(err).ShouldNot(HaveOccurred())
 
 	By("waiting for the approved label to appear")
@@ -1202,7 +1372,7 @@ func (t *TestOptions) AddReviewerToPullRequestWithChatOpsCommand(provider gits.G
 
 	err = t.ExpectThatPullRequestMatches(provider, *pullRequest.Number, pullRequest.Owner, pullRequest.Repo, func(request *scm.PullRequest) error {
 		if len(request.Assignees) == 0 && len(request.Reviewers) == 0 {
-			return fmt.Errorf("expected %s as reviewer, but no reviewers or assignees set on PR")
+			return fmt.Errorf("expected %s as reviewer, but no reviewers or assignees set on PR", reviewer)
 		}
 		for _, r := range request.Reviewers {
 			if r.Login == reviewer {
@@ -0,0 +1,169 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/webhooks"
+	"github.com/jenkins-x/go-scm/scm"
+	cmd "github.com/jenkins-x/jx/v2/pkg/cmd/clients"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// LighthouseHMACTokenSecret is the secret holding the token Lighthouse validates webhook signatures with
+	LighthouseHMACTokenSecret = "lighthouse-hmac-token"
+	// LighthouseWebhooksSelector selects the pods which receive webhooks for Lighthouse
+	LighthouseWebhooksSelector = "app=lighthouse-webhooks"
+)
+
+// webhookDrivers are the drivers of the webhook receiver for the names of the go-scm drivers in the provider dialects
+var webhookDrivers = map[string]string{
+	"github": webhooks.DriverGitHub,
+	"gitlab": webhooks.DriverGitLab,
+	"stash":  webhooks.DriverStash,
+}
+
+// webhookDriver returns the driver the webhook receiver parses the webhooks of the provider of the dialect with
+func webhookDriver(dialect *ProviderDialect) (string, error) {
+	driver, ok := webhookDrivers[dialect.WebhookDriver]
+	if !ok {
+		return "", errors.Errorf("no webhook receiver driver for the %s webhooks of git provider kind %s", dialect.WebhookDriver, dialect.Kind)
+	}
+	return driver, nil
+}
+
+// WebhookVerifier verifies that the webhooks expected for the actions taken on a repository were delivered
+type WebhookVerifier interface {
+	// ExpectWebhook returns an error unless a webhook of the given kind was delivered for the repository after the given
+	// time, within TimeoutWebhookDelivery
+	ExpectWebhook(kind scm.WebhookKind, since time.Time) error
+	// Close stops verifying webhooks
+	Close() error
+}
+
+// NewWebhookVerifier returns a verifier for the webhooks delivered for the given repository. If WebhookReceiverURL is
+// set a local receiver is registered on the repository, validating each delivery against the Lighthouse HMAC token;
+// otherwise the Lighthouse webhooks logs are searched.
+func (t *TestOptions) NewWebhookVerifier(provider gits.GitProvider, owner string, repo string) (WebhookVerifier, error) {
+	kubeClient, ns, err := cmd.NewFactory().CreateKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	if WebhookReceiverURL == "" {
+		utils.LogInfof("verifying webhooks for %s/%s from the Lighthouse webhooks logs\n", owner, repo)
+		return &logWebhookVerifier{kubeClient: kubeClient, ns: ns, repository: scm.Join(owner, repo)}, nil
+	}
+
+	token, err := LighthouseHMACToken(kubeClient, ns)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := webhookDriver(DialectForProvider(provider))
+	if err != nil {
+		return nil, err
+	}
+	receiver, err := webhooks.NewReceiver(driver, token)
+	if err != nil {
+		return nil, err
+	}
+	err = receiver.Start(WebhookReceiverAddress)
+	if err != nil {
+		return nil, err
+	}
+	utils.LogInfof("registering webhook %s on %s/%s\n", WebhookReceiverURL, owner, repo)
+	err = provider.CreateWebHook(&gits.GitWebHookArguments{
+		Owner:  owner,
+		Repo:   &gits.GitRepository{Name: repo},
+		URL:    WebhookReceiverURL,
+		Secret: token,
+	})
+	if err != nil {
+		_ = receiver.Close()
+		return nil, errors.Wrapf(err, "registering webhook %s on %s/%s", WebhookReceiverURL, owner, repo)
+	}
+	return &receiverWebhookVerifier{receiver: receiver, repository: scm.Join(owner, repo)}, nil
+}
+
+// LighthouseHMACToken returns the token Lighthouse validates webhook signatures with
+func LighthouseHMACToken(kubeClient kubernetes.Interface, ns string) (string, error) {
+	secret, err := kubeClient.CoreV1().Secrets(ns).Get(LighthouseHMACTokenSecret, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "getting secret %s in namespace %s", LighthouseHMACTokenSecret, ns)
+	}
+	token := string(secret.Data["hmac"])
+	if token == "" {
+		return "", fmt.Errorf("no hmac token in secret %s in namespace %s", LighthouseHMACTokenSecret, ns)
+	}
+	return token, nil
+}
+
+type receiverWebhookVerifier struct {
+	receiver   *webhooks.Receiver
+	repository string
+}
+
+func (v *receiverWebhookVerifier) ExpectWebhook(kind scm.WebhookKind, since time.Time) error {
+	d, err := v.receiver.WaitFor(TimeoutWebhookDelivery, webhooks.MatchAll(
+		webhooks.MatchKind(kind),
+		webhooks.MatchReceivedAfter(since),
+		func(d *webhooks.Delivery) bool {
+			return strings.EqualFold(d.Repository, v.repository)
+		},
+	))
+	if err != nil {
+		return errors.Wrapf(err, "waiting for %s webhook for %s", kind, v.repository)
+	}
+	utils.LogInfof("received %s webhook %s for %s\n", d.Kind, d.Event, d.Repository)
+	return nil
+}
+
+func (v *receiverWebhookVerifier) Close() error {
+	return v.receiver.Close()
+}
+
+type logWebhookVerifier struct {
+	kubeClient kubernetes.Interface
+	ns         string
+	repository string
+}
+
+func (v *logWebhookVerifier) ExpectWebhook(kind scm.WebhookKind, since time.Time) error {
+	f := func() error {
+		pods, err := v.kubeClient.CoreV1().Pods(v.ns).List(metav1.ListOptions{LabelSelector: LighthouseWebhooksSelector})
+		if err != nil {
+			return err
+		}
+		if len(pods.Items) == 0 {
+			return backoff.Permanent(fmt.Errorf("no pods matching %s in namespace %s", LighthouseWebhooksSelector, v.ns))
+		}
+		for _, pod := range pods.Items {
+			data, err := v.kubeClient.CoreV1().Pods(v.ns).GetLogs(pod.Name, &v1.PodLogOptions{SinceTime: &metav1.Time{Time: since}}).DoRaw()
+			if err != nil {
+				utils.LogInfof("WARNING: failed to get logs of pod %s: %s\n", pod.Name, err)
+				continue
+			}
+			for _, entry := range webhooks.ParseHookLog(string(data)) {
+				if entry.Kind == kind && strings.EqualFold(entry.Repository(), v.repository) {
+					utils.LogInfof("Lighthouse processed %s webhook for %s: %s\n", entry.Kind, entry.Repository(), entry.Message)
+					return nil
+				}
+			}
+		}
+		err = fmt.Errorf("no %s webhook for %s found in the Lighthouse webhooks logs", kind, v.repository)
+		utils.LogInfof("WARNING: %s\n", err)
+		return err
+	}
+	return RetryExponentialBackoff(TimeoutWebhookDelivery, f)
+}
+
+func (v *logWebhookVerifier) Close() error {
+	return nil
+}
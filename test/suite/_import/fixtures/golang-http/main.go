@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+)
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	title := "Jenkins X golang http example"
+	from := ""
+	if r.URL != nil {
+		from = r.URL.String()
+	}
+	fmt.Fprintf(w, "Hello from:  "+title+"\n%s\n", from)
+}
+
+func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	http.HandleFunc("/", handler)
+	log.Fatal(http.ListenAndServe(":"+port, nil))
+}
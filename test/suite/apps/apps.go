@@ -7,8 +7,11 @@ import (
 	"strings"
 
 	"github.com/jenkins-x/bdd-jx/test/helpers"
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/promotion"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
 )
 
 var _ = AppTests()
@@ -40,7 +43,8 @@ type AppTestOptions struct {
 }
 
 // AppTest builds a Ginkgo test testing the app workflow - create, get, upgrade, delete - for the specified app in
-// the given version.
+// the given version. In a GitOps setup each change is made by a pull request on the dev environment repository, which
+// is checked before it merges and the dev environment pipeline deploys it.
 func AppTest(testAppName string, version string) bool {
 	return Describe("Apps Framework", func() {
 		var T AppTestOptions
@@ -52,19 +56,22 @@ func AppTest(testAppName string, version string) bool {
 					WorkDir:         helpers.WorkDir,
 				},
 			}
-			if T.GitOpsEnabled() {
-				Skip(fmt.Sprintf("Skipping apps tests for %s since they require a non gitops setup", testAppName))
+			if isLocalChart(testAppName) && !helpers.ChartRepositoryConfigured() {
+				Skip(fmt.Sprintf("Skipping apps tests for %s since neither %s nor %s is set to an address the cluster can reach the local chart repository on", testAppName, "BDD_CHART_REPOSITORY_URL", "BDD_CHART_REPOSITORY_ADDRESS"))
+			}
+			if T.GitOpsEnabled() && isLocalChart(testAppName) && helpers.ChartRepositoryURL == "" {
+				Skip(fmt.Sprintf("Skipping apps tests for %s in a gitops setup since %s is not set to a URL the dev environment pipeline can reach", testAppName, "BDD_CHART_REPOSITORY_URL"))
 			}
 		})
 
 		_ = T.AddAppTests(testAppName, version)
-		_ = T.GetAppsTests(testAppName)
-		_ = T.UpgradeAppTests(testAppName)
+		_ = T.GetAppsTests(testAppName, version)
+		_ = T.UpgradeAppTests(testAppName, version)
 		_ = T.DeleteAppTests(testAppName)
 	})
 }
 
-// AddAppTests add app tests
+// AddAppTests checks that jx add app adds the app in the given version, deploying the resources of its chart
 func (t *AppTestOptions) AddAppTests(testAppName string, version string) bool {
 	return Describe("Adding an app", func() {
 		Context("by running jx add app "+testAppName, func() {
@@ -75,13 +82,14 @@ func (t *AppTestOptions) AddAppTests(testAppName string, version string) bool {
 					t.ExpectJxExecution(t.WorkDir, timeoutAppTests, 1, args...)
 				})
 
-				args = []string{"add", "app", testAppName, "--repository", helpers.DefaultRepositoryURL}
-				if version != "" {
-					args = append(args, "--version", version)
+				pinned := startVersion(testAppName, version)
+				args = []string{"add", "app", testAppName, "--repository", repositoryURL(testAppName), "--batch-mode"}
+				if pinned != "" {
+					args = append(args, "--version", pinned)
 				}
-				argsStr = strings.Join(args, " ")
-				By(fmt.Sprintf("checking that jx %s exits with signal 0", argsStr), func() {
-					t.ExpectJxExecution(t.WorkDir, timeoutAppTests, 0, args...)
+				args = append(args, t.setValuesArgs(testAppName)...)
+				t.runAppCommand(args, nil, func(diff *promotion.RequirementsDiff) error {
+					return diff.ExpectAdded(testAppName, pinned, repositoryURL(testAppName))
 				})
 
 				args = []string{"get", "app", testAppName}
@@ -89,12 +97,21 @@ func (t *AppTestOptions) AddAppTests(testAppName string, version string) bool {
 				By(fmt.Sprintf("calling jx %s to check that the app exists", args), func() {
 					t.ExpectJxExecution(t.WorkDir, timeoutAppTests, 0, args...)
 				})
+
+				t.expectApp(testAppName, pinned, t.expectedAppValues(testAppName))
+				if isLocalChart(testAppName) {
+					By(fmt.Sprintf("checking that version %s of %s is deployed with the requested values and its hooks ran", pinned, testAppName), func() {
+						Expect(verifyLocalChartInstalled(testAppName, pinned, t.expectedValues())).Should(Succeed())
+					})
+				}
 			})
 		})
 	})
 }
 
-func (t *AppTestOptions) GetAppsTests(testAppName string) bool {
+// GetAppsTests checks that jx get app lists the app in the version it was added in, from the repository it was
+// added from, in each output format
+func (t *AppTestOptions) GetAppsTests(testAppName string, version string) bool {
 	return Describe("Getting an app", func() {
 		Context("by running jx get apps "+testAppName, func() {
 			It("should display the correct output", func() {
@@ -104,42 +121,46 @@ func (t *AppTestOptions) GetAppsTests(testAppName string) bool {
 					t.ExpectJxExecution(t.WorkDir, timeoutAppTests, 0, args...)
 				})
 
-				args = []string{"get", "app", testAppName, "-o", "yaml"}
-				argsStr = strings.Join(args, " ")
-				By(fmt.Sprintf("checking jx %s exits with signal 0", argsStr), func() {
-					t.ExpectJxExecution(t.WorkDir, timeoutAppTests, 0, args...)
-				})
-
-				args = []string{"get", "app", testAppName, "-o", "json"}
-				argsStr = strings.Join(args, " ")
-				By(fmt.Sprintf("checking jx %s exits with signal 0", argsStr), func() {
-					t.ExpectJxExecution(t.WorkDir, timeoutAppTests, 0, args...)
-				})
+				t.expectApp(testAppName, startVersion(testAppName, version), t.expectedAppValues(testAppName))
 			})
 		})
 	})
 }
 
-func (t *AppTestOptions) UpgradeAppTests(testAppName string) bool {
+// UpgradeAppTests checks that jx upgrade app upgrades the app from the version it was added in to the latest version
+// in its chart repository
+func (t *AppTestOptions) UpgradeAppTests(testAppName string, version string) bool {
 	return Describe("Upgrading an app", func() {
 		Context("by running jx upgrade app "+testAppName, func() {
 			It("should be upgraded", func() {
-				args := []string{"get", "app", testAppName}
-				argsStr := strings.Join(args, " ")
-				By(fmt.Sprintf("checking jx %s exits with signal 0", argsStr), func() {
-					t.ExpectJxExecution(t.WorkDir, timeoutAppTests, 0, args...)
+				before := t.expectApp(testAppName, startVersion(testAppName, version), t.expectedAppValues(testAppName))
+
+				args := []string{"upgrade", "app", testAppName, "--repository", repositoryURL(testAppName), "--batch-mode"}
+				args = append(args, t.setValuesArgs(testAppName)...)
+				t.runAppCommand(args, nil, func(diff *promotion.RequirementsDiff) error {
+					if isLocalChart(testAppName) {
+						return diff.ExpectUpgraded(testAppName, before.Version, latestLocalChartVersion())
+					}
+					return diff.ExpectUpgraded(testAppName, before.Version, "")
 				})
-				args = []string{"upgrade", "app", testAppName}
-				argsStr = strings.Join(args, " ")
-				By(fmt.Sprintf("checking jx %s exits with signal 0", argsStr), func() {
-					t.ExpectJxExecution(t.WorkDir, timeoutAppTests, 0, args...)
+
+				if !isLocalChart(testAppName) {
+					after := t.expectApp(testAppName, "", nil)
+					utils.LogInfof("upgraded %s from version %s to %s\n", testAppName, before.Version, after.Version)
+					return
+				}
+				latest := latestLocalChartVersion()
+				Expect(before.Version).ShouldNot(Equal(latest), "the app must be added in an older version than the one it is upgraded to")
+				t.expectApp(testAppName, latest, t.expectedAppValues(testAppName))
+				By(fmt.Sprintf("checking that %s was upgraded to version %s with the requested values and its hooks ran", testAppName, latest), func() {
+					Expect(verifyLocalChartInstalled(testAppName, latest, t.expectedValues())).Should(Succeed())
 				})
 			})
 		})
 	})
 }
 
-// DeleteAppTests delete app tests
+// DeleteAppTests checks that jx delete app deletes the app and the resources of its chart
 func (t *AppTestOptions) DeleteAppTests(testAppName string) bool {
 	return Describe("Deleting an app", func() {
 		Context("by running jx delete app "+testAppName, func() {
@@ -150,10 +171,8 @@ func (t *AppTestOptions) DeleteAppTests(testAppName string) bool {
 					t.ExpectJxExecution(t.WorkDir, timeoutAppTests, 0, args...)
 				})
 				args = []string{"delete", "app", testAppName}
-				argsStr = strings.Join(args, " ")
-				By(fmt.Sprintf("checking jx %s exits with signal 0", argsStr), func() {
-
-					t.ExpectJxExecution(t.WorkDir, timeoutAppTests, 0, args...)
+				t.runAppCommand(args, nil, func(diff *promotion.RequirementsDiff) error {
+					return diff.ExpectRemoved(testAppName)
 				})
 
 				args = []string{"get", "app", testAppName}
@@ -162,6 +181,12 @@ func (t *AppTestOptions) DeleteAppTests(testAppName string) bool {
 
 					t.ExpectJxExecution(t.WorkDir, timeoutAppTests, 1, args...)
 				})
+
+				if isLocalChart(testAppName) {
+					By(fmt.Sprintf("checking that the release of %s was deleted", testAppName), func() {
+						Expect(verifyLocalChartDeleted(testAppName)).Should(Succeed())
+					})
+				}
 			})
 		})
 	})
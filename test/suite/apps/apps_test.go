@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/jenkins-x/bdd-jx/test/helpers"
+	"github.com/jenkins-x/bdd-jx/test/suite/apps"
 
 	. "github.com/onsi/ginkgo"
 )
@@ -14,4 +15,4 @@ func TestSuite(t *testing.T) {
 
 var _ = BeforeSuite(helpers.BeforeSuiteCallback)
 
-var _ = SynchronizedAfterSuite(func() {}, helpers.SynchronizedAfterSuiteCallback)
+var _ = SynchronizedAfterSuite(apps.CloseChartRepository, helpers.SynchronizedAfterSuiteCallback)
@@ -0,0 +1,12 @@
+{{/*
+The name of the resources of the chart: the release name if it contains the chart name, as it does when jx installs
+the chart as an app, otherwise the release name followed by the chart name, as when it is a dependency of the dev
+environment chart in a GitOps setup.
+*/}}
+{{- define "bdd-app.fullname" -}}
+{{- if contains .Chart.Name .Release.Name -}}
+{{- .Release.Name | trunc 63 | trimSuffix "-" -}}
+{{- else -}}
+{{- printf "%s-%s" .Release.Name .Chart.Name | trunc 63 | trimSuffix "-" -}}
+{{- end -}}
+{{- end -}}
@@ -1,15 +1,254 @@
 package apps
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cenkalti/backoff"
+	"github.com/jenkins-x/bdd-jx/test/helpers"
 	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/charts"
+	"github.com/jenkins-x/bdd-jx/test/utils/parsers"
+	"github.com/jenkins-x/bdd-jx/test/utils/promotion"
+	cmd "github.com/jenkins-x/jx/v2/pkg/cmd/clients"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
 )
 
+// helmHookAnnotation marks the resources of a chart which are hooks
+const helmHookAnnotation = "helm.sh/hook"
+
 var (
-	includeApps = "jx-app-jacoco:0.0.139"
+	includeApps = "bdd-app:0.0.1"
+
+	// localChartFixtureDir holds the charts served by the local chart repository
+	localChartFixtureDir = "charts"
+	// localChartVersions are the versions each local chart is served in, the last being the one apps are upgraded to
+	localChartVersions = []string{"0.0.1", "0.0.2"}
+	// localChartValues are the values local charts are added and upgraded with, which their ConfigMap records
+	localChartValues = map[string]string{"greeting": "hello-from-bdd", "replicas": "2"}
+	// localChartDefaultValues are the values in the values.yaml of the local charts
+	localChartDefaultValues = map[string]string{"greeting": "hello", "replicas": "1"}
 
 	uiAppName    = "jx-app-ui"
 	uiAppVersion = utils.GetEnv("JX_APP_VERSION", "0.0.59")
 
 	// Timeout for waiting for jx add app to complete
 	timeoutAppTests = utils.GetTimeoutFromEnv("BDD_TIMEOUT_APP_TESTS", 60)
+
+	chartRepository     *charts.Server
+	chartRepositoryErr  error
+	chartRepositoryOnce sync.Once
 )
+
+// localChartRepository returns the local chart repository serving the fixture charts, starting it the first time
+func localChartRepository() *charts.Server {
+	chartRepositoryOnce.Do(func() {
+		chartRepository, chartRepositoryErr = helpers.StartChartRepository(localChartFixtureDir, localChartVersions...)
+	})
+	Expect(chartRepositoryErr).ShouldNot(HaveOccurred())
+	return chartRepository
+}
+
+// CloseChartRepository stops the local chart repository if it was started
+func CloseChartRepository() {
+	if chartRepository != nil {
+		_ = chartRepository.Close()
+	}
+}
+
+// isLocalChart returns true if the app is one of the fixture charts served by the local chart repository
+func isLocalChart(app string) bool {
+	return localChartRepository().Has(app)
+}
+
+// repositoryURL returns the URL of the chart repository the app is installed from
+func repositoryURL(app string) string {
+	if isLocalChart(app) {
+		return localChartRepository().URL
+	}
+	return helpers.DefaultRepositoryURL
+}
+
+// latestLocalChartVersion returns the version jx upgrade app upgrades a local chart to
+func latestLocalChartVersion() string {
+	return localChartVersions[len(localChartVersions)-1]
+}
+
+// startVersion returns the version an app is added in: the requested version, or the oldest version of a local chart
+// so that it can be upgraded
+func startVersion(app string, version string) string {
+	if version == "" && isLocalChart(app) {
+		return localChartVersions[0]
+	}
+	return version
+}
+
+// setValuesArgs returns the --set arguments local charts are added and upgraded with. Values cannot be set when
+// using GitOps for the dev environment, so there are none in a GitOps setup.
+func (t *AppTestOptions) setValuesArgs(app string) []string {
+	if !isLocalChart(app) || t.GitOpsEnabled() {
+		return nil
+	}
+	var keys []string
+	for k := range localChartValues {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var args []string
+	for _, k := range keys {
+		args = append(args, "--set", fmt.Sprintf("%s=%s", k, localChartValues[k]))
+	}
+	return args
+}
+
+// expectedValues returns the values local charts are expected to be deployed with
+func (t *AppTestOptions) expectedValues() map[string]string {
+	if t.GitOpsEnabled() {
+		return localChartDefaultValues
+	}
+	return localChartValues
+}
+
+// expectedAppValues returns the values jx get app is expected to list the app with, which are only known for local
+// charts
+func (t *AppTestOptions) expectedAppValues(app string) map[string]string {
+	if !isLocalChart(app) {
+		return nil
+	}
+	return t.expectedValues()
+}
+
+// runAppCommand runs a jx add, upgrade or delete app command with the given environment variables, returning its
+// output. In a GitOps setup the pull request the command opens on the dev environment repository is checked with the
+// verify function, and the command returns once the dev environment pipeline has deployed it.
+func (t *AppTestOptions) runAppCommand(args []string, env []string, verify func(*promotion.RequirementsDiff) error) string {
+	var out string
+	if !t.GitOpsEnabled() {
+		By(fmt.Sprintf("checking jx %s exits with signal 0", strings.Join(args, " ")), func() {
+			out = t.ExpectJxExecutionWithEnv(t.WorkDir, timeoutAppTests, 0, env, args...)
+		})
+		return out
+	}
+
+	devRepo, err := gits.ParseGitURL(t.GitOpsDevRepo())
+	Expect(err).ShouldNot(HaveOccurred())
+	// read the build number before the pull request can merge, as the merge triggers the build
+	build := t.NextBuildNumber(devRepo)
+	if helpers.PullRequestApproverUsername == "" {
+		args = append(args, "--auto-merge")
+	}
+	By(fmt.Sprintf("checking jx %s opens a pull request on %s", strings.Join(args, " "), t.GitOpsDevRepo()), func() {
+		out = t.ExpectJxExecutionWithEnv(t.WorkDir, timeoutAppTests, 0, env, args...)
+	})
+	t.ExpectDevEnvironmentPullRequest(devRepo, build, out, verify)
+	return out
+}
+
+// expectApp asserts that jx get app lists the app in the given version, if any, from the chart repository it is
+// installed from, with both -o yaml and -o json, returning it. The app must be listed with the given values, if any,
+// when jx lists the values of apps.
+func (t *AppTestOptions) expectApp(app string, version string, values map[string]string) parsers.App {
+	var answer parsers.App
+	for _, format := range []string{"yaml", "json"} {
+		args := []string{"get", "app", app, "-o", format}
+		By(fmt.Sprintf("checking jx %s lists %s in version %s from %s", strings.Join(args, " "), app, version, repositoryURL(app)), func() {
+			out := t.ExpectJxExecutionWithOutput(t.WorkDir, timeoutAppTests, 0, args...)
+			apps, err := parsers.ParseJxGetApps(out)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(apps).Should(HaveKey(app))
+			answer = apps[app]
+			Expect(answer.Version).ShouldNot(BeEmpty())
+			if version != "" {
+				Expect(answer.Version).Should(Equal(version))
+			}
+			Expect(strings.TrimSuffix(answer.ChartRepository, "/")).Should(Equal(strings.TrimSuffix(repositoryURL(app), "/")))
+			if len(values) > 0 {
+				if answer.Values == nil {
+					utils.LogInfof("jx %s does not list the values of %s so only its ConfigMap is checked for them\n", strings.Join(args, " "), app)
+				} else {
+					Expect(answer.ExpectValues(values)).Should(Succeed())
+				}
+			}
+		})
+	}
+	return answer
+}
+
+// verifyLocalChartInstalled checks that a local chart is deployed in the given version with the given values and
+// that its hooks ran, by finding the ConfigMaps the chart and its hook create in the dev namespace by their app label.
+// Finding them by label rather than by name works whatever the release is called, which is the release of the dev
+// environment in a GitOps setup.
+func verifyLocalChartInstalled(app string, version string, values map[string]string) error {
+	return helpers.RetryExponentialBackoff(helpers.TimeoutDeploymentRollout, func() error {
+		configMaps, hooks, err := localChartConfigMaps(app)
+		if err != nil {
+			return err
+		}
+		if len(configMaps) != 1 || len(hooks) != 1 {
+			err = fmt.Errorf("expected a ConfigMap and a hook ConfigMap labelled app=%s but found %d and %d", app, len(configMaps), len(hooks))
+			utils.LogInfof("WARNING: %s\n", err)
+			return err
+		}
+		for _, cm := range []*corev1.ConfigMap{configMaps[0], hooks[0]} {
+			if actual := cm.Data["version"]; actual != version {
+				err = fmt.Errorf("ConfigMap %s of %s has version %s but expected %s", cm.Name, app, actual, version)
+				utils.LogInfof("WARNING: %s\n", err)
+				return err
+			}
+		}
+		for k, v := range values {
+			if actual := configMaps[0].Data[k]; actual != v {
+				return backoff.Permanent(fmt.Errorf("%s has value %s=%s but expected %s", app, k, actual, v))
+			}
+		}
+		return nil
+	})
+}
+
+// verifyLocalChartDeleted checks that the ConfigMap of a local chart has been deleted. Helm does not delete the
+// ConfigMaps of hooks.
+func verifyLocalChartDeleted(app string) error {
+	return helpers.RetryExponentialBackoff(helpers.TimeoutDeploymentRollout, func() error {
+		configMaps, _, err := localChartConfigMaps(app)
+		if err != nil {
+			return err
+		}
+		if len(configMaps) > 0 {
+			err = fmt.Errorf("ConfigMap %s of %s still exists", configMaps[0].Name, app)
+			utils.LogInfof("WARNING: %s\n", err)
+			return err
+		}
+		return nil
+	})
+}
+
+// localChartConfigMaps returns the ConfigMaps in the dev namespace labelled with the app, split into those created
+// by the chart and those created by its hooks
+func localChartConfigMaps(app string) ([]*corev1.ConfigMap, []*corev1.ConfigMap, error) {
+	kubeClient, ns, err := cmd.NewFactory().CreateKubeClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	list, err := kubeClient.CoreV1().ConfigMaps(ns).List(metav1.ListOptions{LabelSelector: "app=" + app})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "listing ConfigMaps labelled app=%s in namespace %s", app, ns)
+	}
+	var configMaps, hooks []*corev1.ConfigMap
+	for i := range list.Items {
+		cm := &list.Items[i]
+		if cm.Annotations[helmHookAnnotation] != "" {
+			hooks = append(hooks, cm)
+		} else {
+			configMaps = append(configMaps, cm)
+		}
+	}
+	return configMaps, hooks, nil
+}
@@ -0,0 +1,328 @@
+package apps
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cenkalti/backoff"
+	"github.com/jenkins-x/bdd-jx/test/helpers"
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/answers"
+	"github.com/jenkins-x/bdd-jx/test/utils/promotion"
+	cmd "github.com/jenkins-x/jx/v2/pkg/cmd/clients"
+	"github.com/jenkins-x/jx/v2/pkg/helm"
+	"github.com/jenkins-x/jx/v2/pkg/io/secrets"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/yaml"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// valuesAnnotation is the annotation jx stashes the values an app was installed with on its App in
+const valuesAnnotation = "jenkins.io/values.yaml"
+
+var (
+	// valuesAppName is the local chart whose values.schema.json asks for a secret
+	valuesAppName = "bdd-values-app"
+	// valuesAnswersDir holds the answers to the questions of the values.schema.json of local charts
+	valuesAnswersDir = "answers"
+	// valuesFilesDir holds the values files local charts are added with using --values
+	valuesFilesDir = "values"
+	// valuesAppSetValues are the values set with --set when the dev environment does not use GitOps
+	valuesAppSetValues = map[string]string{"replicas": "2"}
+)
+
+var _ = ValuesAppTest(valuesAppName)
+
+// ValuesAppTest builds a Ginkgo test adding an app whose values.schema.json asks for a secret, answering its questions
+// in batch mode from an answers file and passing values with --values and --set. It checks the values the Deployment,
+// ConfigMap and Secret of the app are deployed with, and that the secret answers are kept out of the dev environment
+// repository and the App, being stored in vault and referenced from them if the cluster uses vault.
+func ValuesAppTest(testAppName string) bool {
+	return Describe("Apps Framework values and secrets", func() {
+		var T AppTestOptions
+
+		BeforeEach(func() {
+			T = AppTestOptions{
+				helpers.TestOptions{
+					ApplicationName: helpers.TempDirPrefix + testAppName + "-" + strconv.FormatInt(GinkgoRandomSeed(), 10),
+					WorkDir:         helpers.WorkDir,
+				},
+			}
+			if !helpers.ChartRepositoryConfigured() {
+				Skip(fmt.Sprintf("Skipping apps values tests for %s since neither %s nor %s is set to an address the cluster can reach the local chart repository on", testAppName, "BDD_CHART_REPOSITORY_URL", "BDD_CHART_REPOSITORY_ADDRESS"))
+			}
+			if T.GitOpsEnabled() && helpers.ChartRepositoryURL == "" {
+				Skip(fmt.Sprintf("Skipping apps values tests for %s in a gitops setup since %s is not set to a URL the dev environment pipeline can reach", testAppName, "BDD_CHART_REPOSITORY_URL"))
+			}
+		})
+
+		_ = T.AddValuesAppTests(testAppName)
+		_ = T.DeleteAppTests(testAppName)
+	})
+}
+
+// AddValuesAppTests checks that jx add app in batch mode deploys the app with the answers, values file and set values
+// it is given, keeping the secret answers out of the dev environment repository and the values stashed on the App. When
+// the cluster stores its secrets in vault it also checks that the secret answers are referenced from those values and
+// deployed in the Secret of the app.
+func (t *AppTestOptions) AddValuesAppTests(testAppName string) bool {
+	return Describe("Adding an app with values and secrets", func() {
+		var values *appValues
+		// installedValues are the values jx wrote to the dev environment repository or stashed on the App
+		var installedValues []byte
+
+		Context("by running jx add app "+testAppName+" in batch mode", func() {
+			It("should inject the values and keep the secrets out of the environment repository", func() {
+				values = t.newAppValues(testAppName)
+				utils.LogInfof("adding %s answering %s\n", testAppName, strings.Join(values.secretProperties(), ", "))
+
+				args := []string{"add", "app", testAppName, "--repository", repositoryURL(testAppName), "--batch-mode", "--values", values.valuesFile}
+				args = append(args, values.setArgs...)
+				out := t.runAppCommand(args, values.answers.Env(), func(diff *promotion.RequirementsDiff) error {
+					return diff.ExpectAdded(testAppName, "", repositoryURL(testAppName))
+				})
+				t.expectApp(testAppName, "", values.deployedValues())
+
+				if t.GitOpsEnabled() {
+					By("checking the secret answers are not in the pull request on the dev environment repository", func() {
+						files, err := t.DevEnvironmentPullRequestFiles(out)
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(answers.ExpectNoSecrets(files, values.secrets)).Should(Succeed())
+
+						path := fmt.Sprintf("env/%s/%s", testAppName, helm.ValuesFileName)
+						Expect(files).Should(HaveKey(path))
+						installedValues = []byte(files[path])
+						Expect(answers.ExpectValues(installedValues, values.environmentValues(), values.secrets)).Should(Succeed(), "values of %s in %s", testAppName, path)
+					})
+				} else {
+					By(fmt.Sprintf("checking the secret answers are not in the values stashed on the App of %s", testAppName), func() {
+						var err error
+						installedValues, err = stashedValues(testAppName)
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(answers.ExpectValues(installedValues, values.answeredValues(), values.secrets)).Should(Succeed(), "values stashed on the App of %s", testAppName)
+					})
+				}
+
+				By(fmt.Sprintf("checking that the Deployment and ConfigMap of %s are deployed with the values", testAppName), func() {
+					Expect(verifyValuesAppInstalled(testAppName, values.deployedValues(), nil)).Should(Succeed())
+				})
+			})
+
+			It("should store the secrets in vault and deploy them in the Secret", func() {
+				// jx add app only stores secret answers in vault, dropping them with a warning otherwise
+				if !usesVault() {
+					Skip(fmt.Sprintf("Skipping the secret assertions for %s as the cluster does not store its secrets in vault", testAppName))
+				}
+				if values == nil || installedValues == nil {
+					Skip(fmt.Sprintf("Skipping the secret assertions for %s as it was not added", testAppName))
+				}
+
+				By(fmt.Sprintf("checking the secret answers are referenced from the values of %s", testAppName), func() {
+					Expect(answers.ExpectSecretReferences(installedValues, values.secrets)).Should(Succeed())
+				})
+				By(fmt.Sprintf("checking that the Secret of %s has the secret answers", testAppName), func() {
+					Expect(verifyValuesAppInstalled(testAppName, values.deployedValues(), values.secrets)).Should(Succeed())
+				})
+			})
+		})
+	})
+}
+
+// appValues are the answers, values file and set values an app with a values.schema.json is added with
+type appValues struct {
+	answers    answers.Answers
+	valuesFile string
+	values     map[string]interface{}
+	setArgs    []string
+	set        map[string]string
+	// secrets are the answers generated for the secret properties of the schema, by property path
+	secrets map[string]string
+}
+
+// newAppValues loads the answers and values file of a local chart, generating a unique answer to each secret property
+// of its values.schema.json so that it can be looked for wherever the values end up
+func (t *AppTestOptions) newAppValues(app string) *appValues {
+	answersFile := filepath.Join(valuesAnswersDir, app+".yaml")
+	a, err := answers.Load(answersFile)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	schema, err := ioutil.ReadFile(filepath.Join(localChartFixtureDir, app, "values.schema.json"))
+	Expect(err).ShouldNot(HaveOccurred())
+	properties, err := answers.SecretProperties(schema)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(properties).ShouldNot(BeEmpty(), "the values.schema.json of %s has no secret properties", app)
+	secretAnswers := map[string]string{}
+	for _, property := range properties {
+		secretAnswers[property] = "bdd-" + rand.String(16)
+		a.Set(property, secretAnswers[property])
+	}
+
+	// jx is run in the work dir, so it needs the absolute path of the values file
+	valuesFile, err := filepath.Abs(filepath.Join(valuesFilesDir, app+".yaml"))
+	Expect(err).ShouldNot(HaveOccurred())
+	data, err := ioutil.ReadFile(valuesFile)
+	Expect(err).ShouldNot(HaveOccurred())
+	values := map[string]interface{}{}
+	Expect(yaml.Unmarshal(data, &values)).Should(Succeed(), "parsing %s", valuesFile)
+
+	answer := &appValues{
+		answers:    a,
+		valuesFile: valuesFile,
+		values:     values,
+		secrets:    secretAnswers,
+		set:        map[string]string{},
+	}
+	// values cannot be set when using GitOps for the dev environment
+	if !t.GitOpsEnabled() {
+		for _, k := range sortedKeys(valuesAppSetValues) {
+			answer.setArgs = append(answer.setArgs, "--set", fmt.Sprintf("%s=%s", k, valuesAppSetValues[k]))
+			answer.set[k] = valuesAppSetValues[k]
+		}
+	}
+	return answer
+}
+
+// secretProperties returns the paths of the secret properties of the schema
+func (v *appValues) secretProperties() []string {
+	return sortedKeys(v.secrets)
+}
+
+// answeredValues returns the values answering the questions of the schema, other than secrets
+func (v *appValues) answeredValues() map[string]string {
+	answer := map[string]string{}
+	addValues(answer, v.answers)
+	for property := range v.secrets {
+		delete(answer, property)
+	}
+	return answer
+}
+
+// environmentValues returns the values expected in the values.yaml jx writes to the dev environment repository, those
+// of the values file followed by the answers
+func (v *appValues) environmentValues() map[string]string {
+	answer := map[string]string{}
+	addValues(answer, v.values)
+	for k, value := range v.answeredValues() {
+		answer[k] = value
+	}
+	return answer
+}
+
+// deployedValues returns the values the app is expected to be deployed with, the set values taking precedence over
+// the answers and the answers over the values file
+func (v *appValues) deployedValues() map[string]string {
+	answer := v.environmentValues()
+	for k, value := range v.set {
+		answer[k] = value
+	}
+	return answer
+}
+
+// addValues adds the top level values which are not objects as strings
+func addValues(m map[string]string, values map[string]interface{}) {
+	for k, value := range values {
+		if _, ok := value.(map[string]interface{}); ok {
+			continue
+		}
+		m[k] = fmt.Sprintf("%v", value)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// usesVault returns true if the secrets of the cluster are stored in vault, where jx stores the secret answers to the
+// questions of the values.schema.json of an app
+func usesVault() bool {
+	return cmd.NewFactory().SecretsLocation() == secrets.VaultLocationKind
+}
+
+// stashedValues returns the values jx stashed on the App of an app installed without GitOps
+func stashedValues(app string) ([]byte, error) {
+	jxClient, ns, err := cmd.NewFactory().CreateJXClient()
+	if err != nil {
+		return nil, err
+	}
+	selector := helm.LabelAppName + "=" + app
+	list, err := jxClient.JenkinsV1().Apps(ns).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing Apps labelled %s in namespace %s", selector, ns)
+	}
+	if len(list.Items) != 1 {
+		return nil, errors.Errorf("expected an App labelled %s in namespace %s but found %d", selector, ns, len(list.Items))
+	}
+	values, err := base64.StdEncoding.DecodeString(list.Items[0].Annotations[valuesAnnotation])
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding the %s annotation of App %s", valuesAnnotation, list.Items[0].Name)
+	}
+	return values, nil
+}
+
+// verifyValuesAppInstalled checks that the ConfigMap of a local chart has the given values, that its Deployment has
+// the given greeting and number of replicas, and that its Secret has the given secrets. They are found in the dev
+// namespace by their app label.
+func verifyValuesAppInstalled(app string, values map[string]string, secretValues map[string]string) error {
+	kubeClient, ns, err := cmd.NewFactory().CreateKubeClient()
+	if err != nil {
+		return err
+	}
+	selector := metav1.ListOptions{LabelSelector: "app=" + app}
+	return helpers.RetryExponentialBackoff(helpers.TimeoutDeploymentRollout, func() error {
+		configMaps, _, err := localChartConfigMaps(app)
+		if err != nil {
+			return err
+		}
+		deployments, err := kubeClient.AppsV1().Deployments(ns).List(selector)
+		if err != nil {
+			return errors.Wrapf(err, "listing Deployments labelled app=%s in namespace %s", app, ns)
+		}
+		secretList, err := kubeClient.CoreV1().Secrets(ns).List(selector)
+		if err != nil {
+			return errors.Wrapf(err, "listing Secrets labelled app=%s in namespace %s", app, ns)
+		}
+		if len(configMaps) != 1 || len(deployments.Items) != 1 || len(secretList.Items) != 1 {
+			err = fmt.Errorf("expected a ConfigMap, Deployment and Secret labelled app=%s but found %d, %d and %d", app, len(configMaps), len(deployments.Items), len(secretList.Items))
+			utils.LogInfof("WARNING: %s\n", err)
+			return err
+		}
+
+		for k, v := range values {
+			if actual := configMaps[0].Data[k]; actual != v {
+				return backoff.Permanent(fmt.Errorf("ConfigMap %s of %s has value %s=%s but expected %s", configMaps[0].Name, app, k, actual, v))
+			}
+		}
+		deployment := deployments.Items[0]
+		if replicas, ok := values["replicas"]; ok && deployment.Spec.Replicas != nil && strconv.Itoa(int(*deployment.Spec.Replicas)) != replicas {
+			return backoff.Permanent(fmt.Errorf("Deployment %s of %s has %d replicas but expected %s", deployment.Name, app, *deployment.Spec.Replicas, replicas))
+		}
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			for _, env := range container.Env {
+				if env.Name == "GREETING" && env.Value != values["greeting"] {
+					return backoff.Permanent(fmt.Errorf("Deployment %s of %s has greeting %s but expected %s", deployment.Name, app, env.Value, values["greeting"]))
+				}
+			}
+		}
+		secret := secretList.Items[0]
+		for k, v := range secretValues {
+			if actual := string(secret.Data[k]); actual != v {
+				return backoff.Permanent(fmt.Errorf("Secret %s of %s has the wrong value for %s", secret.Name, app, k))
+			}
+		}
+		return nil
+	})
+}
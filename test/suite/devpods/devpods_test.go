@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/jenkins-x/bdd-jx/test/helpers"
+	"github.com/jenkins-x/bdd-jx/test/suite/devpods"
 
 	. "github.com/onsi/ginkgo"
 )
@@ -12,6 +13,9 @@ func TestSuite(t *testing.T) {
 	helpers.RunWithReporters(t, "devpods")
 }
 
-var _ = BeforeSuite(helpers.BeforeSuiteCallback)
+var _ = BeforeSuite(func() {
+	helpers.BeforeSuiteCallback()
+	devpods.DiscoverDevPods()
+})
 
-var _ = SynchronizedAfterSuite(func() {}, helpers.SynchronizedAfterSuiteCallback)
+var _ = SynchronizedAfterSuite(devpods.WaitForDevPods, helpers.SynchronizedAfterSuiteCallback)
@@ -1,190 +1,388 @@
 package devpods
 
 import (
+	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jenkins-x/bdd-jx/test/helpers"
 	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/pods"
+	"github.com/jenkins-x/bdd-jx/test/utils/podtemplates"
 	"github.com/jenkins-x/bdd-jx/test/utils/runner"
 	cmd "github.com/jenkins-x/jx/v2/pkg/cmd/clients"
+	"github.com/jenkins-x/jx/v2/pkg/kube"
+	"github.com/onsi/ginkgo/config"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+)
+
+// devPodSuffix is the suffix of the names of the devpods the tests create
+const devPodSuffix = "devpod"
+
+// the steps of testing a devpod, each of which has a spec
+const (
+	stepCreate         = "create"
+	stepExists         = "exists"
+	stepToolchain      = "toolchain"
+	stepWorkspace      = "workspace"
+	stepDelete         = "delete"
+	stepNoLongerExists = "no longer exists"
+)
+
+var devPodSteps = []string{stepCreate, stepExists, stepToolchain, stepWorkspace, stepDelete, stepNoLongerExists}
+
+var (
+	// timeoutDevPod is the timeout of jx devpod commands and of waiting for a devpod to be ready or deleted, unless its
+	// label has its own timeout in BDD_DEVPOD_TIMEOUTS
+	timeoutDevPod = utils.GetTimeoutFromEnv("BDD_TIMEOUT_DEVPOD", 15)
+
+	// devPodSelection selects the pod templates to create devpods with, and devPodSelectionErr is the error parsing it,
+	// which fails the suite in DiscoverDevPods
+	devPodSelection, devPodSelectionErr = podtemplates.ParseSelection(
+		utils.GetEnv("BDD_DEVPOD_INCLUDE", ""),
+		utils.GetEnv("BDD_DEVPOD_EXCLUDE", strings.Join(podtemplates.DefaultExclude, ",")),
+		utils.GetEnv("BDD_DEVPOD_MAX_CONCURRENCY", ""),
+		utils.GetEnv("BDD_DEVPOD_TIMEOUTS", ""),
+		timeoutDevPod)
+
+	// devPodLabels are the labels of the pod templates in the dev namespace which are selected, each of which has specs.
+	// They are discovered when the tree of the suite is built, and devPodLabelsErr is the error discovering them, which
+	// fails the suite in DiscoverDevPods.
+	devPodLabels, devPodNamespace, devPodLabelsErr = discoverLabels()
+
+	// devPodRuns are the tests of the devpods with the selected labels, which DiscoverDevPods sets up and
+	// startDevPodRuns starts
+	devPodRuns        = map[string]*devPodRun{}
+	devPodRunsStarted sync.Once
+	devPodRunsDone    sync.WaitGroup
+
+	// devPodSlots limits how many devpods exist at the same time to the maximum concurrency of the selection. Every
+	// test creating a devpod takes a slot until its devpod is deleted.
+	devPodSlots chan struct{}
 )
 
 type TestDevPods struct {
 	*runner.JxRunner
 	kubeClient kubernetes.Interface
+	kubeConfig *rest.Config
+	// namespace is the dev namespace of the current team, which devpods are created in
+	namespace string
+	timeout   time.Duration
 }
 
 func newTestDevPods(factory cmd.Factory) (*TestDevPods, error) {
-
-	timeOut := utils.GetTimeoutFromEnv("BDD_TIMEOUT_DEVPOD", 15)
-
-	client, _, err := factory.CreateKubeClient()
+	client, ns, err := factory.CreateKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	devNs, _, err := kube.GetDevNamespace(client, ns)
+	if err != nil {
+		return nil, err
+	}
+	config, err := factory.CreateKubeConfig()
 	if err != nil {
 		return nil, err
 	}
 
 	return &TestDevPods{
-		JxRunner:   runner.New(helpers.WorkDir, &timeOut, 0),
+		JxRunner:   runner.New(helpers.WorkDir, &timeoutDevPod, 0),
 		kubeClient: client,
+		kubeConfig: config,
+		namespace:  devNs,
+		timeout:    timeoutDevPod,
 	}, nil
 }
 
-func getAllAvailableDevpods() []string {
-	factory := cmd.NewFactory()
-	kc, _, err := factory.CreateKubeClient()
+// withTimeout returns a copy of the test whose jx commands and waits use the timeout
+func (test *TestDevPods) withTimeout(timeout time.Duration) *TestDevPods {
+	answer := *test
+	answer.JxRunner = runner.New(helpers.WorkDir, &timeout, 0)
+	answer.timeout = timeout
+	return &answer
+}
+
+// discoverLabels returns the selected labels of the pod templates in the dev namespace of the current team, and the
+// namespace
+func discoverLabels() ([]string, string, error) {
+	if devPodSelectionErr != nil {
+		return nil, "", nil
+	}
+	test, err := newTestDevPods(cmd.NewFactory())
 	if err != nil {
-		utils.LogInfof("Error creating kube client %s", err)
+		return nil, "", err
 	}
-
-	configMapInterface := kc.CoreV1().ConfigMaps("jx")
-
-	list, err := configMapInterface.List(metav1.ListOptions{
-		LabelSelector: "jenkins.io/kind=podTemplate",
-	})
+	discovered, err := podtemplates.Discover(test.kubeClient, test.namespace)
 	if err != nil {
-		utils.LogInfof("Error querying config map %s", err)
+		return nil, test.namespace, err
 	}
+	labels := devPodSelection.Select(discovered)
+	for _, label := range devPodSelection.Labels() {
+		if !contains(discovered, label) {
+			utils.LogInfof("WARNING: not testing the devpod with label %s as there is no pod template for it in namespace %s\n", label, test.namespace)
+		}
+	}
+	return labels, test.namespace, nil
+}
 
-	devPods := make([]string, 0)
-	for _, cm := range list.Items {
+// DiscoverDevPods sets up the tests of the devpods with the selected labels of the pod templates discovered when the
+// tree of the suite was built. It fails if the selection is invalid, the pod templates could not be discovered, or
+// none of them is selected, so it is called from the BeforeSuite of the suite.
+func DiscoverDevPods() {
+	Expect(devPodSelectionErr).NotTo(HaveOccurred())
+	Expect(devPodLabelsErr).NotTo(HaveOccurred())
+	Expect(config.GinkgoConfig.ParallelTotal).Should(Equal(1), "the devpods suite tests up to BDD_DEVPOD_MAX_CONCURRENCY devpods at a time itself, so it must not run on several nodes")
+
+	devPodSlots = make(chan struct{}, devPodSelection.MaxConcurrency)
+	for _, label := range devPodLabels {
+		devPodRuns[label] = newDevPodRun(label, devPodSelection.TimeoutFor(label))
+	}
+	Expect(devPodRuns).ShouldNot(BeEmpty(), "none of the pod templates in namespace %s is selected", devPodNamespace)
+}
 
-		devPod := strings.TrimPrefix(cm.Name, "jenkins-x-pod-template-")
+// acquireDevPodSlot waits until fewer devpods than the maximum concurrency exist, returning a function releasing the
+// slot it takes
+func acquireDevPodSlot() func() {
+	devPodSlots <- struct{}{}
+	return func() {
+		<-devPodSlots
+	}
+}
 
-		if isDevPod(devPod) && !isExcluded(devPod) {
-			utils.LogInfof("Adding devpod %s \n", devPod)
-			devPods = append(devPods, devPod)
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
 		}
 	}
-	utils.LogInfof("Added all devPods %s", devPods)
-	//return []string{"go", "maven"}
-	return devPods
+	return false
 }
 
-func isDevPod(label string) bool {
-	//these options currently error
-	return label != "terraform" && label != "packer" && label != "jx-base" && label != "promote" && label != "swift" && label != "ruby"
+// WaitForDevPods waits for the tests of the devpods which were started to finish, so that their devpods are deleted
+// even if their specs did not run
+func WaitForDevPods() {
+	devPodRunsDone.Wait()
 }
 
-func isExcluded(label string) bool {
-	//machine learning ones take extremely long time to provision
-	return strings.Contains(label, "machine-learning")
+// devPodRun is the test of the devpod with a label. It runs in the background so that several devpods can be tested
+// at the same time, and records the error of each step, which the spec of the step asserts, as Gomega assertions
+// must be made on the goroutine of the spec.
+type devPodRun struct {
+	label   string
+	timeout time.Duration
+	steps   map[string]*devPodStep
 }
 
-func (test *TestDevPods) createDevPod(label string) {
-	args := []string{"create", "devpod", "-b", "-l", label, "--import=false", "--suffix=devpod"}
-	test.Run(args...)
+// devPodStep is a step of the test of a devpod, whose error is set before done is closed
+type devPodStep struct {
+	done chan struct{}
+	err  error
 }
 
-func (test *TestDevPods) getPodName(suffix string) string {
-	listOptions := metav1.ListOptions{}
+// skippedStep is the error of the steps after a step which failed
+type skippedStep struct {
+	label  string
+	failed string
+}
 
-	pods, err := test.kubeClient.CoreV1().Pods("jx").List(listOptions)
+func (s skippedStep) Error() string {
+	return fmt.Sprintf("skipped as the devpod with label %s failed to %s", s.label, s.failed)
+}
 
-	Expect(err).NotTo(HaveOccurred())
+func newDevPodRun(label string, timeout time.Duration) *devPodRun {
+	run := &devPodRun{label: label, timeout: timeout, steps: map[string]*devPodStep{}}
+	for _, step := range devPodSteps {
+		run.steps[step] = &devPodStep{done: make(chan struct{})}
+	}
+	return run
+}
 
-	var name string
+// startDevPodRuns starts the tests of all the devpods the first time it is called, each of which takes a devpod slot
+// while it runs
+func startDevPodRuns(test *TestDevPods) {
+	devPodRunsStarted.Do(func() {
+		var labels []string
+		for label := range devPodRuns {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		devPodRunsDone.Add(len(labels))
+		go func() {
+			for _, label := range labels {
+				release := acquireDevPodSlot()
+				go func(run *devPodRun) {
+					defer func() {
+						release()
+						devPodRunsDone.Done()
+					}()
+					run.run(test.withTimeout(run.timeout))
+				}(devPodRuns[label])
+			}
+		}()
+	})
+}
 
-	for _, pod := range pods.Items {
-		if strings.HasSuffix(pod.Name, suffix) {
-			name = pod.Name
+// run creates the devpod, checks it and deletes it, recording the error of each step. The devpod is deleted even if
+// one of its checks fails, and the steps after a failed create or delete are skipped.
+func (run *devPodRun) run(test *TestDevPods) {
+	defer func() {
+		if r := recover(); r != nil {
+			run.finishRemaining(errors.Errorf("the test of the devpod with label %s panicked: %v", run.label, r))
 		}
+	}()
+	utils.LogInfof("testing the devpod with label %s with a timeout of %s\n", run.label, run.timeout)
+
+	name, err := test.createDevPod(run.label)
+	run.finish(stepCreate, err)
+	if err != nil {
+		run.finishRemaining(skippedStep{label: run.label, failed: stepCreate})
+		return
 	}
-	Expect(name).ShouldNot(Equal(""))
-	return name
+	run.finish(stepExists, test.checkDevPodExists(name))
+	run.finish(stepToolchain, test.checkDevPodToolchain(run.label, name))
+	run.finish(stepWorkspace, test.checkDevPodWorkspace(name))
+	err = test.deleteDevPod(name)
+	run.finish(stepDelete, err)
+	if err != nil {
+		run.finishRemaining(skippedStep{label: run.label, failed: stepDelete})
+		return
+	}
+	run.finish(stepNoLongerExists, test.checkDevPodNoLongerExists(name))
 }
 
-func (test *TestDevPods) checkDevPodExists(label string) {
-	name := label + "-devpod"
-	utils.LogInfof("Creating dev pod %s", label)
-	args := []string{"get", "devpod"}
-	devPods, err := test.RunWithOutput(args...)
-	utils.ExpectNoError(err)
+func (run *devPodRun) finish(step string, err error) {
+	run.steps[step].err = err
+	close(run.steps[step].done)
+}
 
-	Expect(devPods).Should(ContainSubstring(name))
+func (run *devPodRun) finishRemaining(err error) {
+	for _, step := range devPodSteps {
+		select {
+		case <-run.steps[step].done:
+		default:
+			run.finish(step, err)
+		}
+	}
 }
 
-func (test *TestDevPods) checkDevPodTerminating() {
-	//jx get devpod
-	args := []string{"get", "devpod"}
+// expect waits for the step to finish and expects it to have succeeded, skipping the spec if the step was skipped.
+// Every step has a timeout, so the wait is bounded.
+func (run *devPodRun) expect(step string) {
+	<-run.steps[step].done
+	err := run.steps[step].err
+	if skipped, ok := err.(skippedStep); ok {
+		Skip(skipped.Error())
+	}
+	Expect(err).NotTo(HaveOccurred())
+}
+
+// devPodSelector returns the label selector of the devpods created from the pod template with the given label
+func devPodSelector(label string) string {
+	return fmt.Sprintf("%s=%s,%s", kube.LabelPodTemplate, label, kube.LabelDevPodName)
+}
 
-	var b = false
-	var i = 0
+// createDevPod creates a devpod with the label, returning the name of its pod once it is ready
+func (test *TestDevPods) createDevPod(label string) (string, error) {
+	args := []string{"create", "devpod", "-b", "-l", label, "--import=false", "--suffix=" + devPodSuffix}
+	if _, err := test.RunInBackground(args...); err != nil {
+		return "", err
+	}
 
-	//wait up to two mintutes to terminate
-	for b == false && i < 120 {
-		devPods, err := test.RunWithOutput(args...)
-		utils.ExpectNoError(err)
-		b = !strings.Contains(devPods, "Terminating")
-		time.Sleep(1 * time.Second)
-		i++
+	utils.LogInfof("waiting for the %s devpod to be ready in namespace %s\n", label, test.namespace)
+	pod, err := pods.WaitForReady(test.kubeClient, test.namespace, devPodSelector(label), "-"+devPodSuffix, test.timeout)
+	if err != nil {
+		return "", err
 	}
+	utils.LogInfof("devpod %s is ready\n", pod.Name)
+	return pod.Name, nil
 }
 
-func (test *TestDevPods) checkDevPodNoLongerExists(label string) {
-	name := label + "-devpod"
-	//jx get devpod
-	utils.LogInfof("checking pod no longer exists %s", label)
-	args := []string{"get", "devpod"}
-	devPods, err := test.RunWithOutput(args...)
-	utils.ExpectNoError(err)
-	Expect(devPods).ShouldNot(ContainSubstring(name))
+func (test *TestDevPods) checkDevPodExists(name string) error {
+	utils.LogInfof("checking devpod %s exists\n", name)
+	devPods, err := test.RunInBackground("get", "devpod")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(devPods, name) {
+		return errors.Errorf("devpod %s is not listed by jx get devpod:\n%s", name, devPods)
+	}
+	return nil
 }
 
-func (test *TestDevPods) deleteDevPods(label string) {
-	name := label + "-devpod"
-	utils.LogInfof("deleting pod %s", label)
-	//jx delete devpod
-	podName := test.getPodName(name)
-	args := []string{"delete", "devpod", podName, "-b"}
-	test.Run(args...)
+func (test *TestDevPods) checkDevPodNoLongerExists(name string) error {
+	utils.LogInfof("checking devpod %s no longer exists\n", name)
+	devPods, err := test.RunInBackground("get", "devpod")
+	if err != nil {
+		return err
+	}
+	if strings.Contains(devPods, name) {
+		return errors.Errorf("devpod %s is still listed by jx get devpod:\n%s", name, devPods)
+	}
+	return nil
 }
 
-var _ = Describe("E2E tests for all Dev pods \n", func() {
+// deleteDevPod deletes the devpod with the given name, waiting for its pod to be deleted
+func (test *TestDevPods) deleteDevPod(name string) error {
+	utils.LogInfof("deleting devpod %s\n", name)
+	if _, err := test.RunInBackground("delete", "devpod", name, "-b"); err != nil {
+		return err
+	}
+	return pods.WaitForDeleted(test.kubeClient, test.namespace, name, test.timeout)
+}
 
-	// will get all available devpods
-	devPods := getAllAvailableDevpods()
+var _ = Describe("E2E tests for all Dev pods \n", func() {
+	BeforeEach(func() {
+		test, err := newTestDevPods(cmd.NewFactory())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(test).NotTo(BeNil())
+		startDevPodRuns(test)
+	})
 
-	// and create a set of tests for each of them
-	for _, label := range devPods {
-		var _ = Describe("Given I have created a devpod with label "+label, func() {
-			// copy the label, otherwise by the time the closure executes it's value will of changed
-			devpod := string([]byte(label))
-			var test *TestDevPods
+	// and create a set of tests for each of the selected labels
+	for _, label := range devPodLabels {
+		label := label
+		Describe("Given I have created a devpod with label "+label, func() {
+			var run *devPodRun
 			BeforeEach(func() {
-				var err error
-				test, err = newTestDevPods(cmd.NewFactory())
-
-				Expect(err).NotTo(HaveOccurred())
-				Expect(test).NotTo(BeNil())
+				run = devPodRuns[label]
+				if run == nil {
+					Skip(fmt.Sprintf("Skipping the devpod with label %s as there is no pod template for it", label))
+				}
 			})
 			Context("when running jx create devpod -l "+label, func() {
 				It("a "+label+" dev pod is created\n", func() {
-					test.createDevPod(devpod)
+					run.expect(stepCreate)
 				})
 			})
 			Context("when checking if a devpod exists", func() {
 				It("the dev pod is available ", func() {
-					test.checkDevPodExists(devpod)
+					run.expect(stepExists)
+				})
+			})
+			Context("when running commands in the devpod", func() {
+				It("the toolchain, jx and git are available ", func() {
+					run.expect(stepToolchain)
+				})
+				It("the workspace is mounted and writable ", func() {
+					run.expect(stepWorkspace)
 				})
 			})
 			Context("when running jx delete devpod ", func() {
 				It("the dev pod is delete ", func() {
-					test.deleteDevPods(devpod)
-					test.checkDevPodTerminating()
+					run.expect(stepDelete)
 				})
-
 			})
 			Context("when checking if the devpod exists ", func() {
 				It("the devpod is no longer available ", func() {
-					test.checkDevPodNoLongerExists(devpod)
+					run.expect(stepNoLongerExists)
 				})
 			})
 		})
 	}
-
 })
@@ -0,0 +1,144 @@
+package devpods
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jenkins-x/bdd-jx/test/helpers"
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/fixtures"
+	"github.com/jenkins-x/bdd-jx/test/utils/pods"
+	"github.com/jenkins-x/bdd-jx/test/utils/runner"
+	cmd "github.com/jenkins-x/jx/v2/pkg/cmd/clients"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/rand"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	// syncDevPodSuffix is the suffix of the name of the devpod the sync test creates
+	syncDevPodSuffix = "sync"
+	// syncFixtureFile is the file of the sync fixture the test changes, whose message the fixture prints
+	syncFixtureFile = "main.go"
+	// syncFixtureMessage is the message the sync fixture prints before it is changed
+	syncFixtureMessage = "hello from the devpod sync fixture"
+)
+
+var (
+	// syncLabel is the label of the devpod the sync fixture is synchronised into and built in
+	syncLabel = utils.GetEnv("BDD_DEVPOD_SYNC_LABEL", "go")
+	// syncFixtureDir is the fixture project synchronised into the devpod
+	syncFixtureDir = filepath.Join("testdata", "golang-sync")
+	// syncBuild builds and runs the sync fixture in its directory in the devpod
+	syncBuild = "go build -o /tmp/bdd-devpod-sync . && /tmp/bdd-devpod-sync"
+	// timeoutDevPodSync is the timeout waiting for a local change to be synchronised into the devpod
+	timeoutDevPodSync = utils.GetTimeoutFromEnv("BDD_TIMEOUT_DEVPOD_SYNC", 5)
+)
+
+var _ = Describe("E2E tests for synchronising a project into a devpod\n", func() {
+	var test *TestDevPods
+	BeforeEach(func() {
+		var err error
+		test, err = newTestDevPods(cmd.NewFactory())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(test).NotTo(BeNil())
+
+		if !contains(devPodLabels, syncLabel) {
+			Skip(fmt.Sprintf("Skipping the devpod sync test as the %s pod template is not selected or there is none", syncLabel))
+		}
+		test = test.withTimeout(devPodSelection.TimeoutFor(syncLabel))
+	})
+
+	Context("when running jx create devpod -l "+syncLabel+" --sync in a project", func() {
+		It("local changes are synchronised into the devpod and build there\n", func() {
+			project := test.copySyncFixture()
+			defer os.RemoveAll(project)
+
+			stopSync := test.startSync(project)
+			defer stopSync()
+
+			// the sync devpod counts towards the maximum concurrency like the devpods of the other specs
+			release := acquireDevPodSlot()
+			defer release()
+
+			podName := test.createSyncDevPod(project, syncLabel)
+			defer test.deleteSyncDevPod(podName)
+
+			message := "hello from bdd " + rand.String(8)
+			By(fmt.Sprintf("changing the message of %s locally to %s", syncFixtureFile, message), func() {
+				path := filepath.Join(project, syncFixtureFile)
+				data, err := ioutil.ReadFile(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).Should(ContainSubstring(syncFixtureMessage))
+				changed := strings.Replace(string(data), syncFixtureMessage, message, 1)
+				Expect(ioutil.WriteFile(path, []byte(changed), 0644)).Should(Succeed())
+			})
+
+			By(fmt.Sprintf("waiting for the change to %s to be synchronised into devpod %s", syncFixtureFile, podName), func() {
+				remote := devPodWorkspace + "/" + syncFixtureFile
+				err := helpers.RetryExponentialBackoff(timeoutDevPodSync, func() error {
+					stdout, _, err := pods.Exec(test.kubeConfig, test.kubeClient, test.namespace, podName, devPodContainer, "cat", remote)
+					if err != nil {
+						utils.LogInfof("WARNING: %s\n", err)
+						return err
+					}
+					if !strings.Contains(stdout, message) {
+						return errors.Errorf("%s in devpod %s does not have the change yet", remote, podName)
+					}
+					return nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			By(fmt.Sprintf("building and running the project in devpod %s", podName), func() {
+				out := test.exec(podName, "sh", "-c", fmt.Sprintf("cd %s && %s", devPodWorkspace, syncBuild))
+				Expect(out).Should(Equal(message))
+			})
+		})
+	})
+})
+
+// copySyncFixture copies the sync fixture to a new directory in the work dir, returning it
+func (test *TestDevPods) copySyncFixture() string {
+	name := helpers.TempDirPrefix + "devpod-sync-" + strconv.FormatInt(GinkgoRandomSeed(), 10)
+	project := filepath.Join(helpers.WorkDir, name)
+	Expect(os.RemoveAll(project)).Should(Succeed())
+	Expect(fixtures.Copy(syncFixtureDir, project, fixtures.Values{ApplicationName: name})).Should(Succeed())
+	utils.LogInfof("copied %s to %s\n", syncFixtureDir, project)
+	return project
+}
+
+// startSync starts jx sync in the project, which runs the ksync watch daemon synchronising the directories of
+// devpods created with --sync, returning a function stopping it
+func (test *TestDevPods) startSync(project string) func() {
+	session, err := runner.New(project, &test.timeout, 0).Start("sync", "--daemon", "-b")
+	Expect(err).NotTo(HaveOccurred())
+	return func() {
+		session.Terminate().Wait(test.timeout)
+	}
+}
+
+// createSyncDevPod creates a devpod with the label synchronising the project into its workspace, returning its name
+// once it is ready. The working directory is set so that it does not depend on whether the project is in the GOPATH.
+func (test *TestDevPods) createSyncDevPod(project string, label string) string {
+	args := []string{"create", "devpod", "-b", "-l", label, "--sync", "--import=true", "--suffix=" + syncDevPodSuffix, "-w", devPodWorkspace}
+	runner.New(project, &test.timeout, 0).Run(args...)
+
+	pod, err := pods.WaitForReady(test.kubeClient, test.namespace, devPodSelector(label), "-"+syncDevPodSuffix, test.timeout)
+	Expect(err).NotTo(HaveOccurred())
+	utils.LogInfof("devpod %s synchronising %s is ready\n", pod.Name, project)
+	return pod.Name
+}
+
+// deleteSyncDevPod deletes the devpod, waiting for its pod to be deleted. jx removes the ksync spec of a deleted devpod
+// the next time it creates one with --sync.
+func (test *TestDevPods) deleteSyncDevPod(podName string) {
+	test.Run("delete", "devpod", podName, "-b")
+	Expect(pods.WaitForDeleted(test.kubeClient, test.namespace, podName, test.timeout)).Should(Succeed())
+}
@@ -0,0 +1,10 @@
+package main
+
+import "fmt"
+
+// message is changed locally by the devpod sync test, which expects the devpod to print the change once synchronised
+const message = "hello from the devpod sync fixture"
+
+func main() {
+	fmt.Println(message)
+}
@@ -0,0 +1,164 @@
+package devpods
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/pods"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+
+	. "github.com/onsi/gomega"
+)
+
+const (
+	// devPodContainer is the container of a devpod which has the toolchain of its pod template
+	devPodContainer = "devpod"
+	// devPodWorkspace is where the workspace volume of a devpod is mounted
+	devPodWorkspace = "/workspace"
+)
+
+// toolchainCheck is a command run in a devpod whose output must match a regular expression
+type toolchainCheck struct {
+	command []string
+	expect  *regexp.Regexp
+}
+
+func check(expect string, command ...string) toolchainCheck {
+	return toolchainCheck{command: command, expect: regexp.MustCompile(expect)}
+}
+
+var (
+	// toolchains are the checks of the toolchain of the devpods created from the pod template with each label. A label
+	// without an entry uses the entry of the longest label it starts with, so maven-java11 uses maven.
+	toolchains = map[string][]toolchainCheck{
+		"go":     {check(`go version go\d+\.\d+`, "go", "version")},
+		"gradle": {check(`Gradle \d+\.\d+`, "gradle", "--version")},
+		"maven":  {check(`Apache Maven \d+\.\d+\.\d+`, "mvn", "-v")},
+		"nodejs": {check(`v\d+\.\d+\.\d+`, "node", "--version"), check(`\d+\.\d+\.\d+`, "npm", "--version")},
+		"python": {check(`Python \d+\.\d+`, "python", "--version")},
+		"rust":   {check(`cargo \d+\.\d+`, "cargo", "--version")},
+		"scala":  {check(`sbt`, "sh", "-c", "command -v sbt")},
+	}
+
+	// commonChecks are the checks of every devpod: jx and git must be available, and git must be configured with the
+	// credential helper jx create devpod sets up
+	commonChecks = []toolchainCheck{
+		check(`\d+\.\d+\.\d+`, "jx", "version", "--short"),
+		check(`git version \d+\.\d+`, "git", "--version"),
+		check(`^store$`, "git", "config", "--global", "--get", "credential.helper"),
+	}
+)
+
+// toolchainChecks returns the checks of the toolchain of the devpods with the given label, and false if there are none
+func toolchainChecks(label string) ([]toolchainCheck, bool) {
+	if checks, ok := toolchains[label]; ok {
+		return checks, true
+	}
+	longest := ""
+	for l := range toolchains {
+		if strings.HasPrefix(label, l) && len(l) > len(longest) {
+			longest = l
+		}
+	}
+	if longest == "" {
+		return nil, false
+	}
+	return toolchains[longest], true
+}
+
+// exec runs a command in the devpod container of the devpod, expecting it to succeed and returning its output
+func (test *TestDevPods) exec(pod string, command ...string) string {
+	out, err := test.execWithTimeout(pod, command...)
+	Expect(err).NotTo(HaveOccurred())
+	return out
+}
+
+// execWithTimeout runs a command in the devpod container of the devpod, returning its output and an error if it fails
+// or does not complete within the timeout of the test
+func (test *TestDevPods) execWithTimeout(pod string, command ...string) (string, error) {
+	type result struct {
+		out string
+		err error
+	}
+	results := make(chan result, 1)
+	go func() {
+		stdout, stderr, err := pods.Exec(test.kubeConfig, test.kubeClient, test.namespace, pod, devPodContainer, command...)
+		results <- result{out: strings.TrimSpace(stdout + stderr), err: err}
+	}()
+	select {
+	case r := <-results:
+		return r.out, r.err
+	case <-time.After(test.timeout):
+		return "", errors.Errorf("timed out after %s running %s in devpod %s", test.timeout, strings.Join(command, " "), pod)
+	}
+}
+
+// checkDevPodToolchain runs the toolchain checks of the label and the common checks in the devpod created with it,
+// returning the failures of all the checks which failed
+func (test *TestDevPods) checkDevPodToolchain(label string, name string) error {
+	checks, ok := toolchainChecks(label)
+	if !ok {
+		utils.LogInfof("WARNING: no toolchain checks for devpods with label %s so only checking jx and git\n", label)
+	}
+	var failures []string
+	for _, c := range append(checks, commonChecks...) {
+		args := strings.Join(c.command, " ")
+		out, err := test.execWithTimeout(name, c.command...)
+		switch {
+		case err != nil:
+			failures = append(failures, err.Error())
+		case !c.expect.MatchString(out):
+			failures = append(failures, fmt.Sprintf("the output of %s in devpod %s does not match %s: %s", args, name, c.expect, out))
+		default:
+			utils.LogInfof("%s in devpod %s: %s\n", args, name, out)
+		}
+	}
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// checkDevPodWorkspace checks that the workspace volume of the devpod with the given name is mounted in its devpod
+// container and writable
+func (test *TestDevPods) checkDevPodWorkspace(name string) error {
+	pod, err := test.kubeClient.CoreV1().Pods(test.namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "getting devpod %s", name)
+	}
+	if workspaceMount(pod) == nil {
+		return errors.Errorf("the %s container of devpod %s has no volume mounted on %s", devPodContainer, name, devPodWorkspace)
+	}
+
+	file := fmt.Sprintf("%s/.bdd-%s", devPodWorkspace, rand.String(5))
+	utils.LogInfof("writing %s in devpod %s\n", file, name)
+	out, err := test.execWithTimeout(name, "sh", "-c", fmt.Sprintf("echo %s > %s && cat %s && rm %s", name, file, file, file))
+	if err != nil {
+		return err
+	}
+	if out != name {
+		return errors.Errorf("expected %s to contain %s in devpod %s but it contained %s", file, name, name, out)
+	}
+	return nil
+}
+
+// workspaceMount returns the mount of the workspace volume of the devpod container of a devpod, if any
+func workspaceMount(pod *corev1.Pod) *corev1.VolumeMount {
+	for _, container := range pod.Spec.Containers {
+		if container.Name != devPodContainer {
+			continue
+		}
+		for i, mount := range container.VolumeMounts {
+			if mount.MountPath == devPodWorkspace {
+				return &container.VolumeMounts[i]
+			}
+		}
+	}
+	return nil
+}
@@ -5,16 +5,17 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/cenkalti/backoff"
 	"github.com/jenkins-x/bdd-jx/test/helpers"
-	"github.com/jenkins-x/go-scm/scm"
 
 	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/bdd-jx/test/utils/owners"
+	"github.com/jenkins-x/bdd-jx/test/utils/parsers"
+	"github.com/jenkins-x/bdd-jx/test/utils/pipelines"
 	"github.com/jenkins-x/jx/v2/pkg/gits"
 	"github.com/jenkins-x/jx/v2/pkg/util"
 
@@ -23,18 +24,9 @@ import (
 )
 
 const (
-	defaultContext    = "pr-build"
-	lhQuickstart      = "golang-http"
-	brokenJenkinsXYml = `buildPack: go
-pipelineConfig:
-  pipelines:
-    overrides:
-      # Replace make-build on pullRequest in any stage/lifecycle with "exit 1" so the pipeline will fail.
-      - pipeline: pullRequest
-        name: make-linux
-        step:
-          sh: sleep 15 && exit 1
-`
+	defaultContext = "pr-build"
+	lhQuickstart   = "golang-http"
+	lhBuildPack    = "go"
 )
 
 var _ = ChatOpsTests()
@@ -46,6 +38,7 @@ func ChatOpsTests() bool {
 			err              error
 			provider         gits.GitProvider
 			approverProvider gits.GitProvider
+			dialect          *helpers.ProviderDialect
 		)
 
 		BeforeEach(func() {
@@ -57,80 +50,28 @@ func ChatOpsTests() bool {
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(approverProvider).ShouldNot(BeNil())
 
-			qsNameParts := strings.Split(lhQuickstart, "-")
-			qsAbbr := ""
-			for s := range qsNameParts {
-				qsAbbr = qsAbbr + qsNameParts[s][:1]
+			dialect = helpers.DialectForProvider(provider)
 
-			}
-			applicationName := helpers.TempDirPrefix + qsAbbr + "-" + strconv.FormatInt(GinkgoRandomSeed(), 10)
-			T = helpers.TestOptions{
-				ApplicationName: applicationName,
-				WorkDir:         helpers.WorkDir,
-			}
-			T.GitProviderURL()
+			T = newTestOptions("")
+		})
 
-			utils.LogInfof("Creating application %s in dir %s\n", util.ColorInfo(applicationName), util.ColorInfo(helpers.WorkDir))
+		AfterEach(func() {
+			deleteQuickstart(&T)
 		})
 
 		Describe("Create a quickstart", func() {
 			Context(fmt.Sprintf("by running jx create quickstart %s", lhQuickstart), func() {
 				It("creates a new source repository", func() {
-					args := []string{"create", "quickstart", "-b", "--org", T.GetGitOrganisation(), "-p", T.ApplicationName, "-f", lhQuickstart}
-
-					gitProviderUrl, err := T.GitProviderURL()
-					Expect(err).NotTo(HaveOccurred())
-					if gitProviderUrl != "" {
-						utils.LogInfof("Using Git provider URL %s\n", gitProviderUrl)
-						args = append(args, "--git-provider-url", gitProviderUrl)
-					}
-					argsStr := strings.Join(args, " ")
-					By(fmt.Sprintf("calling jx %s", argsStr), func() {
-						T.ExpectJxExecution(T.WorkDir, helpers.TimeoutSessionWait, 0, args...)
-					})
-
-					By("adding the approver to OWNERS", func() {
-						createdPR := T.CreatePullRequestWithLocalChange(fmt.Sprintf("Adding %s to OWNERS", helpers.PullRequestApproverUsername), func(workDir string) {
-							// overwrite the existing OWNERS with a new one containing the approver user
-							fileName := "OWNERS"
-							owners := filepath.Join(workDir, fileName)
-
-							data := []byte(fmt.Sprintf("approvers:\n- %s\n- %s\nreviewers:\n- %s\n- %s\n",
-								provider.UserAuth().Username, helpers.PullRequestApproverUsername,
-								provider.UserAuth().Username, helpers.PullRequestApproverUsername))
-							err := ioutil.WriteFile(owners, data, util.DefaultWritePermissions)
-							if err != nil {
-								panic(err)
-							}
-
-							T.ExpectCommandExecution(workDir, time.Minute, 0, "git", "add", fileName)
-						})
-
-						ownersPR, err := T.GetPullRequestByNumber(provider, createdPR.Owner, createdPR.Repository, createdPR.PullRequestNumber)
-						Expect(err).NotTo(HaveOccurred())
-						Expect(ownersPR).ShouldNot(BeNil())
-
-						By("merging the OWNERS PR")
-						// GitLab seems to want us to sleep a bit after creation
-						if provider.Kind() == "gitlab" {
-							time.Sleep(30 * time.Second)
-						}
-						err = provider.MergePullRequest(ownersPR, "PR merge")
-						Expect(err).ShouldNot(HaveOccurred())
-
-						T.WaitForPullRequestToMerge(provider, ownersPR.Owner, ownersPR.Repo, *ownersPR.Number, ownersPR.URL)
-					})
+					createQuickstartWithApproverInOwners(&T, provider, dialect)
 
 					prTitle := "My First PR commit"
 					var pr *gits.GitPullRequest
 					By("performing a pull request on the source and making sure it fails", func() {
 						createdPR := T.CreatePullRequestWithLocalChange(prTitle, func(workDir string) {
-							// overwrite the existing jenkins-x.yml with a failing one
-							fileName := "jenkins-x.yml"
-							jxYml := filepath.Join(workDir, fileName)
-
-							data := []byte(brokenJenkinsXYml)
-							err := ioutil.WriteFile(jxYml, data, util.DefaultWritePermissions)
+							// overwrite the existing jenkins-x.yml with one replacing make-linux on pullRequest so the pipeline will fail
+							broken := pipelines.NewConfig(lhBuildPack)
+							broken.PullRequest().FailStep("make-linux", 15*time.Second)
+							fileName, err := broken.WriteTo(workDir)
 							if err != nil {
 								panic(err)
 							}
@@ -143,22 +84,16 @@ func ChatOpsTests() bool {
 						Expect(pr).ShouldNot(BeNil())
 
 						By("verifying OWNERS link in APPROVALNOTIFIER comment is correct", func() {
-							err = T.ExpectThatPullRequestHasCommentMatching(provider, createdPR.PullRequestNumber, createdPR.Owner, createdPR.Repository, func(comments []*scm.Comment) error {
-								for _, c := range comments {
-									if strings.Contains(c.Body, "[APPROVALNOTIFIER]") {
-										ownerRegex := regexp.MustCompile(`(?m).*\[OWNERS]\((.*)\).*`)
-										matches := ownerRegex.FindStringSubmatch(c.Body)
-										if len(matches) == 0 {
-											return backoff.Permanent(fmt.Errorf("could not find OWNERS link in:\n%s", c.Body))
-										}
-										expected := urlForProvider(provider.Kind(), provider.ServerURL(), createdPR.Owner, createdPR.Repository)
-										if expected != matches[1] {
-											return backoff.Permanent(fmt.Errorf("expected OWNERS URL %s, but got %s", expected, matches[1]))
-										}
-										return nil
-									}
+							err = T.ExpectThatPullRequestHasApprovalNotifierMatching(provider, createdPR.PullRequestNumber, createdPR.Owner, createdPR.Repository, func(notifier *parsers.ApprovalNotifier) error {
+								ownersFile := notifier.File(owners.OwnersFileName)
+								if ownersFile == nil {
+									return backoff.Permanent(fmt.Errorf("could not find OWNERS link in APPROVALNOTIFIER comment: %#v", notifier))
+								}
+								expected := dialect.OwnersURL(provider.ServerURL(), createdPR.Owner, createdPR.Repository)
+								if expected != ownersFile.Url {
+									return backoff.Permanent(fmt.Errorf("expected OWNERS URL %s, but got %s", expected, ownersFile.Url))
 								}
-								return fmt.Errorf("couldn't find comment containing APPROVALNOTIFIER")
+								return nil
 							})
 							Expect(err).NotTo(HaveOccurred())
 						})
@@ -167,9 +102,14 @@ func ChatOpsTests() bool {
 						})
 
 						By("getting build log for a completed build", func() {
-							// Verify that we can get the build log for a completed build.
+							// Verify that we can get the build log for a completed build, which reports the failed step.
 							jobName := createdPR.Owner + "/" + createdPR.Repository + "/PR-" + strconv.Itoa(createdPR.PullRequestNumber)
-							T.TailSpecificBuildLog(jobName, 1, helpers.TimeoutBuildCompletes)
+							log, err := T.GetBuildLog(jobName, 1, helpers.TimeoutBuildCompletes)
+							Expect(err).NotTo(HaveOccurred())
+							if len(log.FailedSteps()) == 0 {
+								T.AttachBuildLog(log)
+							}
+							Expect(log.FailedSteps()).ShouldNot(BeEmpty(), "the build log should report the failed step")
 						})
 					})
 
@@ -179,7 +119,7 @@ func ChatOpsTests() bool {
 					})
 
 					// TODO: Figure out if this something that we can actually fix for BitBucket Server or if we should just ignore it forever
-					if provider.Kind() != gits.KindBitBucketServer {
+					if dialect.SupportsReviewRequests {
 						By("requesting and unrequesting a reviewer", func() {
 							err = T.AddReviewerToPullRequestWithChatOpsCommand(provider, approverProvider, pr, helpers.PullRequestApproverUsername)
 							Expect(err).NotTo(HaveOccurred())
@@ -192,7 +132,7 @@ func ChatOpsTests() bool {
 					})
 
 					// Adding WIP to a MR title is hijacked by GitLab and currently doesn't send a webhook event, so skip for now.
-					if provider.Kind() != "gitlab" {
+					if !dialect.HijacksFeature(helpers.FeatureWIPTitle) {
 						By("adding a WIP label", func() {
 							err = T.AddWIPLabelToPullRequestByUpdatingTitle(provider, pr)
 							Expect(err).NotTo(HaveOccurred())
@@ -242,7 +182,7 @@ func ChatOpsTests() bool {
 
 					// TODO: Later: add multiple contexts, one more required, one more optional
 
-					if provider.Kind() == "github" {
+					if dialect.SupportsIssueCommand("assign") {
 						By("creating an issue and assigning it to a valid user", func() {
 							issue := &gits.GitIssue{
 								Owner: T.GetGitOrganisation(),
@@ -254,36 +194,152 @@ func ChatOpsTests() bool {
 							Expect(err).NotTo(HaveOccurred())
 						})
 					}
+				})
+			})
+		})
+	})
+}
 
-					if T.DeleteApplications() {
-						args = []string{"delete", "application", "-b", T.ApplicationName}
-						argsStr := strings.Join(args, " ")
-						By(fmt.Sprintf("calling %s to delete the application", argsStr), func() {
-							T.ExpectJxExecution(T.WorkDir, helpers.TimeoutSessionWait, 0, args...)
-						})
-					}
+// createQuickstartWithApproverInOwners creates the lighthouse quickstart and merges a PR adding the approver user to its
+// OWNERS file, leaving the work dir on the branch of that PR.
+func createQuickstartWithApproverInOwners(t *helpers.TestOptions, provider gits.GitProvider, dialect *helpers.ProviderDialect) {
+	createNamedQuickstartWithApproverInOwners(t, lhQuickstart, provider, dialect)
+}
 
-					if T.DeleteRepos() {
-						args = []string{"delete", "repo", "-b", "--github", "-o", T.GetGitOrganisation(), "-n", T.ApplicationName}
-						argsStr = strings.Join(args, " ")
+// createNamedQuickstartWithApproverInOwners is createQuickstartWithApproverInOwners for the given quickstart
+func createNamedQuickstartWithApproverInOwners(t *helpers.TestOptions, quickstart string, provider gits.GitProvider, dialect *helpers.ProviderDialect) {
+	args := []string{"create", "quickstart", "-b", "--org", t.GetGitOrganisation(), "-p", t.ApplicationName, "-f", quickstart}
 
-						By(fmt.Sprintf("calling %s to delete the repository", os.Args), func() {
-							T.ExpectJxExecution(T.WorkDir, helpers.TimeoutSessionWait, 0, args...)
-						})
-					}
-				})
-			})
+	gitProviderUrl, err := t.GitProviderURL()
+	Expect(err).NotTo(HaveOccurred())
+	if gitProviderUrl != "" {
+		utils.LogInfof("Using Git provider URL %s\n", gitProviderUrl)
+		args = append(args, "--git-provider-url", gitProviderUrl)
+	}
+	argsStr := strings.Join(args, " ")
+	By(fmt.Sprintf("calling jx %s", argsStr), func() {
+		t.ExpectJxExecution(t.WorkDir, helpers.TimeoutSessionWait, 0, args...)
+	})
+
+	By("adding the approver to OWNERS", func() {
+		createdPR := t.CreatePullRequestWithLocalChange(fmt.Sprintf("Adding %s to OWNERS", helpers.PullRequestApproverUsername), func(workDir string) {
+			// overwrite the existing OWNERS with a new one containing the approver user
+			tree := owners.NewTree()
+			tree.Dir("").
+				Approvers(provider.UserAuth().Username, helpers.PullRequestApproverUsername).
+				Reviewers(provider.UserAuth().Username, helpers.PullRequestApproverUsername)
+			written, err := tree.WriteTo(workDir)
+			if err != nil {
+				panic(err)
+			}
+
+			t.ExpectCommandExecution(workDir, time.Minute, 0, "git", append([]string{"add"}, written...)...)
 		})
+
+		By("merging the OWNERS PR")
+		mergePullRequest(t, provider, dialect, createdPR)
 	})
 }
 
-func urlForProvider(providerType string, serverURL string, owner string, repo string) string {
-	switch providerType {
-	case "bitbucketserver":
-		return fmt.Sprintf("%s/projects/%s/repos/%s/browse/OWNERS", serverURL, strings.ToUpper(owner), repo)
-	case "gitlab":
-		return fmt.Sprintf("%s/%s/%s/-/blob/master/OWNERS", serverURL, owner, repo)
-	default:
-		return fmt.Sprintf("%s/%s/%s/blob/master/OWNERS", serverURL, owner, repo)
+// mergePullRequest merges the created pull request through the git provider API without waiting for approval
+func mergePullRequest(t *helpers.TestOptions, provider gits.GitProvider, dialect *helpers.ProviderDialect, createdPR *parsers.CreatePullRequest) {
+	pr, err := t.GetPullRequestByNumber(provider, createdPR.Owner, createdPR.Repository, createdPR.PullRequestNumber)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(pr).ShouldNot(BeNil())
+
+	// Some providers want us to sleep a bit after creation
+	time.Sleep(dialect.MergeDelay)
+	err = provider.MergePullRequest(pr, "PR merge")
+	Expect(err).ShouldNot(HaveOccurred())
+
+	t.WaitForPullRequestToMerge(provider, pr.Owner, pr.Repo, *pr.Number, pr.URL)
+}
+
+// deleteQuickstart closes the issues created by the test and deletes the application and repository created by
+// createQuickstartWithApproverInOwners. It is meant to be called from an AfterEach so nothing is left behind when a spec
+// fails, and deletes nothing if the quickstart was never created.
+func deleteQuickstart(t *helpers.TestOptions) {
+	By("closing the issues created by the test", func() {
+		provider, err := t.GetGitProvider()
+		Expect(err).ShouldNot(HaveOccurred())
+		err = t.CloseCreatedIssues(provider)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	if t.ApplicationName == "" {
+		return
+	}
+	if _, err := os.Stat(filepath.Join(t.WorkDir, t.ApplicationName)); os.IsNotExist(err) {
+		utils.LogInfof("not deleting %s as it was not created\n", t.ApplicationName)
+		return
 	}
+
+	if t.DeleteApplications() {
+		args := []string{"delete", "application", "-b", t.ApplicationName}
+		argsStr := strings.Join(args, " ")
+		By(fmt.Sprintf("calling %s to delete the application", argsStr), func() {
+			t.ExpectJxExecution(t.WorkDir, helpers.TimeoutSessionWait, 0, args...)
+		})
+	}
+
+	if t.DeleteRepos() {
+		args := []string{"delete", "repo", "-b", "--github", "-o", t.GetGitOrganisation(), "-n", t.ApplicationName}
+		argsStr := strings.Join(args, " ")
+
+		By(fmt.Sprintf("calling %s to delete the repository", argsStr), func() {
+			t.ExpectJxExecution(t.WorkDir, helpers.TimeoutSessionWait, 0, args...)
+		})
+	}
+}
+
+// newTestOptions returns the test options for a new application created from the lighthouse quickstart, with the
+// given suffix added to the application name so that several specs can run against the same cluster.
+func newTestOptions(suffix string) helpers.TestOptions {
+	return newQuickstartTestOptions(lhQuickstart, suffix)
+}
+
+// newQuickstartTestOptions is newTestOptions for an application created from the given quickstart
+func newQuickstartTestOptions(quickstart string, suffix string) helpers.TestOptions {
+	qsNameParts := strings.Split(quickstart, "-")
+	qsAbbr := ""
+	for s := range qsNameParts {
+		qsAbbr = qsAbbr + qsNameParts[s][:1]
+
+	}
+	applicationName := helpers.TempDirPrefix + qsAbbr + suffix + "-" + strconv.FormatInt(GinkgoRandomSeed(), 10)
+	t := helpers.TestOptions{
+		ApplicationName: applicationName,
+		WorkDir:         helpers.WorkDir,
+	}
+	t.GitProviderURL()
+
+	utils.LogInfof("Creating application %s in dir %s\n", util.ColorInfo(applicationName), util.ColorInfo(helpers.WorkDir))
+	return t
+}
+
+// createPullRequestFromMaster creates a pull request branched from the latest master which adds the given file
+func createPullRequestFromMaster(t *helpers.TestOptions, provider gits.GitProvider, title string, fileName string) *gits.GitPullRequest {
+	createdPR := createPullRequestFromMasterWithChange(t, title, func(workDir string) []string {
+		err := ioutil.WriteFile(filepath.Join(workDir, fileName), []byte(title+"\n"), util.DefaultWritePermissions)
+		Expect(err).ShouldNot(HaveOccurred())
+		return []string{fileName}
+	})
+
+	pr, err := t.GetPullRequestByNumber(provider, createdPR.Owner, createdPR.Repository, createdPR.PullRequestNumber)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(pr).ShouldNot(BeNil())
+	return pr
+}
+
+// createPullRequestFromMasterWithChange creates a pull request branched from the latest master with the change made by
+// the given function, which returns the paths it wrote relative to the work dir
+func createPullRequestFromMasterWithChange(t *helpers.TestOptions, title string, makeChange func(workDir string) []string) *parsers.CreatePullRequest {
+	workDir := filepath.Join(t.WorkDir, t.GetApplicationName())
+	t.ExpectCommandExecution(workDir, time.Minute, 0, "git", "checkout", "master")
+	t.ExpectCommandExecution(workDir, time.Minute, 0, "git", "pull")
+
+	return t.CreatePullRequestWithLocalChange(title, func(workDir string) {
+		paths := makeChange(workDir)
+		t.ExpectCommandExecution(workDir, time.Minute, 0, "git", append([]string{"add"}, paths...)...)
+	})
 }
@@ -0,0 +1,156 @@
+package lighthouse
+
+import (
+	"fmt"
+
+	"github.com/jenkins-x/bdd-jx/test/helpers"
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	kindLabel      = "kind/bug"
+	priorityLabel  = "priority/important-soon"
+	lifecycleLabel = "lifecycle/frozen"
+
+	unauthorisedCloseReply = "You can't close an active issue/PR unless you authored it or you are a collaborator."
+)
+
+var _ = IssueTests()
+
+// IssueTests verifies the Lighthouse ChatOps commands on issues.
+func IssueTests() bool {
+	return Describe("Lighthouse issue ChatOps", func() {
+		var (
+			T                helpers.TestOptions
+			err              error
+			provider         gits.GitProvider
+			approverProvider gits.GitProvider
+			dialect          *helpers.ProviderDialect
+		)
+
+		BeforeEach(func() {
+			provider, err = T.GetGitProvider()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(provider).ShouldNot(BeNil())
+
+			approverProvider, err = T.GetApproverGitProvider()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(approverProvider).ShouldNot(BeNil())
+
+			dialect = helpers.DialectForProvider(provider)
+
+			T = newTestOptions("-issues")
+		})
+
+		AfterEach(func() {
+			deleteQuickstart(&T)
+		})
+
+		Describe("Commenting on an issue", func() {
+			It("applies the ChatOps commands", func() {
+				if !dialect.SupportsIssues() {
+					Skip(fmt.Sprintf("issue ChatOps commands are not tested on %s", provider.Kind()))
+				}
+
+				createQuickstartWithApproverInOwners(&T, provider, dialect)
+
+				owner := T.GetGitOrganisation()
+				repo := T.GetApplicationName()
+				bot := provider.CurrentUsername()
+				comment := func(p gits.GitProvider, number int, name string, args ...string) {
+					command := dialect.Command(name, args...)
+					utils.LogInfof("commenting %s on issue %d as %s\n", command, number, p.CurrentUsername())
+					err := p.CreateIssueComment(owner, repo, number, command)
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+
+				By("creating the labels used by /kind and /priority", func() {
+					err = T.EnsureRepoLabels(provider, owner, repo, kindLabel, priorityLabel)
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+
+				var issue *scm.Issue
+				By("creating an issue", func() {
+					issue, err = T.CreateIssue(provider, owner, repo, "Test issue ChatOps commands", "This tests the ChatOps commands on issues")
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+
+				if dialect.SupportsIssueCommand("assign") {
+					By("assigning the issue", func() {
+						comment(provider, issue.Number, "assign", bot)
+						err = T.ExpectThatIssueIsAssignedToUser(provider, &gits.GitIssue{Owner: owner, Repo: repo, Number: &issue.Number}, bot)
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+				}
+
+				if dialect.SupportsIssueCommand("unassign") {
+					By("unassigning the issue", func() {
+						comment(provider, issue.Number, "unassign", bot)
+						err = T.ExpectThatIssueIsNotAssignedToUser(provider, owner, repo, issue.Number, bot)
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+				}
+
+				if dialect.SupportsIssueCommand("kind") {
+					By("labelling the issue with /kind", func() {
+						comment(provider, issue.Number, "kind", "bug")
+						err = T.ExpectThatIssueHasLabel(provider, owner, repo, issue.Number, kindLabel)
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+				}
+
+				if dialect.SupportsIssueCommand("priority") {
+					By("labelling the issue with /priority", func() {
+						comment(provider, issue.Number, "priority", "important-soon")
+						err = T.ExpectThatIssueHasLabel(provider, owner, repo, issue.Number, priorityLabel)
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+				}
+
+				if dialect.SupportsIssueCommand("lifecycle") {
+					By("freezing the issue with /lifecycle", func() {
+						comment(provider, issue.Number, "lifecycle", "frozen")
+						err = T.ExpectThatIssueHasLabel(provider, owner, repo, issue.Number, lifecycleLabel)
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+
+					By("unfreezing the issue with /remove-lifecycle", func() {
+						comment(provider, issue.Number, "remove-lifecycle", "frozen")
+						err = T.ExpectThatIssueDoesNotHaveLabel(provider, owner, repo, issue.Number, lifecycleLabel)
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+				}
+
+				if dialect.SupportsIssueCommand("close") {
+					By("trying to close the issue as a user who is not a collaborator", func() {
+						// the approver has not approved anything on this repo, so has not been added as a collaborator
+						comment(approverProvider, issue.Number, "close")
+						err = T.ExpectThatIssueHasCommentContaining(provider, owner, repo, issue.Number, bot, unauthorisedCloseReply)
+						Expect(err).ShouldNot(HaveOccurred())
+						err = T.ExpectThatIssueIsClosed(provider, owner, repo, issue.Number, false)
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+
+					By("closing the issue as its author", func() {
+						comment(provider, issue.Number, "close")
+						err = T.ExpectThatIssueIsClosed(provider, owner, repo, issue.Number, true)
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+				}
+
+				if dialect.SupportsIssueCommand("reopen") {
+					By("reopening the issue as its author", func() {
+						comment(provider, issue.Number, "reopen")
+						err = T.ExpectThatIssueIsClosed(provider, owner, repo, issue.Number, false)
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+				}
+			})
+		})
+	})
+}
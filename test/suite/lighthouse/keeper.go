@@ -0,0 +1,140 @@
+package lighthouse
+
+import (
+	"fmt"
+
+	"github.com/jenkins-x/bdd-jx/test/helpers"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	// keeperPoolSize is the number of pull requests approved together for keeper to merge
+	keeperPoolSize = 3
+
+	holdLabel = "do-not-merge/hold"
+	wipLabel  = "do-not-merge/work-in-progress"
+)
+
+var _ = KeeperTests()
+
+// KeeperTests verifies that Lighthouse keeper merges the pull requests in its merge pool and keeps held and work in
+// progress pull requests out of it.
+func KeeperTests() bool {
+	return Describe("Lighthouse Keeper", func() {
+		var (
+			T                helpers.TestOptions
+			err              error
+			provider         gits.GitProvider
+			approverProvider gits.GitProvider
+			dialect          *helpers.ProviderDialect
+		)
+
+		BeforeEach(func() {
+			provider, err = T.GetGitProvider()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(provider).ShouldNot(BeNil())
+
+			approverProvider, err = T.GetApproverGitProvider()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(approverProvider).ShouldNot(BeNil())
+
+			dialect = helpers.DialectForProvider(provider)
+
+			T = newTestOptions("-keeper")
+		})
+
+		AfterEach(func() {
+			deleteQuickstart(&T)
+		})
+
+		Describe("Approving several pull requests together", func() {
+			It("merges the pool and keeps held and WIP pull requests out of it", func() {
+				createQuickstartWithApproverInOwners(&T, provider, dialect)
+
+				var poolPRs []*gits.GitPullRequest
+				By(fmt.Sprintf("opening %d pull requests for the merge pool", keeperPoolSize), func() {
+					for i := 1; i <= keeperPoolSize; i++ {
+						poolPRs = append(poolPRs, createPullRequestFromMaster(&T, provider, fmt.Sprintf("Keeper pool PR %d", i), fmt.Sprintf("keeper-%d.txt", i)))
+					}
+				})
+
+				var heldPR *gits.GitPullRequest
+				By("opening a pull request and holding it", func() {
+					heldPR = createPullRequestFromMaster(&T, provider, "Keeper held PR", "keeper-held.txt")
+					err = provider.AddPRComment(heldPR, "/hold")
+					Expect(err).ShouldNot(HaveOccurred())
+					err = T.ExpectThatPullRequestHasLabel(provider, *heldPR.Number, heldPR.Owner, heldPR.Repo, holdLabel)
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+
+				var wipPR *gits.GitPullRequest
+				// Adding WIP to a MR title is hijacked by GitLab and currently doesn't send a webhook event, so skip for now.
+				if !dialect.HijacksFeature(helpers.FeatureWIPTitle) {
+					By("opening a work in progress pull request", func() {
+						wipPR = createPullRequestFromMaster(&T, provider, "WIP Keeper PR", "keeper-wip.txt")
+						err = T.ExpectThatPullRequestHasLabel(provider, *wipPR.Number, wipPR.Owner, wipPR.Repo, wipLabel)
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+				}
+
+				By("waiting for the pool pull requests to build successfully", func() {
+					for _, pr := range poolPRs {
+						T.WaitForPullRequestCommitStatus(provider, pr, []string{defaultContext}, "success")
+					}
+				})
+
+				By("approving all the pull requests together", func() {
+					approved := append([]*gits.GitPullRequest{}, poolPRs...)
+					approved = append(approved, heldPR)
+					if wipPR != nil {
+						approved = append(approved, wipPR)
+					}
+					for _, pr := range approved {
+						err = T.ApprovePullRequest(provider, approverProvider, pr)
+						Expect(err).ShouldNot(HaveOccurred())
+					}
+				})
+
+				// checked before the pull requests merge, so keeper must report them while they wait in the pool
+				By("waiting for keeper to report each approved pool pull request as in the merge pool", func() {
+					for _, pr := range poolPRs {
+						err = T.ExpectThatPullRequestIsInMergePool(provider, pr)
+						Expect(err).ShouldNot(HaveOccurred())
+					}
+				})
+
+				By("verifying the held pull request is not in the merge pool", func() {
+					err = T.ExpectThatPullRequestIsNotInMergePool(provider, heldPR, helpers.KeeperStatusNotInPoolForLabel(provider, holdLabel))
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+
+				if wipPR != nil {
+					By("verifying the WIP pull request is not in the merge pool", func() {
+						err = T.ExpectThatPullRequestIsNotInMergePool(provider, wipPR, helpers.KeeperStatusNotInPoolForLabel(provider, wipLabel))
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+				}
+
+				By("waiting for keeper to merge the pool", func() {
+					records := T.WaitForPullRequestsToMergeFromPool(provider, poolPRs)
+					err = T.ExpectPullRequestsMergedInBatchOrRetested(records)
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+
+				By("verifying the held and WIP pull requests were not merged", func() {
+					for _, pr := range []*gits.GitPullRequest{heldPR, wipPR} {
+						if pr == nil {
+							continue
+						}
+						latest, err := T.GetPullRequestByNumber(provider, pr.Owner, pr.Repo, *pr.Number)
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(latest.Merged == nil || !*latest.Merged).Should(BeTrue(), "PR %s should not have been merged", pr.URL)
+					}
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,135 @@
+package lighthouse
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/bdd-jx/test/helpers"
+	"github.com/jenkins-x/bdd-jx/test/utils/owners"
+	"github.com/jenkins-x/bdd-jx/test/utils/parsers"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+	"github.com/jenkins-x/jx/v2/pkg/util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	subDir            = "sub"
+	subApproversAlias = "sub-approvers"
+)
+
+var _ = OwnersTests()
+
+// OwnersTests verifies that Lighthouse requires approval from the OWNERS of each directory a pull request changes.
+func OwnersTests() bool {
+	return Describe("Lighthouse OWNERS", func() {
+		var (
+			T                helpers.TestOptions
+			err              error
+			provider         gits.GitProvider
+			approverProvider gits.GitProvider
+			dialect          *helpers.ProviderDialect
+		)
+
+		BeforeEach(func() {
+			provider, err = T.GetGitProvider()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(provider).ShouldNot(BeNil())
+
+			approverProvider, err = T.GetApproverGitProvider()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(approverProvider).ShouldNot(BeNil())
+
+			dialect = helpers.DialectForProvider(provider)
+
+			T = newTestOptions("-owners")
+		})
+
+		AfterEach(func() {
+			deleteQuickstart(&T)
+		})
+
+		Describe("Changing a file in a directory with its own OWNERS", func() {
+			It("requires approval from the approvers of that directory", func() {
+				createQuickstartWithApproverInOwners(&T, provider, dialect)
+
+				By("adding OWNERS for a subdirectory approved only by an alias of the approver", func() {
+					createdPR := createPullRequestFromMasterWithChange(&T, "Adding subdirectory OWNERS", func(workDir string) []string {
+						tree := owners.NewTree()
+						tree.Alias(subApproversAlias, helpers.PullRequestApproverUsername)
+						tree.Dir(subDir).Approvers(subApproversAlias).Reviewers(subApproversAlias).NoParentOwners()
+						written, err := tree.WriteTo(workDir)
+						Expect(err).ShouldNot(HaveOccurred())
+						return written
+					})
+					mergePullRequest(&T, provider, dialect, createdPR)
+				})
+
+				subOwners := path.Join(subDir, owners.OwnersFileName)
+				var pr *gits.GitPullRequest
+				By("opening a pull request changing a file in the subdirectory", func() {
+					createdPR := createPullRequestFromMasterWithChange(&T, "Changing a file in the subdirectory", func(workDir string) []string {
+						fileName := path.Join(subDir, "README.md")
+						err := os.MkdirAll(filepath.Join(workDir, subDir), os.ModePerm)
+						Expect(err).ShouldNot(HaveOccurred())
+						err = ioutil.WriteFile(filepath.Join(workDir, fileName), []byte("Changed in a PR\n"), util.DefaultWritePermissions)
+						Expect(err).ShouldNot(HaveOccurred())
+						return []string{fileName}
+					})
+					pr, err = T.GetPullRequestByNumber(provider, createdPR.Owner, createdPR.Repository, createdPR.PullRequestNumber)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(pr).ShouldNot(BeNil())
+				})
+
+				By("verifying the APPROVALNOTIFIER comment asks for the subdirectory approvers", func() {
+					err = T.ExpectThatPullRequestHasApprovalNotifierMatching(provider, *pr.Number, pr.Owner, pr.Repo, func(notifier *parsers.ApprovalNotifier) error {
+						if notifier.Approved {
+							return fmt.Errorf("expected PR %s not to be approved yet", pr.URL)
+						}
+						ownersFile := notifier.File(subOwners)
+						if ownersFile == nil {
+							return fmt.Errorf("expected %s in APPROVALNOTIFIER comment but found %#v", subOwners, notifier.Files)
+						}
+						expected := dialect.BlobURL(provider.ServerURL(), pr.Owner, pr.Repo, "master", subOwners)
+						if ownersFile.Url != expected {
+							return fmt.Errorf("expected %s URL %s, but got %s", subOwners, expected, ownersFile.Url)
+						}
+						for _, a := range notifier.SuggestedApprovers {
+							if strings.EqualFold(a, helpers.PullRequestApproverUsername) {
+								return nil
+							}
+						}
+						return fmt.Errorf("expected %s to be a suggested approver but got %v", helpers.PullRequestApproverUsername, notifier.SuggestedApprovers)
+					})
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+
+				By("approving as a root approver and verifying the subdirectory still needs approval", func() {
+					err = provider.AddPRComment(pr, dialect.Command("approve"))
+					Expect(err).ShouldNot(HaveOccurred())
+
+					err = T.ExpectThatPullRequestNeedsApprovalFor(provider, *pr.Number, pr.Owner, pr.Repo, subOwners)
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+
+				By("approving as the subdirectory approver", func() {
+					err = T.ApprovePullRequest(provider, approverProvider, pr)
+					Expect(err).ShouldNot(HaveOccurred())
+
+					err = T.ExpectThatPullRequestHasApprovalNotifierMatching(provider, *pr.Number, pr.Owner, pr.Repo, func(notifier *parsers.ApprovalNotifier) error {
+						if !notifier.Approved || len(notifier.FilesNeedingApproval()) > 0 {
+							return fmt.Errorf("expected PR %s to be approved but still needs approval for %v", pr.URL, notifier.FilesNeedingApproval())
+						}
+						return nil
+					})
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+			})
+		})
+	})
+}
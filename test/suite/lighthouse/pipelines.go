@@ -0,0 +1,141 @@
+package lighthouse
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/helpers"
+	"github.com/jenkins-x/bdd-jx/test/utils/imports"
+	"github.com/jenkins-x/bdd-jx/test/utils/pipelines"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	// pipelineDelay is how long the slow pull request pipeline waits before building
+	pipelineDelay = 2 * time.Minute
+	// pipelineEnvVar is the environment variable the slow pull request pipeline expects to be set by its jenkins-x.yml
+	pipelineEnvVar = "BDD_PIPELINE_MARKER"
+	// pipelineTimeout is the timeout of the pull request pipeline which sleeps for longer than it
+	pipelineTimeout = time.Minute
+)
+
+var _ = PipelineTests()
+
+// PipelineTests verifies how Lighthouse reports failed, retried, slow and timed out pull request builds, overriding the pipelines
+// of a quickstart for each of the build packs in BDD_LIGHTHOUSE_PIPELINE_QUICKSTARTS.
+func PipelineTests() bool {
+	return Describe("Lighthouse pipelines", func() {
+		var (
+			T                helpers.TestOptions
+			err              error
+			provider         gits.GitProvider
+			approverProvider gits.GitProvider
+			dialect          *helpers.ProviderDialect
+		)
+
+		BeforeEach(func() {
+			provider, err = T.GetGitProvider()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(provider).ShouldNot(BeNil())
+
+			approverProvider, err = T.GetApproverGitProvider()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(approverProvider).ShouldNot(BeNil())
+
+			dialect = helpers.DialectForProvider(provider)
+		})
+
+		for _, quickstart := range strings.Split(helpers.LighthousePipelineQuickstarts, ",") {
+			quickstart := strings.TrimSpace(quickstart)
+			if quickstart == "" {
+				continue
+			}
+
+			Describe(fmt.Sprintf("Overriding the pull request pipeline of the %s quickstart", quickstart), func() {
+				BeforeEach(func() {
+					T = newQuickstartTestOptions(quickstart, "-pipelines")
+				})
+
+				AfterEach(func() {
+					deleteQuickstart(&T)
+				})
+
+				It("reports failed, retried, slow and timed out builds", func() {
+					createNamedQuickstartWithApproverInOwners(&T, quickstart, provider, dialect)
+
+					var buildPack string
+					By("reading the build pack of the quickstart", func() {
+						buildPack, err = imports.ReadBuildPack(imports.Dir(filepath.Join(T.WorkDir, T.GetApplicationName())))
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+
+					By("failing the build lifecycle", func() {
+						broken := pipelines.NewConfig(buildPack)
+						broken.PullRequest().Fail(pipelines.Build)
+						pr := createPullRequestWithPipeline(&T, provider, "Failing pipeline", broken)
+
+						T.WaitForPullRequestCommitStatus(provider, pr, []string{defaultContext}, "failure")
+					})
+
+					By("retrying a build which only fails the first time", func() {
+						flaky := pipelines.NewConfig(buildPack)
+						flaky.PullRequest().FailBuildsBefore(pipelines.Build, 2)
+						pr := createPullRequestWithPipeline(&T, provider, "Flaky pipeline", flaky)
+
+						T.WaitForPullRequestCommitStatus(provider, pr, []string{defaultContext}, "failure")
+
+						// '/retest' needs to be done by a user other than the bot
+						err = approverProvider.AddPRComment(pr, "/retest")
+						Expect(err).ShouldNot(HaveOccurred())
+
+						// Wait until we see a pending or running status, meaning we've got a new build
+						T.WaitForPullRequestCommitStatus(provider, pr, []string{defaultContext}, "pending", "running", "in-progress")
+
+						T.WaitForPullRequestCommitStatus(provider, pr, []string{defaultContext}, "success")
+					})
+
+					By(fmt.Sprintf("delaying the build by %s", pipelineDelay), func() {
+						slow := pipelines.NewConfig(buildPack).Env(pipelineEnvVar, T.ApplicationName)
+						slow.PullRequest().Delay(pipelines.Build, pipelineDelay).ExpectEnv(pipelines.Build, pipelineEnvVar, T.ApplicationName)
+						pr := createPullRequestWithPipeline(&T, provider, "Slow pipeline", slow)
+						started := time.Now()
+
+						T.WaitForPullRequestCommitStatus(provider, pr, []string{defaultContext}, "pending", "running", "in-progress")
+						T.WaitForPullRequestCommitStatus(provider, pr, []string{defaultContext}, "success")
+						Expect(time.Since(started)).Should(BeNumerically(">=", pipelineDelay), "the build succeeded before its delay was over")
+					})
+
+					By(fmt.Sprintf("exceeding a pipeline timeout of %s", pipelineTimeout), func() {
+						timedOut := pipelines.NewConfig(buildPack)
+						timedOut.PullRequest().ExceedTimeout(pipelineTimeout)
+						pr := createPullRequestWithPipeline(&T, provider, "Timed out pipeline", timedOut)
+
+						T.WaitForPullRequestCommitStatus(provider, pr, []string{defaultContext}, "failure", "error")
+						err = T.ExpectPipelineRunTimedOut(fmt.Sprintf("PR-%d", *pr.Number), "")
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+				})
+			})
+		}
+	})
+}
+
+// createPullRequestWithPipeline creates a pull request from the latest master which replaces the jenkins-x.yml with
+// the given pipeline configuration
+func createPullRequestWithPipeline(t *helpers.TestOptions, provider gits.GitProvider, title string, c *pipelines.Config) *gits.GitPullRequest {
+	createdPR := createPullRequestFromMasterWithChange(t, title, func(workDir string) []string {
+		fileName, err := c.WriteTo(workDir)
+		Expect(err).ShouldNot(HaveOccurred())
+		return []string{fileName}
+	})
+
+	pr, err := t.GetPullRequestByNumber(provider, createdPR.Owner, createdPR.Repository, createdPR.PullRequestNumber)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(pr).ShouldNot(BeNil())
+	return pr
+}
@@ -0,0 +1,92 @@
+package lighthouse
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/helpers"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx/v2/pkg/gits"
+	"github.com/jenkins-x/jx/v2/pkg/util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = WebhookTests()
+
+// WebhookTests verifies that the actions taken on a repository deliver the webhooks Lighthouse relies on.
+func WebhookTests() bool {
+	return Describe("Lighthouse webhooks", func() {
+		var (
+			T        helpers.TestOptions
+			err      error
+			provider gits.GitProvider
+			dialect  *helpers.ProviderDialect
+		)
+
+		BeforeEach(func() {
+			provider, err = T.GetGitProvider()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(provider).ShouldNot(BeNil())
+
+			dialect = helpers.DialectForProvider(provider)
+
+			T = newTestOptions("-hooks")
+		})
+
+		AfterEach(func() {
+			deleteQuickstart(&T)
+		})
+
+		Describe("Acting on a pull request", func() {
+			It("delivers a webhook for each action", func() {
+				createQuickstartWithApproverInOwners(&T, provider, dialect)
+
+				verifier, err := T.NewWebhookVerifier(provider, T.GetGitOrganisation(), T.GetApplicationName())
+				Expect(err).ShouldNot(HaveOccurred())
+				defer verifier.Close()
+
+				var pr *gits.GitPullRequest
+				By("opening a pull request", func() {
+					since := time.Now()
+					pr = createPullRequestFromMaster(&T, provider, "Webhook PR", "webhook.txt")
+					err = verifier.ExpectWebhook(scm.WebhookKindPullRequest, since)
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+
+				By("commenting on the pull request", func() {
+					since := time.Now()
+					err = provider.AddPRComment(pr, "Checking webhooks")
+					Expect(err).ShouldNot(HaveOccurred())
+					err = verifier.ExpectWebhook(dialect.PullRequestCommentWebhook, since)
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+
+				if dialect.SupportsLabelsAPI {
+					By("labelling the pull request", func() {
+						since := time.Now()
+						err = provider.AddLabelsToIssue(pr.Owner, pr.Repo, *pr.Number, []string{"webhook-test"})
+						Expect(err).ShouldNot(HaveOccurred())
+						err = verifier.ExpectWebhook(scm.WebhookKindPullRequest, since)
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+				}
+
+				By("pushing a commit to the pull request branch", func() {
+					workDir := filepath.Join(T.WorkDir, T.GetApplicationName())
+					fileName := "webhook.txt"
+					err = ioutil.WriteFile(filepath.Join(workDir, fileName), []byte("Pushed again\n"), util.DefaultWritePermissions)
+					Expect(err).ShouldNot(HaveOccurred())
+					T.ExpectCommandExecution(workDir, time.Minute, 0, "git", "commit", "-a", "-m", "Pushing again")
+
+					since := time.Now()
+					T.ExpectCommandExecution(workDir, time.Minute, 0, "git", "push")
+					err = verifier.ExpectWebhook(scm.WebhookKindPush, since)
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+			})
+		})
+	})
+}
@@ -2,30 +2,31 @@ package quickstart
 
 import (
 	"fmt"
-	"os"
 	"os/exec"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/jenkins-x/bdd-jx/test/helpers"
 
 	"github.com/jenkins-x/bdd-jx/test/utils"
-	"github.com/jenkins-x/jx/v2/pkg/util"
+	"github.com/jenkins-x/bdd-jx/test/utils/scenarios"
 
 	. "github.com/onsi/ginkgo"
-	. "github.com/onsi/gomega"
 )
 
 var (
-	IncludedQuickstarts = []string{"node-http", "spring-boot-http-gradle", "golang-http"}
-	_                   = AllQuickstartsTest()
+	// ScenarioDir is the directory of the scenario files describing the quickstarts to test
+	ScenarioDir = utils.GetEnv("BDD_QUICKSTART_SCENARIO_DIR", "scenarios")
+	// invalidParameterQuickstarts are the quickstarts the invalid parameter tests have been created for, as several
+	// scenarios may create the same quickstart
+	invalidParameterQuickstarts = map[string]bool{}
+	_                           = AllQuickstartsTest()
 )
 
-// AllQuickstartsTest is responsible for running `jx get quickstarts, and creating a test for each quickstart currnetly
-// available
+// AllQuickstartsTest is responsible for running `jx get quickstarts, and creating a test for each quickstart scenario
+// file in ScenarioDir
 // Individual tests can be run with `go test test/quickstart -ginkgo.focus <quickstart name>`
 func AllQuickstartsTest() []bool {
 	cmd := exec.Command("jx", "get", "quickstarts")
@@ -33,101 +34,59 @@ func AllQuickstartsTest() []bool {
 	if err != nil {
 		panic(errors.Wrapf(err, "running jx get quickstarts, output was %s", string(bytes)))
 	}
+	loaded, err := scenarios.LoadDir(ScenarioDir)
 	if err != nil {
 		panic(errors.WithStack(err))
 	}
 	tests := make([]bool, 0)
-	for _, testQuickstartName := range IncludedQuickstarts {
-		tests = append(tests, CreateQuickstartsTests(testQuickstartName))
+	for _, scenario := range loaded {
+		tests = append(tests, helpers.ScenarioTests(scenario))
+		if scenario.SourceKind() == scenarios.SourceQuickstart {
+			tests = append(tests, createInvalidParameterTestsOnce(scenario.Source.Quickstart))
+		}
 	}
 	return tests
 }
 
-//CreateQuickstartsTests creates a batch quickstart test for the given quickstart
+// CreateQuickstartsTests creates a batch quickstart test for the given quickstart
 func CreateQuickstartsTests(quickstartName string) bool {
 	return createQuickstartTests(quickstartName)
 }
 
 // CreateQuickstartTest Creates quickstart tests.
 func createQuickstartTests(quickstartName string) bool {
+	scenario := &scenarios.Scenario{
+		Name:         quickstartName,
+		Source:       scenarios.Source{Quickstart: quickstartName},
+		Environments: []scenarios.Environment{{Name: "staging"}},
+		PullRequest:  &scenarios.PullRequest{},
+	}
+	return helpers.ScenarioTests(scenario) && createInvalidParameterTestsOnce(quickstartName)
+}
+
+// createInvalidParameterTestsOnce creates the invalid parameter tests of the quickstart unless they have already been
+// created
+func createInvalidParameterTestsOnce(quickstartName string) bool {
+	if invalidParameterQuickstarts[quickstartName] {
+		return true
+	}
+	invalidParameterQuickstarts[quickstartName] = true
+	return createInvalidParameterTests(quickstartName)
+}
+
+// createInvalidParameterTests creates the tests which run jx create quickstart with invalid parameters
+func createInvalidParameterTests(quickstartName string) bool {
 	return Describe("quickstart "+quickstartName+"\n", func() {
 		var T helpers.TestOptions
 
 		BeforeEach(func() {
-			qsNameParts := strings.Split(quickstartName, "-")
-			qsAbbr := ""
-			for s := range qsNameParts {
-				qsAbbr = qsAbbr + qsNameParts[s][:1]
-
-			}
-			applicationName := helpers.TempDirPrefix + qsAbbr + "-" + strconv.FormatInt(GinkgoRandomSeed(), 10)
 			T = helpers.TestOptions{
-				ApplicationName: applicationName,
+				ApplicationName: helpers.TempDirPrefix + "invalid-" + strconv.FormatInt(GinkgoRandomSeed(), 10),
 				WorkDir:         helpers.WorkDir,
 			}
 			T.GitProviderURL()
-
-			utils.LogInfof("Creating application %s in dir %s\n", util.ColorInfo(applicationName), util.ColorInfo(helpers.WorkDir))
 		})
 
-		Describe("Create a quickstart", func() {
-			Context(fmt.Sprintf("by running jx create quickstart %s", quickstartName), func() {
-				It("creates a new source repository and promotes it to staging", func() {
-					args := []string{"create", "quickstart", "-b", "--org", T.GetGitOrganisation(), "-p", T.ApplicationName, "-f", quickstartName}
-
-					gitProviderUrl, err := T.GitProviderURL()
-					Expect(err).NotTo(HaveOccurred())
-					if gitProviderUrl != "" {
-						utils.LogInfof("Using Git provider URL %s\n", gitProviderUrl)
-						args = append(args, "--git-provider-url", gitProviderUrl)
-					}
-					argsStr := strings.Join(args, " ")
-					By(fmt.Sprintf("calling jx %s", argsStr), func() {
-						T.ExpectJxExecution(T.WorkDir, helpers.TimeoutSessionWait, 0, args...)
-					})
-
-					applicationName := T.GetApplicationName()
-					owner := T.GetGitOrganisation()
-					jobName := owner + "/" + applicationName + "/master"
-
-					if T.WaitForFirstRelease() {
-						//FIXME Need to wait a little here to ensure that the build has started before asking for the log as the jx create quickstart command returns slightly before the build log is available
-						time.Sleep(30 * time.Second)
-						By(fmt.Sprintf("waiting for the first release of %s", applicationName), func() {
-							T.ThereShouldBeAJobThatCompletesSuccessfully(jobName, helpers.TimeoutBuildCompletes)
-							T.TheApplicationIsRunningInStaging(200)
-						})
-
-						if T.TestPullRequest() {
-							By("performing a pull request on the source and asserting that a preview environment is created", func() {
-								T.CreatePullRequestAndGetPreviewEnvironment(200)
-							})
-						}
-					} else {
-						By(fmt.Sprintf("waiting for the first successful build of master of %s", applicationName), func() {
-							T.ThereShouldBeAJobThatCompletesSuccessfully(jobName, helpers.TimeoutBuildCompletes)
-						})
-					}
-
-					if T.DeleteApplications() {
-						args = []string{"delete", "application", "-b", T.ApplicationName}
-						argsStr := strings.Join(args, " ")
-						By(fmt.Sprintf("calling %s to delete the application", argsStr), func() {
-							T.ExpectJxExecution(T.WorkDir, helpers.TimeoutSessionWait, 0, args...)
-						})
-					}
-
-					if T.DeleteRepos() {
-						args = []string{"delete", "repo", "-b", "--github", "-o", T.GetGitOrganisation(), "-n", T.ApplicationName}
-						argsStr = strings.Join(args, " ")
-
-						By(fmt.Sprintf("calling %s to delete the repository", os.Args), func() {
-							T.ExpectJxExecution(T.WorkDir, helpers.TimeoutSessionWait, 0, args...)
-						})
-					}
-				})
-			})
-		})
 		Describe("Create a quickstart with invalid parameters", func() {
 			Context("when -p param (project name) is missing", func() {
 				It("exits with signal 1", func() {
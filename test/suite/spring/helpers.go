@@ -1,7 +0,0 @@
-package spring
-
-import "github.com/jenkins-x/bdd-jx/test/helpers"
-
-type SpringTestOptions struct {
-	helpers.TestOptions
-}
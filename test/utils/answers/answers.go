@@ -0,0 +1,228 @@
+package answers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// envPrefix is the prefix of the environment variables jx answers the questions of a values.schema.json from in
+	// batch mode
+	envPrefix = "JX_VALUE_"
+	// passthroughSuffix may follow the format of a property to pass its value through to the values unchanged
+	passthroughSuffix = "-passthrough"
+)
+
+// secretFormats are the formats of the properties in a values.schema.json whose answers jx stores as secrets
+var secretFormats = []string{"password", "token"}
+
+// Answers are the answers to the questions the values.schema.json of an app asks, keyed by property name, with
+// nested answers for the properties of objects
+type Answers map[string]interface{}
+
+// Load loads an answers file
+func Load(path string) (Answers, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading answers file %s", path)
+	}
+	answers, err := Parse(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "answers file %s", path)
+	}
+	return answers, nil
+}
+
+// Parse parses the YAML of an answers file
+func Parse(data []byte) (Answers, error) {
+	answers := Answers{}
+	err := yaml.Unmarshal(data, &answers)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing answers")
+	}
+	return answers, nil
+}
+
+// Set sets the answer to the property with the given dotted path, such as db.password
+func (a Answers) Set(path string, value interface{}) {
+	names := strings.Split(path, ".")
+	current := map[string]interface{}(a)
+	for _, name := range names[:len(names)-1] {
+		next, ok := current[name].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			current[name] = next
+		}
+		current = next
+	}
+	current[names[len(names)-1]] = value
+}
+
+// Get returns the answer to the property with the given dotted path as a string
+func (a Answers) Get(path string) (string, bool) {
+	return Lookup(a, path)
+}
+
+// Env returns the environment variables jx answers the questions from in batch mode, such as JX_VALUE_DB_PASSWORD
+// for db.password, in order. jx only reads them for properties with no default and no existing value.
+func (a Answers) Env() []string {
+	var env []string
+	addEnv(&env, nil, a)
+	sort.Strings(env)
+	return env
+}
+
+func addEnv(env *[]string, prefixes []string, values map[string]interface{}) {
+	for name, value := range values {
+		path := append(append([]string{}, prefixes...), name)
+		if nested, ok := value.(map[string]interface{}); ok {
+			addEnv(env, path, nested)
+			continue
+		}
+		*env = append(*env, fmt.Sprintf("%s%s=%v", envPrefix, strings.ToUpper(strings.Join(path, "_")), value))
+	}
+}
+
+// Lookup returns the value with the given dotted path in the given values, such as those of a values.yaml, as a
+// string
+func Lookup(values map[string]interface{}, path string) (string, bool) {
+	names := strings.Split(path, ".")
+	current := values
+	for _, name := range names[:len(names)-1] {
+		next, ok := current[name].(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		current = next
+	}
+	value, ok := current[names[len(names)-1]]
+	if !ok || value == nil {
+		return "", false
+	}
+	return fmt.Sprintf("%v", value), true
+}
+
+// property is a property of a values.schema.json
+type property struct {
+	Type       string               `json:"type"`
+	Format     string               `json:"format"`
+	Properties map[string]*property `json:"properties"`
+}
+
+// SecretProperties returns the dotted paths of the properties in a values.schema.json which jx treats as secrets,
+// those with the password or token format, in order
+func SecretProperties(schema []byte) ([]string, error) {
+	root := property{}
+	err := json.Unmarshal(schema, &root)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing values.schema.json")
+	}
+	var paths []string
+	addSecretProperties(&paths, "", &root)
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func addSecretProperties(paths *[]string, prefix string, p *property) {
+	for name, child := range p.Properties {
+		if child == nil {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		format := strings.TrimSuffix(child.Format, passthroughSuffix)
+		for _, f := range secretFormats {
+			if format == f {
+				*paths = append(*paths, path)
+			}
+		}
+		addSecretProperties(paths, path, child)
+	}
+}
+
+// IsSecretReference returns true if the value is a reference to a secret in a secret store, such as
+// vault:gitOps/org/repo:password, rather than the secret itself. jx add app always writes vault references, whereas
+// local references such as local:gitOps/org/repo:password are written by jx step create values when the secrets are
+// stored on the file system.
+func IsSecretReference(value string) bool {
+	return strings.HasPrefix(value, "vault:") || strings.HasPrefix(value, "local:")
+}
+
+// ExpectNoSecrets returns an error if any of the given files, keyed by path, contains any of the given secrets, keyed
+// by the path of the property they answer
+func ExpectNoSecrets(files map[string]string, secrets map[string]string) error {
+	for _, file := range sortedKeys(files) {
+		for _, property := range sortedKeys(secrets) {
+			if strings.Contains(files[file], secrets[property]) {
+				return errors.Errorf("the secret answer to %s is in %s", property, file)
+			}
+		}
+	}
+	return nil
+}
+
+// ExpectValues returns an error unless the YAML values, such as a values.yaml, have the expected values, keyed by
+// dotted path, and contain none of the given secrets, keyed by the path of the property they answer
+func ExpectValues(data []byte, expected map[string]string, secrets map[string]string) error {
+	values, err := parseValues(data)
+	if err != nil {
+		return err
+	}
+	for _, path := range sortedKeys(expected) {
+		actual, ok := Lookup(values, path)
+		if !ok || actual != expected[path] {
+			return errors.Errorf("expected value %s=%s but found %q", path, expected[path], actual)
+		}
+	}
+	for _, path := range sortedKeys(secrets) {
+		if actual, ok := Lookup(values, path); ok && actual == secrets[path] {
+			return errors.Errorf("the secret answer to %s is in the values rather than a reference to it", path)
+		}
+	}
+	return ExpectNoSecrets(map[string]string{"values": string(data)}, secrets)
+}
+
+// ExpectSecretReferences returns an error unless each of the given secrets, keyed by the path of the property they
+// answer, is referenced from a secret store in the YAML values
+func ExpectSecretReferences(data []byte, secrets map[string]string) error {
+	values, err := parseValues(data)
+	if err != nil {
+		return err
+	}
+	for _, path := range sortedKeys(secrets) {
+		actual, ok := Lookup(values, path)
+		if !ok {
+			return errors.Errorf("expected %s to be a reference to a secret but found none", path)
+		}
+		if !IsSecretReference(actual) {
+			return errors.Errorf("expected %s to be a reference to a secret but found %q", path, actual)
+		}
+	}
+	return nil
+}
+
+func parseValues(data []byte) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	err := yaml.Unmarshal(data, &values)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing values")
+	}
+	return values, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
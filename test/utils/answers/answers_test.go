@@ -0,0 +1,129 @@
+package answers_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/answers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const schema = `{
+  "type": "object",
+  "properties": {
+    "greeting": {"type": "string"},
+    "apiToken": {"type": "string", "format": "token-passthrough"},
+    "db": {
+      "type": "object",
+      "properties": {
+        "user": {"type": "string"},
+        "password": {"type": "string", "format": "password"}
+      }
+    }
+  }
+}`
+
+func TestSecretProperties(t *testing.T) {
+	paths, err := answers.SecretProperties([]byte(schema))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"apiToken", "db.password"}, paths)
+
+	_, err = answers.SecretProperties([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestEnv(t *testing.T) {
+	a, err := answers.Parse([]byte(`greeting: hello
+db:
+  user: bdd
+replicas: 2
+`))
+	require.NoError(t, err)
+	a.Set("db.password", "s3cret")
+	a.Set("cache.size", 10)
+	assert.Equal(t, []string{
+		"JX_VALUE_CACHE_SIZE=10",
+		"JX_VALUE_DB_PASSWORD=s3cret",
+		"JX_VALUE_DB_USER=bdd",
+		"JX_VALUE_GREETING=hello",
+		"JX_VALUE_REPLICAS=2",
+	}, a.Env())
+
+	value, ok := a.Get("db.password")
+	assert.True(t, ok)
+	assert.Equal(t, "s3cret", value)
+	_, ok = a.Get("db.host")
+	assert.False(t, ok)
+}
+
+func TestLoad(t *testing.T) {
+	a, err := answers.Load("testdata/answers.yaml")
+	require.NoError(t, err)
+	value, ok := a.Get("greeting")
+	assert.True(t, ok)
+	assert.Equal(t, "hello-from-answers", value)
+
+	_, err = answers.Load("testdata/missing.yaml")
+	assert.Error(t, err)
+}
+
+func TestLookup(t *testing.T) {
+	values := map[string]interface{}{
+		"replicas": 2,
+		"db":       map[string]interface{}{"password": "vault:gitOps/org/env:db-password"},
+	}
+	value, ok := answers.Lookup(values, "replicas")
+	assert.True(t, ok)
+	assert.Equal(t, "2", value)
+	value, ok = answers.Lookup(values, "db.password")
+	assert.True(t, ok)
+	assert.True(t, answers.IsSecretReference(value))
+	assert.True(t, answers.IsSecretReference("local:gitOps/org/env:db-password"))
+	assert.False(t, answers.IsSecretReference("s3cret"))
+	_, ok = answers.Lookup(values, "replicas.count")
+	assert.False(t, ok)
+}
+
+func TestExpectNoSecrets(t *testing.T) {
+	secrets := map[string]string{"password": "bdd-s3cret"}
+	assert.NoError(t, answers.ExpectNoSecrets(map[string]string{
+		"env/requirements.yaml":          "dependencies: []\n",
+		"env/bdd-values-app/values.yaml": "password: vault:gitOps/org/env:password\n",
+	}, secrets))
+	assert.EqualError(t, answers.ExpectNoSecrets(map[string]string{
+		"env/bdd-values-app/values.yaml": "password: bdd-s3cret\n",
+	}, secrets), "the secret answer to password is in env/bdd-values-app/values.yaml")
+}
+
+func TestExpectValues(t *testing.T) {
+	expected := map[string]string{"greeting": "hello-from-answers", "farewell": "goodbye-from-values-file"}
+	secrets := map[string]string{"password": "bdd-s3cret"}
+
+	referenced := []byte(`farewell: goodbye-from-values-file
+greeting: hello-from-answers
+password: vault:gitOps/org/env:password
+`)
+	assert.NoError(t, answers.ExpectValues(referenced, expected, secrets))
+
+	omitted := []byte(`farewell: goodbye-from-values-file
+greeting: hello-from-answers
+`)
+	assert.NoError(t, answers.ExpectValues(omitted, expected, secrets))
+
+	leaked := []byte(`farewell: goodbye-from-values-file
+greeting: hello-from-answers
+password: bdd-s3cret
+`)
+	assert.EqualError(t, answers.ExpectValues(leaked, expected, secrets), "the secret answer to password is in the values rather than a reference to it")
+
+	assert.EqualError(t, answers.ExpectValues([]byte("greeting: hello\n"), expected, nil), `expected value farewell=goodbye-from-values-file but found ""`)
+}
+
+func TestExpectSecretReferences(t *testing.T) {
+	secrets := map[string]string{"password": "bdd-s3cret"}
+
+	assert.NoError(t, answers.ExpectSecretReferences([]byte("password: vault:gitOps/org/env:password\n"), secrets))
+	assert.NoError(t, answers.ExpectSecretReferences([]byte("password: local:gitOps/org/env:password\n"), secrets))
+	assert.EqualError(t, answers.ExpectSecretReferences([]byte("greeting: hello\n"), secrets), "expected password to be a reference to a secret but found none")
+	assert.EqualError(t, answers.ExpectSecretReferences([]byte("password: bdd-s3cret\n"), secrets), `expected password to be a reference to a secret but found "bdd-s3cret"`)
+}
@@ -0,0 +1,259 @@
+package buildlogs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ansiRegex        = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+	showingLogsRegex = regexp.MustCompile(`^Showing logs for build (.+) stage (\S+) and container (\S+)$`)
+	stepFailedRegex  = regexp.MustCompile(`^Pipeline failed on stage '([^']*)' : container '([^']*)'`)
+
+	// DefaultForbidden are the patterns no build log may match unless an expectation lists its own forbidden patterns:
+	// Go panics and Java stack traces
+	DefaultForbidden = []string{
+		`^panic: `,
+		`^goroutine \d+ \[running\]:`,
+		`^Exception in thread "`,
+		`^\s+at [\w$.]+\([\w$]+\.java:\d+\)$`,
+	}
+
+	// PermissionDenied is the pattern forbidden by expectations which set ForbidPermissionDenied. It is not one of the
+	// DefaultForbidden patterns as plenty of tools print permission errors they recover from.
+	PermissionDenied = `(?i)permission denied`
+)
+
+// Log is the log of a build as printed by jx get build logs, split into the steps of its stages
+type Log struct {
+	// Job is the name of the job, such as myorg/myapp/master
+	Job string
+	// Build is the number of the build of the job
+	Build int
+	// Preamble holds the lines printed before the log of the first step
+	Preamble []string
+	// Steps holds the log of each step in the order they ran
+	Steps []*Step
+}
+
+// Step is the log of a single step of a stage of a build
+type Step struct {
+	Stage     string
+	Container string
+	Lines     []string
+	// Failed is true if jx reported that the pipeline failed on this step
+	Failed bool
+}
+
+// Expectation is what a build log is expected to contain
+type Expectation struct {
+	// Succeeded are the names of steps which must have run without failing, either as a container name such as
+	// step-build-make-linux or as the title jx gives the step such as Build Make Linux
+	Succeeded []string `json:"succeeded,omitempty"`
+	// Failed are the names of steps which must have failed
+	Failed []string `json:"failed,omitempty"`
+	// Lines are regular expressions which must each match a line of the log
+	Lines []string `json:"lines,omitempty"`
+	// Forbidden are regular expressions which no line of the log may match, defaulting to DefaultForbidden
+	Forbidden []string `json:"forbidden,omitempty"`
+	// ForbidPermissionDenied forbids lines matching PermissionDenied as well as the Forbidden patterns
+	ForbidPermissionDenied bool `json:"forbidPermissionDenied,omitempty"`
+}
+
+// Parse parses the output of jx get build logs for the given build of the job
+func Parse(job string, build int, output string) *Log {
+	log := &Log{Job: job, Build: build}
+	var current *Step
+	for _, line := range strings.Split(ansiRegex.ReplaceAllString(output, ""), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := showingLogsRegex.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			current = &Step{Stage: m[2], Container: m[3]}
+			log.Steps = append(log.Steps, current)
+			continue
+		}
+		if m := stepFailedRegex.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			if step := log.find(m[1], m[2]); step != nil {
+				step.Failed = true
+			}
+			continue
+		}
+		if current == nil {
+			if strings.TrimSpace(line) != "" {
+				log.Preamble = append(log.Preamble, line)
+			}
+			continue
+		}
+		current.Lines = append(current.Lines, line)
+	}
+	for _, step := range log.Steps {
+		step.Lines = trimBlankLines(step.Lines)
+	}
+	return log
+}
+
+// Stages returns the names of the stages of the build in the order they ran
+func (l *Log) Stages() []string {
+	var answer []string
+	seen := map[string]bool{}
+	for _, step := range l.Steps {
+		if !seen[step.Stage] {
+			seen[step.Stage] = true
+			answer = append(answer, step.Stage)
+		}
+	}
+	return answer
+}
+
+// Step returns the step with the given container name or title, or nil if there is none
+func (l *Log) Step(name string) *Step {
+	key := StepKey(name)
+	for _, step := range l.Steps {
+		if StepKey(step.Container) == key {
+			return step
+		}
+	}
+	return nil
+}
+
+// FailedSteps returns the steps jx reported the pipeline failed on
+func (l *Log) FailedSteps() []*Step {
+	var answer []*Step
+	for _, step := range l.Steps {
+		if step.Failed {
+			answer = append(answer, step)
+		}
+	}
+	return answer
+}
+
+// Matching returns the lines of the log matching the regular expression
+func (l *Log) Matching(regex *regexp.Regexp) []string {
+	var answer []string
+	for _, line := range l.lines() {
+		if regex.MatchString(line) {
+			answer = append(answer, line)
+		}
+	}
+	return answer
+}
+
+// String returns the log with a header for each step, as it would be attached to a report
+func (l *Log) String() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "build %d of %s\n", l.Build, l.Job)
+	for _, line := range l.Preamble {
+		b.WriteString(line + "\n")
+	}
+	for _, step := range l.Steps {
+		status := ""
+		if step.Failed {
+			status = " FAILED"
+		}
+		fmt.Fprintf(&b, "\n=== stage %s step %s%s\n", step.Stage, step.Container, status)
+		for _, line := range step.Lines {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// Verify returns an error listing everything in the log which does not meet the expectation
+func (l *Log) Verify(expect *Expectation) error {
+	if expect == nil {
+		expect = &Expectation{}
+	}
+	var problems []string
+	for _, name := range expect.Succeeded {
+		step := l.Step(name)
+		if step == nil {
+			problems = append(problems, fmt.Sprintf("step %s did not run", name))
+		} else if step.Failed {
+			problems = append(problems, fmt.Sprintf("step %s failed", name))
+		}
+	}
+	for _, name := range expect.Failed {
+		step := l.Step(name)
+		if step == nil {
+			problems = append(problems, fmt.Sprintf("step %s did not run", name))
+		} else if !step.Failed {
+			problems = append(problems, fmt.Sprintf("step %s did not fail", name))
+		}
+	}
+	for _, pattern := range expect.Lines {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return errors.Wrapf(err, "invalid expected line %s", pattern)
+		}
+		if len(l.Matching(regex)) == 0 {
+			problems = append(problems, fmt.Sprintf("no line matches %s", pattern))
+		}
+	}
+	forbidden := expect.Forbidden
+	if forbidden == nil {
+		forbidden = DefaultForbidden
+	}
+	if expect.ForbidPermissionDenied {
+		forbidden = append(append([]string{}, forbidden...), PermissionDenied)
+	}
+	for _, pattern := range forbidden {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return errors.Wrapf(err, "invalid forbidden pattern %s", pattern)
+		}
+		if matches := l.Matching(regex); len(matches) > 0 {
+			problems = append(problems, fmt.Sprintf("%d line(s) match forbidden pattern %s, the first being: %s", len(matches), pattern, strings.TrimSpace(matches[0])))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("build %d of %s:\n%s", l.Build, l.Job, strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// Validate returns an error if any of the patterns of the expectation is not a valid regular expression
+func (e *Expectation) Validate() error {
+	for _, pattern := range append(append([]string{}, e.Lines...), e.Forbidden...) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return errors.Wrapf(err, "invalid pattern %s", pattern)
+		}
+	}
+	return nil
+}
+
+// StepKey returns the key steps are matched by, so that the container step-build-make-linux matches the title
+// Build Make Linux
+func StepKey(name string) string {
+	key := strings.ToLower(strings.TrimSpace(name))
+	key = strings.NewReplacer(" ", "-", "_", "-").Replace(key)
+	return strings.TrimPrefix(key, "step-")
+}
+
+func (l *Log) find(stage string, container string) *Step {
+	for i := len(l.Steps) - 1; i >= 0; i-- {
+		if l.Steps[i].Stage == stage && l.Steps[i].Container == container {
+			return l.Steps[i]
+		}
+	}
+	return nil
+}
+
+func (l *Log) lines() []string {
+	answer := append([]string{}, l.Preamble...)
+	for _, step := range l.Steps {
+		answer = append(answer, step.Lines...)
+	}
+	return answer
+}
+
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,134 @@
+package buildlogs_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/buildlogs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func load(t *testing.T, name string, job string, build int) *buildlogs.Log {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return buildlogs.Parse(job, build, string(data))
+}
+
+func TestParse(t *testing.T) {
+	log := load(t, "release.log", "myorg/myapp/master", 2)
+
+	assert.Equal(t, []string{"Build logs for myorg/myapp/master #2 release"}, log.Preamble)
+	assert.Equal(t, []string{"from-build-pack"}, log.Stages())
+	require.Len(t, log.Steps, 6)
+
+	step := log.Step("Build Make Linux")
+	require.NotNil(t, step)
+	assert.Equal(t, "step-build-make-linux", step.Container)
+	assert.Equal(t, []string{"CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -ldflags '' -o bin/myapp main.go"}, step.Lines)
+	assert.False(t, step.Failed)
+
+	assert.Empty(t, log.Step("step-setup-jx-git-credentials").Lines)
+	assert.Nil(t, log.Step("build-make-test"))
+	assert.Empty(t, log.FailedSteps())
+	assert.Equal(t, []string{"Tag v0.0.2 created and pushed to remote origin"}, log.Matching(regexp.MustCompile(`^Tag v\d+\.\d+\.\d+ created`)))
+}
+
+func TestParseFailedStep(t *testing.T) {
+	log := load(t, "failed.log", "myorg/myapp/PR-1", 1)
+
+	failed := log.FailedSteps()
+	require.Len(t, failed, 1)
+	assert.Equal(t, "step-build-bdd-fail", failed[0].Container)
+	assert.Equal(t, []string{"mkdir: cannot create directory '/workspace/out': Permission denied"}, failed[0].Lines)
+	assert.False(t, log.Step("git-merge").Failed)
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		expect *buildlogs.Expectation
+		err    string
+	}{
+		{
+			name: "release",
+			file: "release.log",
+			expect: &buildlogs.Expectation{
+				Succeeded: []string{"Build Make Linux", "step-promote-jx-promote"},
+				Lines:     []string{`^Tag v0\.0\.2 created`, `Promoting app myapp version 0\.0\.2`},
+			},
+		},
+		{
+			name: "no expectation",
+			file: "release.log",
+		},
+		{
+			name:   "missing step and line",
+			file:   "release.log",
+			expect: &buildlogs.Expectation{Succeeded: []string{"Build Make Test"}, Lines: []string{"^Tag v1"}},
+			err:    "build 7 of myorg/myapp/master:\nstep Build Make Test did not run\nno line matches ^Tag v1",
+		},
+		{
+			name:   "failed step",
+			file:   "failed.log",
+			expect: &buildlogs.Expectation{Succeeded: []string{"git-merge", "build-bdd-fail"}},
+			err:    "build 7 of myorg/myapp/master:\nstep build-bdd-fail failed",
+		},
+		{
+			name:   "permission denied",
+			file:   "failed.log",
+			expect: &buildlogs.Expectation{Failed: []string{"Build Bdd Fail"}, ForbidPermissionDenied: true},
+			err:    "build 7 of myorg/myapp/master:\n1 line(s) match forbidden pattern (?i)permission denied, the first being: mkdir: cannot create directory '/workspace/out': Permission denied",
+		},
+		{
+			name:   "expected failure with own forbidden patterns",
+			file:   "failed.log",
+			expect: &buildlogs.Expectation{Failed: []string{"Build Bdd Fail"}, Forbidden: []string{}},
+		},
+		{
+			name:   "stack traces",
+			file:   "panic.log",
+			expect: &buildlogs.Expectation{Forbidden: []string{`^panic: `, `^Exception in thread "`}},
+			err:    "build 7 of myorg/myapp/master:\n1 line(s) match forbidden pattern ^panic: , the first being: panic: runtime error: invalid memory address or nil pointer dereference\n1 line(s) match forbidden pattern ^Exception in thread \", the first being: Exception in thread \"main\" java.lang.IllegalStateException: boom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := load(t, tt.file, "myorg/myapp/master", 7).Verify(tt.expect)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDefaultForbiddenMatchesStackTraces(t *testing.T) {
+	err := load(t, "panic.log", "myorg/myapp/master", 3).Verify(nil)
+	require.Error(t, err)
+	for _, pattern := range buildlogs.DefaultForbidden {
+		assert.Contains(t, err.Error(), "forbidden pattern "+pattern)
+	}
+}
+
+func TestString(t *testing.T) {
+	log := load(t, "failed.log", "myorg/myapp/PR-1", 1)
+	assert.Equal(t, `build 1 of myorg/myapp/PR-1
+Build logs for myorg/myapp/PR-1 #1 pr-build
+
+=== stage from-build-pack step step-git-merge
+Merged SHA 3e1ac1a4a1e0e5e2d with master
+
+=== stage from-build-pack step step-build-bdd-fail FAILED
+mkdir: cannot create directory '/workspace/out': Permission denied
+`, log.String())
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, (&buildlogs.Expectation{Lines: []string{"^ok$"}}).Validate())
+	assert.Error(t, (&buildlogs.Expectation{Forbidden: []string{"("}}).Validate())
+}
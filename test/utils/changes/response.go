@@ -0,0 +1,286 @@
+package changes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// LanguageGo is an application written in Go
+	LanguageGo = "go"
+	// LanguageNode is an application written in JavaScript or TypeScript
+	LanguageNode = "node"
+	// LanguageJava is an application built with Maven or Gradle, such as Spring Boot
+	LanguageJava = "java"
+
+	// DefaultResponseMatch matches the greetings the quickstarts respond with
+	DefaultResponseMatch = "(?i)hello"
+)
+
+var (
+	doubleQuoted = regexp.MustCompile(`"((?:[^"\\\n]|\\.)*)"`)
+	singleQuoted = regexp.MustCompile(`'((?:[^'\\\n]|\\.)*)'`)
+	backQuoted   = regexp.MustCompile("`([^`]*)`")
+	htmlText     = regexp.MustCompile(`>([^<>]+)<`)
+
+	// placeholders are the parts of a string literal which are not returned as they are, such as format verbs, template
+	// expressions and escape sequences
+	placeholders = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z]|\$\{[^}]*\}|\{\{[^}]*\}\}|\{\}|\\.`)
+)
+
+// language describes where the responses of an application and the tests of them are written and how its string
+// literals are quoted
+type language struct {
+	extensions map[string][]*regexp.Regexp
+	skipDirs   []string
+	// testDirs and testSuffixes are where the tests are, which expect the responses of the application
+	testDirs     []string
+	testSuffixes []string
+}
+
+var languages = map[string]language{
+	LanguageGo: {
+		extensions:   map[string][]*regexp.Regexp{".go": {doubleQuoted, backQuoted}},
+		skipDirs:     []string{"vendor", "charts"},
+		testSuffixes: []string{"_test.go"},
+	},
+	LanguageNode: {
+		extensions: map[string][]*regexp.Regexp{
+			".js":   {doubleQuoted, singleQuoted, backQuoted},
+			".ts":   {doubleQuoted, singleQuoted, backQuoted},
+			".html": {htmlText},
+		},
+		skipDirs:     []string{"node_modules", "charts"},
+		testDirs:     []string{"test", "tests"},
+		testSuffixes: []string{".test.js", ".spec.js", ".test.ts", ".spec.ts"},
+	},
+	LanguageJava: {
+		extensions: map[string][]*regexp.Regexp{
+			".java": {doubleQuoted},
+			".kt":   {doubleQuoted},
+			".html": {htmlText},
+		},
+		skipDirs: []string{"target", "build", "charts"},
+		testDirs: []string{"test"},
+	},
+}
+
+// ResponseChange is an edit of a string an application responds with
+type ResponseChange struct {
+	Language string
+	// File is the changed file relative to the application directory
+	File string
+	// Old is the string literal as it was in the source
+	Old string
+	// New is the string literal as it is now in the source
+	New string
+	// Marker is the unique text added to the string literal
+	Marker string
+	// Tests are the test files relative to the application directory whose expected responses were changed to match
+	Tests []string
+}
+
+// DetectLanguage returns the language of the application in the given directory
+func DetectLanguage(dir string) (string, error) {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	switch {
+	case exists("go.mod") || exists("main.go"):
+		return LanguageGo, nil
+	case exists("package.json"):
+		return LanguageNode, nil
+	case exists("pom.xml") || exists("build.gradle") || exists("build.gradle.kts"):
+		return LanguageJava, nil
+	}
+	return "", fmt.Errorf("could not detect the language of the application in %s", dir)
+}
+
+// ChangeResponse adds the marker to the first string literal in the source of the application in the given directory
+// which matches the regular expression, or DefaultResponseMatch if it is empty. Files are searched in lexical order so
+// the same literal is changed each time. The marker is also added to the literals of the tests which expect the
+// response, so the unit tests of the application still pass.
+func ChangeResponse(dir string, marker string, match string) (*ResponseChange, error) {
+	if match == "" {
+		match = DefaultResponseMatch
+	}
+	matchRegex, err := regexp.Compile(match)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid response match %s", match)
+	}
+	name, err := DetectLanguage(dir)
+	if err != nil {
+		return nil, err
+	}
+	lang := languages[name]
+
+	files, err := lang.files(dir, false)
+	if err != nil {
+		return nil, err
+	}
+	var change *ResponseChange
+	for _, file := range files {
+		changed, err := lang.addMarker(dir, file, marker, func(literal string) int {
+			if change != nil || !matchRegex.MatchString(literal) {
+				return -1
+			}
+			change = &ResponseChange{Language: name, File: file, Old: literal, Marker: marker}
+			return len(literal) - len(strings.TrimLeft(literal, " \t\r\n"))
+		})
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			change.New = changed[0]
+			break
+		}
+	}
+	if change == nil {
+		return nil, fmt.Errorf("no %s string literal matching %s found in %s", name, match, dir)
+	}
+
+	tests, err := lang.files(dir, true)
+	if err != nil {
+		return nil, err
+	}
+	oldRegex := regexp.MustCompile(change.OldPattern())
+	for _, file := range tests {
+		changed, err := lang.addMarker(dir, file, marker, func(literal string) int {
+			if loc := oldRegex.FindStringIndex(literal); loc != nil {
+				return loc[0]
+			}
+			return -1
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(changed) > 0 {
+			change.Tests = append(change.Tests, file)
+		}
+	}
+	return change, nil
+}
+
+// addMarker adds the marker to the string literals of the file at the index returned by the position function,
+// leaving those it returns -1 for alone. The changed literals are returned.
+func (l *language) addMarker(dir string, file string, marker string, position func(literal string) int) ([]string, error) {
+	path := filepath.Join(dir, file)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", path)
+	}
+	source := string(data)
+	var answer []string
+	for _, literal := range l.extensions[filepath.Ext(file)] {
+		// the literals after a changed one are shifted by the length of the markers added before them
+		shift := 0
+		for _, loc := range literal.FindAllStringSubmatchIndex(source, -1) {
+			start, end := loc[2]+shift, loc[3]+shift
+			old := source[start:end]
+			idx := position(old)
+			if idx < 0 {
+				continue
+			}
+			changed := old[:idx] + marker + " " + old[idx:]
+			source = source[:start] + changed + source[end:]
+			shift += len(changed) - len(old)
+			answer = append(answer, changed)
+		}
+	}
+	if len(answer) == 0 {
+		return nil, nil
+	}
+	err = ioutil.WriteFile(path, []byte(source), 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "writing %s", path)
+	}
+	return answer, nil
+}
+
+// OldPattern returns a regular expression matching a response containing the string before the change, with format
+// verbs, template expressions and escape sequences matching anything
+func (c *ResponseChange) OldPattern() string {
+	return literalPattern(c.Old)
+}
+
+// NewPattern returns a regular expression matching a response containing the string after the change
+func (c *ResponseChange) NewPattern() string {
+	return regexp.QuoteMeta(c.Marker)
+}
+
+func literalPattern(literal string) string {
+	var parts []string
+	for _, part := range placeholders.Split(literal, -1) {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			parts = append(parts, regexp.QuoteMeta(part))
+		}
+	}
+	return "(?s)" + strings.Join(parts, ".*")
+}
+
+// files returns the source files of the application in lexical order, or those of its tests
+func (l *language) files(dir string, tests bool) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if rel != "." && (strings.HasPrefix(info.Name(), ".") || contains(l.skipDirs, info.Name())) {
+				return filepath.SkipDir
+			}
+			if !tests && contains(l.testDirs, info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := l.extensions[filepath.Ext(path)]; !ok {
+			return nil
+		}
+		if l.isTest(rel) == tests {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "finding source files in %s", dir)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (l *language) isTest(file string) bool {
+	for _, suffix := range l.testSuffixes {
+		if strings.HasSuffix(file, suffix) {
+			return true
+		}
+	}
+	for _, dir := range strings.Split(filepath.Dir(file), string(filepath.Separator)) {
+		if contains(l.testDirs, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
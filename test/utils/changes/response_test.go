@@ -0,0 +1,109 @@
+package changes_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/changes"
+	"github.com/jenkins-x/jx/v2/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const marker = "bdd-pr-abc12"
+
+func copyTestData(t *testing.T, name string) string {
+	dir, err := ioutil.TempDir("", "changes")
+	require.NoError(t, err)
+	err = util.CopyDirOverwrite(filepath.Join("testdata", name), dir)
+	require.NoError(t, err)
+	return dir
+}
+
+func TestChangeResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		language    string
+		file        string
+		old         string
+		oldResponse string
+	}{
+		{name: "go", language: changes.LanguageGo, file: "main.go", old: "Hello from:  ", oldResponse: "Hello from:  golang-http-6c8f\n"},
+		{name: "node", language: changes.LanguageNode, file: "index.js", old: "<h1>Hello from Jenkins X!</h1>", oldResponse: "<h1>Hello from Jenkins X!</h1>"},
+		{name: "java", language: changes.LanguageJava, file: filepath.Join("src", "main", "java", "com", "example", "GreetingController.java"), old: "Hello, %s!", oldResponse: `{"id":1,"content":"Hello, World!"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := copyTestData(t, tt.name)
+			defer os.RemoveAll(dir)
+
+			change, err := changes.ChangeResponse(dir, marker, "")
+			require.NoError(t, err)
+			assert.Equal(t, tt.language, change.Language)
+			assert.Equal(t, tt.file, change.File)
+			assert.Equal(t, tt.old, change.Old)
+			assert.Equal(t, marker+" "+tt.old, change.New)
+
+			data, err := ioutil.ReadFile(filepath.Join(dir, tt.file))
+			require.NoError(t, err)
+			assert.Contains(t, string(data), change.New)
+
+			assert.Regexp(t, regexp.MustCompile(change.OldPattern()), tt.oldResponse)
+			assert.NotRegexp(t, regexp.MustCompile(change.NewPattern()), tt.oldResponse)
+			assert.Regexp(t, regexp.MustCompile(change.NewPattern()), marker+" "+tt.oldResponse)
+		})
+	}
+}
+
+func TestChangeResponseUpdatesTests(t *testing.T) {
+	tests := []struct {
+		name     string
+		tests    []string
+		file     string
+		expected string
+		other    string
+	}{
+		{name: "go", tests: []string{"main_test.go"}, file: "main_test.go", expected: `"` + marker + ` Hello from:  bdd-host\n"`, other: `"Hello from the test"`},
+		{name: "node", file: filepath.Join("test", "index.test.js"), other: "'Hello'"},
+		{name: "java", tests: []string{filepath.Join("src", "test", "java", "com", "example", "GreetingControllerTest.java")}, file: filepath.Join("src", "test", "java", "com", "example", "GreetingControllerTest.java"), expected: `"` + marker + ` Hello, World!"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := copyTestData(t, tt.name)
+			defer os.RemoveAll(dir)
+
+			change, err := changes.ChangeResponse(dir, marker, "")
+			require.NoError(t, err)
+			assert.Equal(t, tt.tests, change.Tests)
+
+			data, err := ioutil.ReadFile(filepath.Join(dir, tt.file))
+			require.NoError(t, err)
+			if tt.expected != "" {
+				assert.Contains(t, string(data), tt.expected)
+			}
+			if tt.other != "" {
+				assert.Contains(t, string(data), tt.other)
+			}
+		})
+	}
+}
+
+func TestChangeResponseWithoutMatch(t *testing.T) {
+	dir := copyTestData(t, "go")
+	defer os.RemoveAll(dir)
+
+	_, err := changes.ChangeResponse(dir, marker, "Goodbye")
+	assert.Error(t, err)
+}
+
+func TestDetectLanguageOfUnknownProject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "changes")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = changes.DetectLanguage(dir)
+	assert.Error(t, err)
+}
@@ -0,0 +1 @@
+module github.com/jenkins-x-quickstarts/golang-http
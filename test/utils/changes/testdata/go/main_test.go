@@ -0,0 +1,6 @@
+package main
+
+const (
+	expected = "Hello from:  bdd-host\n"
+	other    = "Hello from the test"
+)
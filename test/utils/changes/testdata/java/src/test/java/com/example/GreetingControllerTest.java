@@ -0,0 +1,5 @@
+package com.example;
+
+public class GreetingControllerTest {
+    private static final String expected = "Hello, World!";
+}
@@ -0,0 +1 @@
+expect(body).toContain('Hello');
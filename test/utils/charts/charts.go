@@ -0,0 +1,258 @@
+package charts
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ChartFileName is the name of the file describing a chart
+	ChartFileName = "Chart.yaml"
+	// IndexFileName is the name of the index of a chart repository
+	IndexFileName = "index.yaml"
+)
+
+// packagedAt is the modification time of every file in a packaged chart, so that packaging the same chart twice gives
+// the same digest
+var packagedAt = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Metadata is the content of the Chart.yaml of a chart
+type Metadata struct {
+	APIVersion  string `json:"apiVersion,omitempty"`
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	AppVersion  string `json:"appVersion,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ChartVersion is the entry for a packaged version of a chart in the index of a chart repository
+type ChartVersion struct {
+	Metadata
+	URLs    []string  `json:"urls"`
+	Created time.Time `json:"created"`
+	Digest  string    `json:"digest"`
+}
+
+// Index is the index.yaml of a chart repository
+type Index struct {
+	APIVersion string                     `json:"apiVersion"`
+	Entries    map[string][]*ChartVersion `json:"entries"`
+	Generated  time.Time                  `json:"generated"`
+}
+
+// Repository is a Helm chart repository in a local directory, holding packaged charts and their index
+type Repository struct {
+	// Dir is the directory the packaged charts and index are written to
+	Dir string
+	// URL is the URL the directory is served on, which the index refers to the packaged charts by
+	URL   string
+	index *Index
+}
+
+// NewRepository creates an empty chart repository in the given directory, which is served on the given URL
+func NewRepository(dir string, url string) (*Repository, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating chart repository directory %s", dir)
+	}
+	return &Repository{
+		Dir:   dir,
+		URL:   strings.TrimSuffix(url, "/"),
+		index: &Index{APIVersion: "v1", Entries: map[string][]*ChartVersion{}},
+	}, nil
+}
+
+// LoadMetadata loads the Chart.yaml of the chart in the given directory
+func LoadMetadata(chartDir string) (*Metadata, error) {
+	fileName := filepath.Join(chartDir, ChartFileName)
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", fileName)
+	}
+	metadata := &Metadata{}
+	err = yaml.Unmarshal(data, metadata)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", fileName)
+	}
+	if metadata.Name == "" || metadata.Version == "" {
+		return nil, fmt.Errorf("%s has no name or version", fileName)
+	}
+	return metadata, nil
+}
+
+// Add packages the chart in the given directory into the repository once for each of the given versions, or once in
+// the version of its Chart.yaml if no versions are given, and writes the index
+func (r *Repository) Add(chartDir string, versions ...string) error {
+	metadata, err := LoadMetadata(chartDir)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		versions = []string{metadata.Version}
+	}
+	for _, version := range versions {
+		if _, err := semver.Parse(version); err != nil {
+			return errors.Wrapf(err, "invalid version %s of chart %s", version, metadata.Name)
+		}
+		m := *metadata
+		m.Version = version
+		entry, err := r.pack(chartDir, &m)
+		if err != nil {
+			return errors.Wrapf(err, "packaging chart %s version %s", m.Name, version)
+		}
+		r.index.add(entry)
+	}
+	return r.writeIndex()
+}
+
+// AddAll adds each chart in a directory of the given directory to the repository in the given versions
+func (r *Repository) AddAll(fixtureDir string, versions ...string) error {
+	files, err := ioutil.ReadDir(fixtureDir)
+	if err != nil {
+		return errors.Wrapf(err, "reading chart fixtures %s", fixtureDir)
+	}
+	for _, f := range files {
+		chartDir := filepath.Join(fixtureDir, f.Name())
+		if _, err := os.Stat(filepath.Join(chartDir, ChartFileName)); !f.IsDir() || os.IsNotExist(err) {
+			continue
+		}
+		err = r.Add(chartDir, versions...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Charts returns the names of the charts in the repository, sorted
+func (r *Repository) Charts() []string {
+	var answer []string
+	for name := range r.index.Entries {
+		answer = append(answer, name)
+	}
+	sort.Strings(answer)
+	return answer
+}
+
+// Versions returns the versions of the given chart in the repository, newest first as in the index
+func (r *Repository) Versions(chart string) []string {
+	var answer []string
+	for _, entry := range r.index.Entries[chart] {
+		answer = append(answer, entry.Version)
+	}
+	return answer
+}
+
+// Has returns true if the repository has the given chart
+func (r *Repository) Has(chart string) bool {
+	return len(r.index.Entries[chart]) > 0
+}
+
+// pack writes the chart as a .tgz with the given metadata replacing its Chart.yaml, returning its index entry
+func (r *Repository) pack(chartDir string, metadata *Metadata) (*ChartVersion, error) {
+	chartYaml, err := yaml.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	zw.ModTime = packagedAt
+	tw := tar.NewWriter(zw)
+	err = filepath.Walk(chartDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(chartDir, path)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if rel == ChartFileName {
+			data = chartYaml
+		}
+		header := &tar.Header{
+			Name:    metadata.Name + "/" + filepath.ToSlash(rel),
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: packagedAt,
+		}
+		err = tw.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = tw.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = zw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := fmt.Sprintf("%s-%s.tgz", metadata.Name, metadata.Version)
+	err = ioutil.WriteFile(filepath.Join(r.Dir, fileName), buf.Bytes(), 0644)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(buf.Bytes())
+	return &ChartVersion{
+		Metadata: *metadata,
+		URLs:     []string{r.URL + "/" + fileName},
+		Created:  packagedAt,
+		Digest:   hex.EncodeToString(digest[:]),
+	}, nil
+}
+
+func (r *Repository) writeIndex() error {
+	r.index.Generated = time.Now().UTC()
+	data, err := yaml.Marshal(r.index)
+	if err != nil {
+		return err
+	}
+	fileName := filepath.Join(r.Dir, IndexFileName)
+	err = ioutil.WriteFile(fileName, data, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "writing %s", fileName)
+	}
+	return nil
+}
+
+// add adds the entry to the index, replacing any entry for the same version and keeping the newest version first as
+// helm does
+func (i *Index) add(entry *ChartVersion) {
+	var entries []*ChartVersion
+	for _, e := range i.Entries[entry.Name] {
+		if e.Version != entry.Version {
+			entries = append(entries, e)
+		}
+	}
+	entries = append(entries, entry)
+	sort.SliceStable(entries, func(a, b int) bool {
+		return semver.MustParse(entries[a].Version).GT(semver.MustParse(entries[b].Version))
+	})
+	i.Entries[entry.Name] = entries
+}
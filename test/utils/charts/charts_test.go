@@ -0,0 +1,139 @@
+package charts_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/charts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func TestAddAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	r, err := charts.NewRepository(dir, "http://charts.example.com/")
+	require.NoError(t, err)
+	require.NoError(t, r.AddAll(filepath.Join("testdata", "charts"), "0.0.2", "0.0.10", "0.0.1"))
+
+	assert.Equal(t, []string{"hello"}, r.Charts())
+	assert.True(t, r.Has("hello"))
+	assert.False(t, r.Has("not-a-chart"))
+	assert.Equal(t, []string{"0.0.10", "0.0.2", "0.0.1"}, r.Versions("hello"))
+
+	index := loadIndex(t, dir)
+	assert.Equal(t, "v1", index.APIVersion)
+	entry := index.Entries["hello"][0]
+	assert.Equal(t, "0.0.10", entry.Version)
+	assert.Equal(t, "A chart for testing chart repositories", entry.Description)
+	assert.Equal(t, []string{"http://charts.example.com/hello-0.0.10.tgz"}, entry.URLs)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "hello-0.0.10.tgz"))
+	require.NoError(t, err)
+	digest := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(digest[:]), entry.Digest)
+
+	files := untar(t, filepath.Join(dir, "hello-0.0.10.tgz"))
+	assert.Equal(t, []string{"hello/Chart.yaml", "hello/templates/configmap.yaml", "hello/values.yaml"}, sortedKeys(files))
+	metadata := &charts.Metadata{}
+	require.NoError(t, yaml.Unmarshal(files["hello/Chart.yaml"], metadata))
+	assert.Equal(t, "0.0.10", metadata.Version)
+	assert.Equal(t, "hello", metadata.Name)
+}
+
+func TestAddIsDeterministic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	r, err := charts.NewRepository(dir, "http://charts.example.com")
+	require.NoError(t, err)
+	chartDir := filepath.Join("testdata", "charts", "hello")
+	require.NoError(t, r.Add(chartDir))
+	first := loadIndex(t, dir).Entries["hello"][0].Digest
+	require.NoError(t, r.Add(chartDir))
+
+	entries := loadIndex(t, dir).Entries["hello"]
+	require.Len(t, entries, 1)
+	assert.Equal(t, "1.0.0", entries[0].Version)
+	assert.Equal(t, first, entries[0].Digest)
+}
+
+func TestAddInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	r, err := charts.NewRepository(dir, "http://charts.example.com")
+	require.NoError(t, err)
+	assert.Error(t, r.Add(filepath.Join("testdata", "charts", "hello"), "latest"))
+	assert.Error(t, r.Add(filepath.Join("testdata", "charts", "not-a-chart")))
+}
+
+func TestServe(t *testing.T) {
+	s, err := charts.Serve("127.0.0.1:0", "")
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.Equal(t, "http://"+s.Address(), s.URL)
+	require.NoError(t, s.Add(filepath.Join("testdata", "charts", "hello")))
+
+	for _, path := range []string{"/index.yaml", "/hello-1.0.0.tgz"} {
+		resp, err := http.Get(s.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+	}
+	resp, err := http.Get(s.URL + "/hello-2.0.0.tgz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func loadIndex(t *testing.T, dir string) *charts.Index {
+	data, err := ioutil.ReadFile(filepath.Join(dir, charts.IndexFileName))
+	require.NoError(t, err)
+	index := &charts.Index{}
+	require.NoError(t, yaml.Unmarshal(data, index))
+	return index
+}
+
+func untar(t *testing.T, fileName string) map[string][]byte {
+	f, err := os.Open(fileName)
+	require.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tr := tar.NewReader(zr)
+	files := map[string][]byte{}
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		data, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = data
+	}
+	return files
+}
+
+func sortedKeys(m map[string][]byte) []string {
+	var answer []string
+	for k := range m {
+		answer = append(answer, k)
+	}
+	sort.Strings(answer)
+	return answer
+}
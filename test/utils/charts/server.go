@@ -0,0 +1,61 @@
+package charts
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Server serves a chart repository over HTTP from a temporary directory
+type Server struct {
+	*Repository
+
+	listener net.Listener
+	server   *http.Server
+}
+
+// Serve listens on the given address, such as ":8879" or "127.0.0.1:0", and serves an empty chart repository until
+// Close is called. The repository refers to its charts by the given URL, or by the address listened on if it is empty.
+func Serve(address string, url string) (*Server, error) {
+	dir, err := ioutil.TempDir("", "charts")
+	if err != nil {
+		return nil, errors.Wrap(err, "creating chart repository directory")
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, errors.Wrapf(err, "listening for chart requests on %s", address)
+	}
+	if url == "" {
+		url = fmt.Sprintf("http://%s", listener.Addr().String())
+	}
+	repository, err := NewRepository(dir, url)
+	if err != nil {
+		_ = listener.Close()
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	s := &Server{
+		Repository: repository,
+		listener:   listener,
+		server:     &http.Server{Handler: http.FileServer(http.Dir(dir))},
+	}
+	go s.server.Serve(listener) //nolint:errcheck
+	return s, nil
+}
+
+// Address returns the address the server is listening on
+func (s *Server) Address() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops serving the repository and removes its directory
+func (s *Server) Close() error {
+	err := s.server.Close()
+	_ = os.RemoveAll(s.Dir)
+	return err
+}
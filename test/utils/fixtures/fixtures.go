@@ -0,0 +1,178 @@
+package fixtures
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// TemplateSuffix is the suffix of fixture files which are rendered with the Values, and which is removed when
+	// copying
+	TemplateSuffix = ".tmpl"
+	// BaseFileName is the file of a fixture naming another fixture in the same directory which it is based on. The
+	// files of the base fixture are copied first, then those of the fixture replace them, so that fixtures which only
+	// differ by a few files share the others. The file itself is not copied.
+	BaseFileName = ".fixture-base"
+)
+
+// Values are the values fixture templates are rendered with
+type Values struct {
+	// ApplicationName is the name of the application the fixture is imported as
+	ApplicationName string
+}
+
+// Copy copies the fixture project in the source directory, on top of the fixtures it is based on, to the destination
+// directory, rendering the files ending in TemplateSuffix with the values. The destination directory must not exist
+// yet.
+func Copy(src string, dest string, values Values) error {
+	layers, err := Layers(src)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("cannot copy fixture %s as %s already exists", src, dest)
+	}
+	for _, layer := range layers {
+		err = copyLayer(layer, dest, values)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Layers returns the directory of the fixture preceded by those of the fixtures it is based on, the first base first
+func Layers(dir string) ([]string, error) {
+	var layers []string
+	seen := map[string]bool{}
+	for dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "finding fixture %s", dir)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("fixture %s is not a directory", dir)
+		}
+		if seen[dir] {
+			return nil, fmt.Errorf("fixture %s is based on itself", dir)
+		}
+		seen[dir] = true
+		layers = append([]string{dir}, layers...)
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, BaseFileName))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		base := strings.TrimSpace(string(data))
+		if base == "" || strings.ContainsAny(base, `/\`) {
+			return nil, fmt.Errorf("the %s of fixture %s does not name a fixture: %q", BaseFileName, dir, base)
+		}
+		dir = filepath.Join(filepath.Dir(dir), base)
+	}
+	return layers, nil
+}
+
+func copyLayer(src string, dest string, values Values) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == BaseFileName {
+			return nil
+		}
+		target := filepath.Join(dest, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(path, TemplateSuffix) {
+			target = strings.TrimSuffix(target, TemplateSuffix)
+			data, err = render(path, data, values)
+			if err != nil {
+				return err
+			}
+		}
+		return ioutil.WriteFile(target, data, info.Mode().Perm())
+	})
+}
+
+// Project reads the files of a fixture project as they are when copied with the values
+type Project struct {
+	Dir    string
+	Values Values
+}
+
+// Read returns the content of the file at the slash separated path relative to the fixture, rendering it if it is a
+// template, or false if neither the fixture nor the fixtures it is based on have the file
+func (p Project) Read(name string) ([]byte, bool, error) {
+	layers, err := Layers(p.Dir)
+	if err != nil {
+		return nil, false, err
+	}
+	for i := len(layers) - 1; i >= 0; i-- {
+		data, ok, err := readLayer(layers[i], name, p.Values)
+		if err != nil || ok {
+			return data, ok, err
+		}
+	}
+	return nil, false, nil
+}
+
+func readLayer(dir string, name string, values Values) ([]byte, bool, error) {
+	file := filepath.Join(dir, filepath.FromSlash(name))
+	data, err := ioutil.ReadFile(file)
+	if err == nil {
+		return data, true, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, false, err
+	}
+	file += TemplateSuffix
+	data, err = ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	data, err = render(file, data, values)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// String returns the directory of the fixture
+func (p Project) String() string {
+	return "fixture " + p.Dir
+}
+
+func render(path string, data []byte, values Values) ([]byte, error) {
+	tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing template %s", path)
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, values)
+	if err != nil {
+		return nil, errors.Wrapf(err, "rendering template %s", path)
+	}
+	return buf.Bytes(), nil
+}
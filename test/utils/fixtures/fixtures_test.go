@@ -0,0 +1,104 @@
+package fixtures_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/fixtures"
+	"github.com/jenkins-x/bdd-jx/test/utils/scenarios"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const importDir = "../../suite/_import"
+
+func TestCopyRendersTemplates(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "bdd-srp-import-123")
+	err := fixtures.Copy(filepath.Join(importDir, "fixtures", "spring-boot-rest-prometheus"), dest, fixtures.Values{ApplicationName: "bdd-srp-import-123"})
+	require.NoError(t, err)
+
+	pom, err := ioutil.ReadFile(filepath.Join(dest, "pom.xml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(pom), "<artifactId>bdd-srp-import-123</artifactId>")
+	assert.NoFileExists(t, filepath.Join(dest, "pom.xml"+fixtures.TemplateSuffix))
+	assert.FileExists(t, filepath.Join(dest, "src", "main", "java", "com", "example", "demo", "DemoApplication.java"))
+
+	err = fixtures.Copy(filepath.Join(importDir, "fixtures", "node-http"), dest, fixtures.Values{ApplicationName: "bdd-nh-import-123"})
+	assert.Error(t, err, "copying over an existing directory")
+}
+
+func TestCopyMissingFixture(t *testing.T) {
+	err := fixtures.Copy(filepath.Join(importDir, "fixtures", "missing"), filepath.Join(t.TempDir(), "app"), fixtures.Values{})
+	assert.Error(t, err)
+}
+
+func TestCopyBasedFixture(t *testing.T) {
+	fixtureDir := filepath.Join(importDir, "fixtures", "golang-http-from-jenkins-x-yml")
+	layers, err := fixtures.Layers(fixtureDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(importDir, "fixtures", "golang-http"), fixtureDir}, layers)
+
+	dest := filepath.Join(t.TempDir(), "app")
+	values := fixtures.Values{ApplicationName: "bdd-ghfjxy-import-123"}
+	err = fixtures.Copy(fixtureDir, dest, values)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dest, "main.go"))
+	assert.FileExists(t, filepath.Join(dest, "jenkins-x.yml"))
+	assert.NoFileExists(t, filepath.Join(dest, fixtures.BaseFileName))
+	goMod, err := ioutil.ReadFile(filepath.Join(dest, "go.mod"))
+	require.NoError(t, err)
+	assert.Contains(t, string(goMod), "bdd-ghfjxy-import-123")
+
+	project := fixtures.Project{Dir: fixtureDir, Values: values}
+	data, ok, err := project.Read("go.mod")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, string(goMod), string(data))
+	_, ok, err = project.Read("Makefile")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestLayersErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, base := range map[string]string{"a": "b", "b": "a", "nested": "../a", "missing": "none"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, name), 0700))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name, fixtures.BaseFileName), []byte(base+"\n"), 0600))
+	}
+	for _, name := range []string{"a", "nested", "missing"} {
+		_, err := fixtures.Layers(filepath.Join(dir, name))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestImportScenarioFixtures(t *testing.T) {
+	loaded, err := scenarios.LoadDir(filepath.Join(importDir, "scenarios"))
+	require.NoError(t, err)
+	for _, scenario := range loaded {
+		if scenario.Source.Fixture == "" {
+			continue
+		}
+		t.Run(scenario.Name, func(t *testing.T) {
+			dir := t.TempDir()
+			values := fixtures.Values{ApplicationName: "bdd-app"}
+			fixtureDir := filepath.Join(importDir, "fixtures", scenario.Source.Fixture)
+			err := fixtures.Copy(fixtureDir, filepath.Join(dir, "app"), values)
+			require.NoError(t, err)
+
+			if scenario.Imported == nil {
+				return
+			}
+			project := fixtures.Project{Dir: fixtureDir, Values: values}
+			for _, name := range scenario.Imported.Untouched {
+				data, ok, err := project.Read(name)
+				require.NoError(t, err)
+				assert.True(t, ok, "fixture %s has no %s", scenario.Source.Fixture, name)
+				copied, err := ioutil.ReadFile(filepath.Join(dir, "app", filepath.FromSlash(name)))
+				require.NoError(t, err)
+				assert.Equal(t, string(copied), string(data))
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package imports
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/v2/pkg/config"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// Expectation is what jx import is expected to produce from a project
+type Expectation struct {
+	// BuildPack is the build pack jx import is expected to choose, which is not checked if empty
+	BuildPack string `json:"buildPack,omitempty"`
+	// Dockerfile expects the project to have a Dockerfile after the import
+	Dockerfile bool `json:"dockerfile,omitempty"`
+	// Chart expects the project to have a chart named after the application in the charts directory after the import
+	Chart bool `json:"chart,omitempty"`
+	// Files are further files the project is expected to have after the import
+	Files []string `json:"files,omitempty"`
+	// Untouched are files of the project which the import must not change
+	Untouched []string `json:"untouched,omitempty"`
+}
+
+// Files reads the files of a project
+type Files interface {
+	// Read returns the content of the file at the slash separated path relative to the root of the project, or false if
+	// the file does not exist
+	Read(path string) ([]byte, bool, error)
+	// String describes where the files are read from
+	String() string
+}
+
+// Dir reads the files of the project in a local directory
+type Dir string
+
+// Read returns the content of the file in the directory
+func (d Dir) Read(name string) ([]byte, bool, error) {
+	data, err := ioutil.ReadFile(filepath.Join(string(d), filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// String returns the directory
+func (d Dir) String() string {
+	return string(d)
+}
+
+// ReadBuildPack returns the build pack jx import chose for the project, as recorded in its jenkins-x.yml
+func ReadBuildPack(files Files) (string, error) {
+	data, ok, err := files.Read(config.ProjectConfigFileName)
+	if err != nil {
+		return "", errors.Wrapf(err, "reading %s of %s", config.ProjectConfigFileName, files)
+	}
+	if !ok {
+		return "", fmt.Errorf("%s has no %s", files, config.ProjectConfigFileName)
+	}
+	projectConfig := config.ProjectConfig{}
+	err = yaml.Unmarshal(data, &projectConfig)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing %s of %s", config.ProjectConfigFileName, files)
+	}
+	if projectConfig.BuildPack == "" {
+		return "", fmt.Errorf("%s of %s does not name a build pack", config.ProjectConfigFileName, files)
+	}
+	return projectConfig.BuildPack, nil
+}
+
+// ChartDir returns the slash separated path of the chart jx import generates for the application
+func ChartDir(app string) string {
+	return path.Join("charts", app)
+}
+
+// Verify returns an error unless the imported project of the application meets the expectation. Untouched files are
+// compared with the same files of the original project.
+func Verify(imported Files, original Files, expect *Expectation, app string) error {
+	if expect.BuildPack != "" {
+		buildPack, err := ReadBuildPack(imported)
+		if err != nil {
+			return err
+		}
+		if buildPack != expect.BuildPack {
+			return fmt.Errorf("expected %s to use the %s build pack but found %s", imported, expect.BuildPack, buildPack)
+		}
+	}
+	files := append([]string{}, expect.Files...)
+	if expect.Dockerfile {
+		files = append(files, "Dockerfile")
+	}
+	for _, name := range files {
+		_, ok, err := imported.Read(name)
+		if err != nil {
+			return errors.Wrapf(err, "reading %s of %s", name, imported)
+		}
+		if !ok {
+			return fmt.Errorf("expected %s to have %s", imported, name)
+		}
+	}
+	if expect.Chart {
+		err := verifyChart(imported, app)
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range expect.Untouched {
+		err := verifyUntouched(imported, original, name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifySourceRepository returns an error unless the SourceRepository jx import created is for the repository of the
+// owner on the git provider at the given URL
+func VerifySourceRepository(sr *v1.SourceRepository, providerURL string, owner string, repo string) error {
+	spec := sr.Spec
+	if !strings.EqualFold(spec.Org, owner) || !strings.EqualFold(spec.Repo, repo) {
+		return fmt.Errorf("SourceRepository %s is for %s/%s rather than %s/%s", sr.Name, spec.Org, spec.Repo, owner, repo)
+	}
+	if providerURL != "" && !sameURL(spec.Provider, providerURL) {
+		return fmt.Errorf("SourceRepository %s has provider %s rather than %s", sr.Name, spec.Provider, providerURL)
+	}
+	if spec.ProviderKind == "" {
+		return fmt.Errorf("SourceRepository %s has no provider kind", sr.Name)
+	}
+	if spec.ProviderName == "" {
+		return fmt.Errorf("SourceRepository %s has no provider name", sr.Name)
+	}
+	if spec.HTTPCloneURL == "" && spec.URL == "" {
+		return fmt.Errorf("SourceRepository %s has no URL to clone", sr.Name)
+	}
+	return nil
+}
+
+func verifyChart(imported Files, app string) error {
+	name := path.Join(ChartDir(app), "Chart.yaml")
+	data, ok, err := imported.Read(name)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s of %s", name, imported)
+	}
+	if !ok {
+		return fmt.Errorf("expected %s to have %s", imported, name)
+	}
+	chart := struct {
+		Name string `json:"name"`
+	}{}
+	err = yaml.Unmarshal(data, &chart)
+	if err != nil {
+		return errors.Wrapf(err, "parsing %s of %s", name, imported)
+	}
+	if chart.Name != app {
+		return fmt.Errorf("expected %s of %s to name the chart %s but found '%s'", name, imported, app, chart.Name)
+	}
+	return nil
+}
+
+func verifyUntouched(imported Files, original Files, name string) error {
+	expected, ok, err := original.Read(name)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s of %s", name, original)
+	}
+	if !ok {
+		return fmt.Errorf("cannot check %s is untouched as %s does not have it", name, original)
+	}
+	actual, ok, err := imported.Read(name)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s of %s", name, imported)
+	}
+	if !ok {
+		return fmt.Errorf("expected %s to still have %s", imported, name)
+	}
+	if !bytes.Equal(expected, actual) {
+		return fmt.Errorf("expected %s of %s to be untouched by the import", name, imported)
+	}
+	return nil
+}
+
+func sameURL(a string, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "/"), strings.TrimSuffix(b, "/"))
+}
@@ -0,0 +1,134 @@
+package imports_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/imports"
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBuildPack(t *testing.T) {
+	buildPack, err := imports.ReadBuildPack(imports.Dir("testdata/go"))
+	require.NoError(t, err)
+	assert.Equal(t, "go", buildPack)
+
+	_, err = imports.ReadBuildPack(imports.Dir("testdata/none"))
+	assert.Error(t, err)
+}
+
+func writeProject(t *testing.T, files map[string]string) imports.Dir {
+	dir, err := ioutil.TempDir("", "imports")
+	require.NoError(t, err)
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	}
+	return imports.Dir(dir)
+}
+
+func TestVerify(t *testing.T) {
+	original := writeProject(t, map[string]string{
+		"main.go":       "package main\n",
+		"jenkins-x.yml": "buildPack: go\n",
+	})
+	defer os.RemoveAll(string(original))
+
+	imported := map[string]string{
+		"main.go":                   "package main\n",
+		"jenkins-x.yml":             "buildPack: go\n",
+		"Dockerfile":                "FROM scratch\n",
+		"Makefile":                  "build:\n",
+		"charts/bdd-app/Chart.yaml": "name: bdd-app\nversion: 0.1.0-SNAPSHOT\n",
+	}
+	without := func(name string) map[string]string {
+		files := map[string]string{}
+		for k, v := range imported {
+			if k != name {
+				files[k] = v
+			}
+		}
+		return files
+	}
+	with := func(name string, content string) map[string]string {
+		files := without(name)
+		files[name] = content
+		return files
+	}
+	expect := &imports.Expectation{
+		BuildPack:  "go",
+		Dockerfile: true,
+		Chart:      true,
+		Files:      []string{"Makefile"},
+		Untouched:  []string{"main.go", "jenkins-x.yml"},
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		valid bool
+	}{
+		{name: "as expected", files: imported, valid: true},
+		{name: "other build pack", files: with("jenkins-x.yml", "buildPack: javascript\n")},
+		{name: "no jenkins-x.yml", files: without("jenkins-x.yml")},
+		{name: "no Dockerfile", files: without("Dockerfile")},
+		{name: "no Makefile", files: without("Makefile")},
+		{name: "no chart", files: without("charts/bdd-app/Chart.yaml")},
+		{name: "chart not renamed", files: with("charts/bdd-app/Chart.yaml", "name: golang-http\n")},
+		{name: "source changed", files: with("main.go", "package app\n")},
+		{name: "source removed", files: without("main.go")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeProject(t, tt.files)
+			defer os.RemoveAll(string(dir))
+
+			err := imports.Verify(dir, original, expect, "bdd-app")
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifySourceRepository(t *testing.T) {
+	sr := func() *v1.SourceRepository {
+		return &v1.SourceRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "jenkins-x-tests-bdd-app"},
+			Spec: v1.SourceRepositorySpec{
+				Provider:     "https://github.com",
+				ProviderName: "github",
+				ProviderKind: "github",
+				Org:          "jenkins-x-tests",
+				Repo:         "bdd-app",
+				HTTPCloneURL: "https://github.com/jenkins-x-tests/bdd-app.git",
+			},
+		}
+	}
+	assert.NoError(t, imports.VerifySourceRepository(sr(), "https://github.com/", "jenkins-x-tests", "bdd-app"))
+
+	tests := []struct {
+		name   string
+		modify func(sr *v1.SourceRepository)
+	}{
+		{name: "other repository", modify: func(sr *v1.SourceRepository) { sr.Spec.Repo = "bdd-other" }},
+		{name: "other provider", modify: func(sr *v1.SourceRepository) { sr.Spec.Provider = "https://gitlab.com" }},
+		{name: "no provider kind", modify: func(sr *v1.SourceRepository) { sr.Spec.ProviderKind = "" }},
+		{name: "no clone URL", modify: func(sr *v1.SourceRepository) { sr.Spec.HTTPCloneURL = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := sr()
+			tt.modify(repo)
+			assert.Error(t, imports.VerifySourceRepository(repo, "https://github.com", "jenkins-x-tests", "bdd-app"))
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package keeper
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+)
+
+// BatchBranch is the branch of the PipelineActivities of the batch builds Lighthouse runs to merge several pull requests
+// of the merge pool at once
+const BatchBranch = "batch"
+
+// Merge is a pull request of the merge pool which keeper merged
+type Merge struct {
+	Number   int
+	URL      string
+	MergedAt time.Time
+}
+
+// BatchedPullRequests returns the names of the successful batch builds of the repository keyed by the numbers of the
+// pull requests they built
+func BatchedPullRequests(activities []v1.PipelineActivity, owner string, repo string) map[int]string {
+	answer := map[int]string{}
+	for _, a := range activities {
+		if !forRepository(&a, owner, repo) || !strings.EqualFold(a.Spec.GitBranch, BatchBranch) || a.Spec.Status != v1.ActivityStatusTypeSucceeded {
+			continue
+		}
+		for _, pr := range a.Spec.BatchPipelineActivity.ComprisingPulLRequests {
+			if number, err := strconv.Atoi(pr.PullRequestNumber); err == nil {
+				answer[number] = a.Name
+			}
+		}
+	}
+	return answer
+}
+
+// RetestedAfterBaseMoved returns true if the pull request has a build against a different base than its first build,
+// as Lighthouse creates when the base branch moves while the pull request is in the merge pool
+func RetestedAfterBaseMoved(activities []v1.PipelineActivity, owner string, repo string, number int) bool {
+	var builds []v1.PipelineActivity
+	branch := fmt.Sprintf("PR-%d", number)
+	for _, a := range activities {
+		if forRepository(&a, owner, repo) && strings.EqualFold(a.Spec.GitBranch, branch) && a.Spec.BaseSHA != "" {
+			builds = append(builds, a)
+		}
+	}
+	if len(builds) < 2 {
+		return false
+	}
+	sort.Slice(builds, func(i, j int) bool {
+		return buildNumber(&builds[i]) < buildNumber(&builds[j])
+	})
+	for _, b := range builds[1:] {
+		if b.Spec.BaseSHA != builds[0].Spec.BaseSHA {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyMerges returns an error unless each merged pull request was either built by a successful batch build, was the
+// first to merge, or was retested after the base moved because earlier pull requests merged
+func VerifyMerges(activities []v1.PipelineActivity, owner string, repo string, merges []Merge) error {
+	if len(merges) == 0 {
+		return fmt.Errorf("no pull requests were merged")
+	}
+	sorted := append([]Merge{}, merges...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].MergedAt.Before(sorted[j].MergedAt)
+	})
+	batched := BatchedPullRequests(activities, owner, repo)
+	var notRetested []string
+	for i, m := range sorted {
+		if _, ok := batched[m.Number]; ok || i == 0 {
+			continue
+		}
+		if !RetestedAfterBaseMoved(activities, owner, repo, m.Number) {
+			notRetested = append(notRetested, m.URL)
+		}
+	}
+	if len(notRetested) > 0 {
+		return fmt.Errorf("pull requests were merged neither in a batch nor after being retested against the moved base: %s", strings.Join(notRetested, ", "))
+	}
+	return nil
+}
+
+func forRepository(a *v1.PipelineActivity, owner string, repo string) bool {
+	return strings.EqualFold(a.Spec.GitOwner, owner) && strings.EqualFold(a.Spec.GitRepository, repo)
+}
+
+func buildNumber(a *v1.PipelineActivity) int {
+	n, _ := strconv.Atoi(a.Spec.Build)
+	return n
+}
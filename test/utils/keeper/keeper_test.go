@@ -0,0 +1,90 @@
+package keeper_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/keeper"
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	owner = "jenkins-x-tests"
+	repo  = "bdd-keeper"
+)
+
+func activity(branch string, build string, baseSHA string, status v1.ActivityStatusType, pulls ...string) v1.PipelineActivity {
+	a := v1.PipelineActivity{
+		ObjectMeta: metav1.ObjectMeta{Name: owner + "-" + repo + "-" + branch + "-" + build},
+		Spec: v1.PipelineActivitySpec{
+			GitOwner:      owner,
+			GitRepository: repo,
+			GitBranch:     branch,
+			Build:         build,
+			BaseSHA:       baseSHA,
+			Status:        status,
+		},
+	}
+	for _, pull := range pulls {
+		a.Spec.BatchPipelineActivity.ComprisingPulLRequests = append(a.Spec.BatchPipelineActivity.ComprisingPulLRequests, v1.PullRequestInfo{PullRequestNumber: pull})
+	}
+	return a
+}
+
+func merges(numbers ...int) []keeper.Merge {
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	var answer []keeper.Merge
+	for i, n := range numbers {
+		answer = append(answer, keeper.Merge{Number: n, URL: fmt.Sprintf("pr-%d", n), MergedAt: start.Add(time.Duration(i) * time.Minute)})
+	}
+	return answer
+}
+
+func TestBatchedPullRequests(t *testing.T) {
+	activities := []v1.PipelineActivity{
+		activity("batch", "1", "aaa", v1.ActivityStatusTypeFailed, "1", "2", "3"),
+		activity("batch", "2", "aaa", v1.ActivityStatusTypeSucceeded, "1", "3"),
+		activity("PR-2", "1", "aaa", v1.ActivityStatusTypeSucceeded),
+	}
+	batched := keeper.BatchedPullRequests(activities, owner, repo)
+	assert.Equal(t, map[int]string{1: activities[1].Name, 3: activities[1].Name}, batched)
+	assert.Empty(t, keeper.BatchedPullRequests(activities, owner, "other"))
+}
+
+func TestRetestedAfterBaseMoved(t *testing.T) {
+	activities := []v1.PipelineActivity{
+		activity("PR-1", "1", "aaa", v1.ActivityStatusTypeSucceeded),
+		activity("PR-2", "2", "bbb", v1.ActivityStatusTypeRunning),
+		activity("PR-2", "1", "aaa", v1.ActivityStatusTypeSucceeded),
+		activity("PR-3", "1", "aaa", v1.ActivityStatusTypeSucceeded),
+		activity("PR-3", "2", "aaa", v1.ActivityStatusTypeSucceeded),
+	}
+	assert.False(t, keeper.RetestedAfterBaseMoved(activities, owner, repo, 1))
+	assert.True(t, keeper.RetestedAfterBaseMoved(activities, owner, repo, 2))
+	assert.False(t, keeper.RetestedAfterBaseMoved(activities, owner, repo, 3), "rebuilt against the same base")
+}
+
+func TestVerifyMerges(t *testing.T) {
+	batch := []v1.PipelineActivity{
+		activity("batch", "1", "aaa", v1.ActivityStatusTypeSucceeded, "1", "2", "3"),
+	}
+	assert.NoError(t, keeper.VerifyMerges(batch, owner, repo, merges(1, 2, 3)))
+
+	serial := []v1.PipelineActivity{
+		activity("PR-1", "1", "aaa", v1.ActivityStatusTypeSucceeded),
+		activity("PR-2", "1", "aaa", v1.ActivityStatusTypeSucceeded),
+		activity("PR-2", "2", "bbb", v1.ActivityStatusTypeSucceeded),
+		activity("PR-3", "1", "aaa", v1.ActivityStatusTypeSucceeded),
+		activity("PR-3", "2", "ccc", v1.ActivityStatusTypeSucceeded),
+	}
+	assert.NoError(t, keeper.VerifyMerges(serial, owner, repo, merges(1, 2, 3)))
+	assert.EqualError(t, keeper.VerifyMerges(serial[:4], owner, repo, merges(1, 2, 3)), "pull requests were merged neither in a batch nor after being retested against the moved base: pr-3")
+
+	mixed := append([]v1.PipelineActivity{activity("batch", "1", "aaa", v1.ActivityStatusTypeSucceeded, "2", "3")}, serial[0], serial[1], serial[3])
+	assert.NoError(t, keeper.VerifyMerges(mixed, owner, repo, merges(1, 2, 3)))
+
+	assert.EqualError(t, keeper.VerifyMerges(nil, owner, repo, nil), "no pull requests were merged")
+}
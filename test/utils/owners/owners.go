@@ -0,0 +1,228 @@
+package owners
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// OwnersFileName is the name of the file holding the owners of a directory
+	OwnersFileName = "OWNERS"
+	// AliasesFileName is the name of the file in the root of a repo holding the owners aliases
+	AliasesFileName = "OWNERS_ALIASES"
+)
+
+// Config holds the roles and labels for the files an OWNERS file or filter applies to
+type Config struct {
+	Approvers         []string `json:"approvers,omitempty"`
+	Reviewers         []string `json:"reviewers,omitempty"`
+	RequiredReviewers []string `json:"required_reviewers,omitempty"`
+	Labels            []string `json:"labels,omitempty"`
+}
+
+// Options holds the options for an OWNERS file
+type Options struct {
+	NoParentOwners bool `json:"no_parent_owners,omitempty"`
+}
+
+type simpleFile struct {
+	Options *Options `json:"options,omitempty"`
+	Config  `json:",inline"`
+}
+
+type fullFile struct {
+	Options *Options          `json:"options,omitempty"`
+	Filters map[string]Config `json:"filters"`
+}
+
+type aliasesFile struct {
+	Aliases map[string][]string `json:"aliases"`
+}
+
+// Tree builds the OWNERS files for the directories of a repo along with its OWNERS_ALIASES
+type Tree struct {
+	dirs    map[string]*Dir
+	aliases map[string][]string
+}
+
+// Dir builds the OWNERS file for a single directory
+type Dir struct {
+	path    string
+	config  Config
+	options Options
+	filters map[string]*Config
+}
+
+// NewTree creates an empty OWNERS tree
+func NewTree() *Tree {
+	return &Tree{
+		dirs:    map[string]*Dir{},
+		aliases: map[string][]string{},
+	}
+}
+
+// Dir returns the builder for the OWNERS file in the given directory relative to the root of the repo, creating it
+// if need be. Use "" for the root directory.
+func (t *Tree) Dir(path string) *Dir {
+	path = canonicalize(path)
+	d, ok := t.dirs[path]
+	if !ok {
+		d = &Dir{path: path}
+		t.dirs[path] = d
+	}
+	return d
+}
+
+// Alias adds an OWNERS_ALIASES entry for the given users
+func (t *Tree) Alias(name string, users ...string) *Tree {
+	t.aliases[name] = append(t.aliases[name], users...)
+	return t
+}
+
+// Dirs returns the directories which have OWNERS files, sorted
+func (t *Tree) Dirs() []string {
+	var answer []string
+	for path := range t.dirs {
+		answer = append(answer, path)
+	}
+	sort.Strings(answer)
+	return answer
+}
+
+// Files returns the contents of each file in the tree keyed by its path relative to the root of the repo
+func (t *Tree) Files() (map[string][]byte, error) {
+	answer := map[string][]byte{}
+	for path, d := range t.dirs {
+		data, err := d.Marshal()
+		if err != nil {
+			return nil, errors.Wrapf(err, "marshalling OWNERS for directory '%s'", path)
+		}
+		answer[filepath.Join(path, OwnersFileName)] = data
+	}
+	if len(t.aliases) > 0 {
+		data, err := yaml.Marshal(&aliasesFile{Aliases: t.aliases})
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling OWNERS_ALIASES")
+		}
+		answer[AliasesFileName] = data
+	}
+	return answer, nil
+}
+
+// WriteTo writes the tree into the given repo directory, returning the paths written relative to it
+func (t *Tree) WriteTo(dir string) ([]string, error) {
+	files, err := t.Files()
+	if err != nil {
+		return nil, err
+	}
+	var written []string
+	for path, data := range files {
+		fileName := filepath.Join(dir, path)
+		err = os.MkdirAll(filepath.Dir(fileName), os.ModePerm)
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating directory for %s", fileName)
+		}
+		err = ioutil.WriteFile(fileName, data, utils.DefaultWritePermissions)
+		if err != nil {
+			return nil, errors.Wrapf(err, "writing %s", fileName)
+		}
+		written = append(written, path)
+	}
+	sort.Strings(written)
+	return written, nil
+}
+
+// Path returns the directory of the OWNERS file relative to the root of the repo
+func (d *Dir) Path() string {
+	return d.path
+}
+
+// Approvers adds approvers for every file in the directory
+func (d *Dir) Approvers(users ...string) *Dir {
+	d.config.Approvers = append(d.config.Approvers, users...)
+	return d
+}
+
+// Reviewers adds reviewers for every file in the directory
+func (d *Dir) Reviewers(users ...string) *Dir {
+	d.config.Reviewers = append(d.config.Reviewers, users...)
+	return d
+}
+
+// RequiredReviewers adds required reviewers for every file in the directory
+func (d *Dir) RequiredReviewers(users ...string) *Dir {
+	d.config.RequiredReviewers = append(d.config.RequiredReviewers, users...)
+	return d
+}
+
+// Labels adds labels applied to pull requests touching files in the directory
+func (d *Dir) Labels(labels ...string) *Dir {
+	d.config.Labels = append(d.config.Labels, labels...)
+	return d
+}
+
+// NoParentOwners stops the owners of parent directories from applying to this one
+func (d *Dir) NoParentOwners() *Dir {
+	d.options.NoParentOwners = true
+	return d
+}
+
+// Filter returns the config applied to the files in the directory matching the given regular expression. Once a
+// directory has filters, its approvers, reviewers and labels are written as the filter for all files.
+func (d *Dir) Filter(regex string) *Config {
+	if d.filters == nil {
+		d.filters = map[string]*Config{}
+	}
+	c, ok := d.filters[regex]
+	if !ok {
+		c = &Config{}
+		d.filters[regex] = c
+	}
+	return c
+}
+
+// Marshal returns the YAML for the OWNERS file
+func (d *Dir) Marshal() ([]byte, error) {
+	var options *Options
+	if d.options.NoParentOwners {
+		options = &d.options
+	}
+	if len(d.filters) == 0 {
+		return yaml.Marshal(&simpleFile{Options: options, Config: d.config})
+	}
+	full := &fullFile{
+		Options: options,
+		Filters: map[string]Config{},
+	}
+	for regex, c := range d.filters {
+		full.Filters[regex] = *c
+	}
+	if !d.config.empty() {
+		all := full.Filters[".*"]
+		all.Approvers = append(all.Approvers, d.config.Approvers...)
+		all.Reviewers = append(all.Reviewers, d.config.Reviewers...)
+		all.RequiredReviewers = append(all.RequiredReviewers, d.config.RequiredReviewers...)
+		all.Labels = append(all.Labels, d.config.Labels...)
+		full.Filters[".*"] = all
+	}
+	return yaml.Marshal(full)
+}
+
+func (c *Config) empty() bool {
+	return len(c.Approvers) == 0 && len(c.Reviewers) == 0 && len(c.RequiredReviewers) == 0 && len(c.Labels) == 0
+}
+
+func canonicalize(path string) string {
+	path = strings.Trim(filepath.ToSlash(filepath.Clean(path)), "/")
+	if path == "." {
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,76 @@
+package owners_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/owners"
+	"github.com/jenkins-x/lighthouse/pkg/repoowners"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func TestTreeFiles(t *testing.T) {
+	tree := owners.NewTree()
+	tree.Dir("").Approvers("root-approver").Reviewers("root-reviewer")
+	tree.Dir("/sub/").Approvers("sub-team").NoParentOwners()
+	tree.Dir("docs").Filter(`\.md$`).Approvers = []string{"docs-approver"}
+	tree.Alias("sub-team", "alice", "bob")
+
+	files, err := tree.Files()
+	require.NoError(t, err)
+	assert.Len(t, files, 4)
+	assert.Equal(t, []string{"", "docs", "sub"}, tree.Dirs())
+
+	root, err := repoowners.ParseSimpleConfig(files["OWNERS"])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"root-approver"}, root.Approvers)
+	assert.Equal(t, []string{"root-reviewer"}, root.Reviewers)
+	assert.False(t, root.Options.NoParentOwners)
+
+	sub, err := repoowners.ParseSimpleConfig(files["sub/OWNERS"])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub-team"}, sub.Approvers)
+	assert.True(t, sub.Options.NoParentOwners)
+
+	docs, err := repoowners.ParseFullConfig(files["docs/OWNERS"])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs-approver"}, docs.Filters[`\.md$`].Approvers)
+
+	aliases := map[string]map[string][]string{}
+	err = yaml.Unmarshal(files["OWNERS_ALIASES"], &aliases)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, aliases["aliases"]["sub-team"])
+}
+
+func TestFilterWithDirectoryConfig(t *testing.T) {
+	tree := owners.NewTree()
+	d := tree.Dir("pkg").Approvers("everyone")
+	d.Filter(`_test\.go$`).Reviewers = []string{"tester"}
+
+	data, err := d.Marshal()
+	require.NoError(t, err)
+
+	full, err := repoowners.ParseFullConfig(data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"everyone"}, full.Filters[".*"].Approvers)
+	assert.Equal(t, []string{"tester"}, full.Filters[`_test\.go$`].Reviewers)
+}
+
+func TestWriteTo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "owners-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tree := owners.NewTree()
+	tree.Dir("").Approvers("root-approver")
+	tree.Dir("a/b").Approvers("nested-approver")
+
+	written, err := tree.WriteTo(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"OWNERS", "a/b/OWNERS"}, written)
+	assert.FileExists(t, filepath.Join(dir, "a", "b", "OWNERS"))
+}
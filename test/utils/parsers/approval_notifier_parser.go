@@ -0,0 +1,121 @@
+package parsers
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ApprovalNotifierPrefix is the prefix on the comment Lighthouse keeps up to date with the approval state of a PR
+	ApprovalNotifierPrefix = "[APPROVALNOTIFIER]"
+)
+
+var (
+	approvedByRegex       = regexp.MustCompile(`(?m)^This pull-request has been approved by:(.*)$`)
+	approverNameRegex     = regexp.MustCompile(`\[([^\]]+)\]\(`)
+	approvedFileRegex     = regexp.MustCompile(`(?m)^- ~~\[([^\]]+)\]\(([^)]*)\)~~ \[([^\]]*)\]\s*$`)
+	unapprovedFileRegex   = regexp.MustCompile(`(?m)^- \*\*\[([^\]]+)\]\(([^)]*)\)\*\*\s*$`)
+	approvalMetadataRegex = regexp.MustCompile(`<!-- META=(.*) -->`)
+)
+
+// ApprovalNotifier is the parsed content of the APPROVALNOTIFIER comment
+type ApprovalNotifier struct {
+	Approved bool
+	// ApprovedBy are the users who have approved the PR
+	ApprovedBy []string
+	// SuggestedApprovers are the approvers Lighthouse suggests assigning to complete approval
+	SuggestedApprovers []string
+	Files              []ApprovalNotifierFile
+}
+
+// ApprovalNotifierFile is an OWNERS file listed in the APPROVALNOTIFIER comment
+type ApprovalNotifierFile struct {
+	Path      string
+	Url       string
+	Approved  bool
+	Approvers []string
+}
+
+// ParseApprovalNotifier parses the body of the APPROVALNOTIFIER comment
+func ParseApprovalNotifier(s string) (*ApprovalNotifier, error) {
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	idx := strings.Index(s, ApprovalNotifierPrefix)
+	if idx < 0 {
+		return nil, errors.Errorf("could not find %s in comment %s", ApprovalNotifierPrefix, s)
+	}
+	s = s[idx:]
+	firstLine := strings.SplitN(s, "\n", 2)[0]
+	answer := &ApprovalNotifier{
+		Approved: strings.Contains(firstLine, "**APPROVED**"),
+	}
+
+	if parts := approvedByRegex.FindStringSubmatch(s); len(parts) == 2 {
+		for _, name := range approverNameRegex.FindAllStringSubmatch(parts[1], -1) {
+			answer.ApprovedBy = append(answer.ApprovedBy, name[1])
+		}
+		if len(answer.ApprovedBy) == 0 {
+			for _, name := range strings.Split(parts[1], ",") {
+				name = strings.Trim(strings.TrimSpace(name), "*")
+				if name != "" {
+					answer.ApprovedBy = append(answer.ApprovedBy, name)
+				}
+			}
+		}
+	}
+
+	for _, parts := range unapprovedFileRegex.FindAllStringSubmatch(s, -1) {
+		answer.Files = append(answer.Files, ApprovalNotifierFile{
+			Path: parts[1],
+			Url:  parts[2],
+		})
+	}
+	for _, parts := range approvedFileRegex.FindAllStringSubmatch(s, -1) {
+		var approvers []string
+		for _, name := range strings.Split(parts[3], ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				approvers = append(approvers, name)
+			}
+		}
+		answer.Files = append(answer.Files, ApprovalNotifierFile{
+			Path:      parts[1],
+			Url:       parts[2],
+			Approved:  true,
+			Approvers: approvers,
+		})
+	}
+
+	if parts := approvalMetadataRegex.FindStringSubmatch(s); len(parts) == 2 {
+		metadata := map[string][]string{}
+		err := json.Unmarshal([]byte(parts[1]), &metadata)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing metadata %s from comment %s", parts[1], s)
+		}
+		answer.SuggestedApprovers = metadata["approvers"]
+	}
+	return answer, nil
+}
+
+// File returns the OWNERS file with the given path, or nil if it isn't listed
+func (a *ApprovalNotifier) File(path string) *ApprovalNotifierFile {
+	for i := range a.Files {
+		if a.Files[i].Path == path {
+			return &a.Files[i]
+		}
+	}
+	return nil
+}
+
+// FilesNeedingApproval returns the paths of the OWNERS files that still need approval
+func (a *ApprovalNotifier) FilesNeedingApproval() []string {
+	var answer []string
+	for _, f := range a.Files {
+		if !f.Approved {
+			answer = append(answer, f.Path)
+		}
+	}
+	return answer
+}
@@ -0,0 +1,87 @@
+package parsers_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/parsers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApprovalNotifierParser(t *testing.T) {
+	out := "[APPROVALNOTIFIER] This PR is **NOT APPROVED**\n" + `
+This pull-request has been approved by: *[Bill](REFERENCE "Approved")*
+To complete the [pull request process](https://git.k8s.io/community/contributors/guide/owners.md#the-code-review-process), please assign **alice**
+You can assign the PR to them by writing ` + "`/assign @alice`" + ` in a comment when ready.
+
+The full list of commands accepted by this bot can be found [here](https://go.k8s.io/bot-commands?repo=org%2Frepo).
+
+<details open>
+Needs approval from an approver in each of these files:
+
+- **[a/OWNERS](https://github.com/org/repo/blob/dev/a/OWNERS)**
+- ~~[b/OWNERS](https://github.com/org/repo/blob/dev/b/OWNERS)~~ [Bill]
+
+Approvers can indicate their approval by writing ` + "`/approve`" + ` in a comment
+Approvers can cancel approval by writing ` + "`/approve cancel`" + ` in a comment
+</details>
+<!-- META={"approvers":["alice"]} -->`
+	notifier, err := parsers.ParseApprovalNotifier(out)
+	assert.NoError(t, err)
+	assert.False(t, notifier.Approved)
+	assert.Equal(t, []string{"Bill"}, notifier.ApprovedBy)
+	assert.Equal(t, []string{"alice"}, notifier.SuggestedApprovers)
+	assert.Len(t, notifier.Files, 2)
+	assert.Equal(t, []string{"a/OWNERS"}, notifier.FilesNeedingApproval())
+
+	approved := notifier.File("b/OWNERS")
+	assert.NotNil(t, approved)
+	assert.True(t, approved.Approved)
+	assert.Equal(t, []string{"Bill"}, approved.Approvers)
+	assert.Equal(t, "https://github.com/org/repo/blob/dev/b/OWNERS", approved.Url)
+}
+
+func TestApprovalNotifierParserApproved(t *testing.T) {
+	out := "[APPROVALNOTIFIER] This PR is **APPROVED**\n" + `
+This pull-request has been approved by: *[bdd-approver](https://github.com/org/repo/pull/2#issuecomment-1 "Approved")*
+
+The full list of commands accepted by this bot can be found [here](https://go.k8s.io/bot-commands?repo=org%2Frepo).
+
+<details >
+Needs approval from an approver in each of these files:
+
+- ~~[OWNERS](https://github.com/org/repo/blob/master/OWNERS)~~ [bdd-approver]
+
+</details>
+<!-- META={"approvers":[]} -->`
+	notifier, err := parsers.ParseApprovalNotifier(out)
+	assert.NoError(t, err)
+	assert.True(t, notifier.Approved)
+	assert.Equal(t, []string{"bdd-approver"}, notifier.ApprovedBy)
+	assert.Empty(t, notifier.FilesNeedingApproval())
+	assert.Empty(t, notifier.SuggestedApprovers)
+}
+
+func TestApprovalNotifierParserSeveralApprovers(t *testing.T) {
+	out := "[APPROVALNOTIFIER] This PR is **APPROVED**\n" + `
+This pull-request has been approved by: *[alice](https://github.com/org/repo/pull/3#issuecomment-1 "Approved")*, *[bob](https://github.com/org/repo/pull/3#issuecomment-2 "Approved")*
+
+<details >
+Needs approval from an approver in each of these files:
+
+- ~~[OWNERS](https://github.com/org/repo/blob/master/OWNERS)~~ [alice, bob]
+
+</details>
+<!-- META={"approvers":[]} -->`
+	notifier, err := parsers.ParseApprovalNotifier(out)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, notifier.ApprovedBy)
+	approved := notifier.File("OWNERS")
+	if assert.NotNil(t, approved) {
+		assert.Equal(t, []string{"alice", "bob"}, approved.Approvers)
+	}
+}
+
+func TestApprovalNotifierParserMissing(t *testing.T) {
+	_, err := parsers.ParseApprovalNotifier("LGTM")
+	assert.Error(t, err)
+}
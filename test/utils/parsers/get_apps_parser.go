@@ -0,0 +1,92 @@
+package parsers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// appsStartRegex matches the start of the output of jx get apps -o json or -o yaml
+var appsStartRegex = regexp.MustCompile(`(?m)^(\{|items:)`)
+
+// App is an app as listed by jx get apps -o json or -o yaml
+type App struct {
+	Name            string `json:"appName"`
+	Version         string `json:"version"`
+	Description     string `json:"description"`
+	ChartRepository string `json:"chartRepository"`
+	Status          string `json:"status"`
+	Namespace       string `json:"namespace"`
+	// Values are the values the app is installed with, which are only listed by versions of jx that print them
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+type appsOutput struct {
+	Items []App `json:"items"`
+}
+
+// ParseJxGetApps parses the output of jx get apps -o json or -o yaml, returning the apps by name. Any log lines
+// printed before the structured output are ignored.
+func ParseJxGetApps(s string) (map[string]App, error) {
+	answer := map[string]App{}
+	if strings.Contains(s, "No Apps found") {
+		return answer, nil
+	}
+	loc := appsStartRegex.FindStringIndex(s)
+	if loc == nil {
+		return nil, errors.Errorf("no apps found in output %s", s)
+	}
+	output := appsOutput{}
+	err := yaml.Unmarshal([]byte(s[loc[0]:]), &output)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing apps in output %s", s)
+	}
+	for _, app := range output.Items {
+		answer[app.Name] = app
+	}
+	return answer, nil
+}
+
+// ExpectValues returns an error listing the given values the app is not installed with. Keys of nested values are
+// separated by dots, as they are for helm --set.
+func (a App) ExpectValues(expected map[string]string) error {
+	var keys []string
+	for k := range expected {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var problems []string
+	for _, k := range keys {
+		actual, ok := lookupValue(a.Values, k)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is not set", k))
+		} else if actual != expected[k] {
+			problems = append(problems, fmt.Sprintf("%s is %s but expected %s", k, actual, expected[k]))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.Errorf("values of app %s: %s", a.Name, strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// lookupValue returns the value with the given dotted key formatted as helm --set would take it
+func lookupValue(values map[string]interface{}, key string) (string, bool) {
+	parts := strings.Split(key, ".")
+	var value interface{} = values
+	for _, part := range parts {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		value, ok = m[part]
+		if !ok {
+			return "", false
+		}
+	}
+	return fmt.Sprint(value), true
+}
@@ -0,0 +1,82 @@
+package parsers_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/parsers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJxGetAppsYaml(t *testing.T) {
+	out := `WARNING: There was a problem obtaining the app status: tiller not found
+items:
+- appName: bdd-app
+  chartRepository: http://127.0.0.1:41235
+  description: A chart installed as an app by the BDD tests
+  namespace: jx
+  status: DEPLOYED
+  version: 0.0.1
+- appName: jx-app-jacoco
+  chartRepository: http://chartmuseum.jenkins-x.io
+  description: ""
+  namespace: jx
+  status: ""
+  version: 0.0.139
+`
+	apps, err := parsers.ParseJxGetApps(out)
+	require.NoError(t, err)
+	assert.Len(t, apps, 2)
+	assert.Equal(t, parsers.App{
+		Name:            "bdd-app",
+		Version:         "0.0.1",
+		Description:     "A chart installed as an app by the BDD tests",
+		ChartRepository: "http://127.0.0.1:41235",
+		Status:          "DEPLOYED",
+		Namespace:       "jx",
+	}, apps["bdd-app"])
+	assert.Equal(t, "0.0.139", apps["jx-app-jacoco"].Version)
+}
+
+func TestParseJxGetAppsJson(t *testing.T) {
+	out := `{"items":[{"appName":"bdd-app","version":"0.0.2","description":"","chartRepository":"http://127.0.0.1:41235","status":"DEPLOYED","namespace":"jx"}]}`
+	apps, err := parsers.ParseJxGetApps(out)
+	require.NoError(t, err)
+	assert.Len(t, apps, 1)
+	assert.Equal(t, "0.0.2", apps["bdd-app"].Version)
+	assert.Equal(t, "DEPLOYED", apps["bdd-app"].Status)
+}
+
+func TestParseJxGetAppsValues(t *testing.T) {
+	out := `items:
+- appName: bdd-app
+  chartRepository: http://127.0.0.1:41235
+  namespace: jx
+  status: DEPLOYED
+  version: 0.0.1
+  values:
+    greeting: hello-from-bdd
+    replicas: 2
+    service:
+      port: 8080
+`
+	apps, err := parsers.ParseJxGetApps(out)
+	require.NoError(t, err)
+	app := apps["bdd-app"]
+	assert.NoError(t, app.ExpectValues(map[string]string{"greeting": "hello-from-bdd", "replicas": "2", "service.port": "8080"}))
+	assert.EqualError(t, app.ExpectValues(map[string]string{"greeting": "hello", "missing": "x", "service.port.name": "http"}),
+		"values of app bdd-app: greeting is hello-from-bdd but expected hello, missing is not set, service.port.name is not set")
+
+	apps, err = parsers.ParseJxGetApps(`{"items":[{"appName":"bdd-app","version":"0.0.2","values":{"replicas":1}}]}`)
+	require.NoError(t, err)
+	assert.NoError(t, apps["bdd-app"].ExpectValues(map[string]string{"replicas": "1"}))
+}
+
+func TestParseJxGetAppsNone(t *testing.T) {
+	apps, err := parsers.ParseJxGetApps("No Apps found\n")
+	require.NoError(t, err)
+	assert.Empty(t, apps)
+
+	_, err = parsers.ParseJxGetApps("error: unable to connect to the cluster")
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package pipelines
+
+import (
+	"fmt"
+
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+)
+
+// IsTerminal returns true if a PipelineActivity with the given status has finished running
+func IsTerminal(status v1.ActivityStatusType) bool {
+	switch status {
+	case v1.ActivityStatusTypeSucceeded, v1.ActivityStatusTypeFailed, v1.ActivityStatusTypeError, v1.ActivityStatusTypeAborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// VerifyTerminalStatus returns an error unless the activity has finished with the expected status. The second result
+// is true once the activity has finished, so that callers know whether waiting longer could change the outcome.
+func VerifyTerminalStatus(activity *v1.PipelineActivity, expected v1.ActivityStatusType) (bool, error) {
+	status := activity.Spec.Status
+	if !IsTerminal(status) {
+		return false, fmt.Errorf("PipelineActivity %s is still %s", activity.Name, statusOrPending(status))
+	}
+	if status != expected {
+		return true, fmt.Errorf("PipelineActivity %s finished with status %s rather than %s", activity.Name, status, expected)
+	}
+	return true, nil
+}
+
+// VerifyNotPromoted returns an error if the activity started promoting to any environment
+func VerifyNotPromoted(activity *v1.PipelineActivity) error {
+	for _, step := range activity.Spec.Steps {
+		if step.Promote == nil {
+			continue
+		}
+		if step.Promote.PullRequest != nil && step.Promote.PullRequest.PullRequestURL != "" {
+			return fmt.Errorf("PipelineActivity %s opened promotion pull request %s to %s", activity.Name, step.Promote.PullRequest.PullRequestURL, step.Promote.Environment)
+		}
+		if step.Promote.Status == v1.ActivityStatusTypeSucceeded {
+			return fmt.Errorf("PipelineActivity %s promoted to %s", activity.Name, step.Promote.Environment)
+		}
+	}
+	return nil
+}
+
+func statusOrPending(status v1.ActivityStatusType) string {
+	if status == "" {
+		return string(v1.ActivityStatusTypePending)
+	}
+	return string(status)
+}
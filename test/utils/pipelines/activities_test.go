@@ -0,0 +1,58 @@
+package pipelines_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/pipelines"
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVerifyTerminalStatus(t *testing.T) {
+	tests := []struct {
+		status   v1.ActivityStatusType
+		expected v1.ActivityStatusType
+		done     bool
+		err      string
+	}{
+		{status: "", expected: v1.ActivityStatusTypeFailed, err: "PipelineActivity myorg-myapp-master-3 is still Pending"},
+		{status: v1.ActivityStatusTypeRunning, expected: v1.ActivityStatusTypeFailed, err: "PipelineActivity myorg-myapp-master-3 is still Running"},
+		{status: v1.ActivityStatusTypeFailed, expected: v1.ActivityStatusTypeFailed, done: true},
+		{status: v1.ActivityStatusTypeAborted, expected: v1.ActivityStatusTypeAborted, done: true},
+		{status: v1.ActivityStatusTypeSucceeded, expected: v1.ActivityStatusTypeFailed, done: true, err: "PipelineActivity myorg-myapp-master-3 finished with status Succeeded rather than Failed"},
+	}
+	for _, tt := range tests {
+		activity := &v1.PipelineActivity{
+			ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp-master-3"},
+			Spec:       v1.PipelineActivitySpec{Status: tt.status},
+		}
+		done, err := pipelines.VerifyTerminalStatus(activity, tt.expected)
+		assert.Equal(t, tt.done, done, "status %s", tt.status)
+		if tt.err == "" {
+			assert.NoError(t, err, "status %s", tt.status)
+		} else {
+			assert.EqualError(t, err, tt.err)
+		}
+	}
+}
+
+func TestVerifyNotPromoted(t *testing.T) {
+	activity := &v1.PipelineActivity{
+		ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp-master-3"},
+		Spec: v1.PipelineActivitySpec{
+			Steps: []v1.PipelineActivityStep{
+				{Kind: v1.ActivityStepKindTypeStage, Stage: &v1.StageActivityStep{}},
+				{Kind: v1.ActivityStepKindTypePromote, Promote: &v1.PromoteActivityStep{Environment: "staging"}},
+			},
+		},
+	}
+	assert.NoError(t, pipelines.VerifyNotPromoted(activity))
+
+	activity.Spec.Steps[1].Promote.PullRequest = &v1.PromotePullRequestStep{PullRequestURL: "https://github.com/myorg/environment-staging/pull/7"}
+	assert.EqualError(t, pipelines.VerifyNotPromoted(activity), "PipelineActivity myorg-myapp-master-3 opened promotion pull request https://github.com/myorg/environment-staging/pull/7 to staging")
+
+	activity.Spec.Steps[1].Promote.PullRequest = nil
+	activity.Spec.Steps[1].Promote.Status = v1.ActivityStatusTypeSucceeded
+	assert.EqualError(t, pipelines.VerifyNotPromoted(activity), "PipelineActivity myorg-myapp-master-3 promoted to staging")
+}
@@ -0,0 +1,258 @@
+package pipelines
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/jenkins-x/jx/v2/pkg/config"
+	"github.com/jenkins-x/jx/v2/pkg/jenkinsfile"
+	"github.com/jenkins-x/jx/v2/pkg/tekton/syntax"
+	"github.com/jenkins-x/jx/v2/pkg/util"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ProjectConfigFileName is the name of the file in the root of a repo holding its pipeline configuration
+	ProjectConfigFileName = config.ProjectConfigFileName
+
+	// PullRequest is the name of the pipeline run for pull requests
+	PullRequest = "pullRequest"
+	// Release is the name of the pipeline run for merges to master
+	Release = "release"
+
+	// Setup is the lifecycle of a build pack pipeline which checks out the source
+	Setup = "setup"
+	// SetVersion is the lifecycle of a build pack pipeline which works out the version to build
+	SetVersion = "setVersion"
+	// PreBuild is the lifecycle of a build pack pipeline run before the build
+	PreBuild = "preBuild"
+	// Build is the lifecycle of a build pack pipeline which builds the application
+	Build = "build"
+	// PostBuild is the lifecycle of a build pack pipeline run after the build
+	PostBuild = "postBuild"
+	// Promote is the lifecycle of a build pack pipeline which promotes or previews the application
+	Promote = "promote"
+
+	// StepPrefix is the prefix of the names of the steps added by the builder
+	StepPrefix = "bdd-"
+
+	// timeoutImage is the image of the pipelines which exceed their timeout
+	timeoutImage = "busybox"
+)
+
+// Config builds a jenkins-x.yml which uses a build pack and overrides steps of its pipelines, or replaces them
+type Config struct {
+	buildPack string
+	env       []corev1.EnvVar
+	overrides []*syntax.PipelineOverride
+	// pipelines replace the pipelines of the build pack with the same name
+	pipelines map[string]*syntax.ParsedPipeline
+}
+
+// Pipeline builds the overrides of a single pipeline of a Config
+type Pipeline struct {
+	config *Config
+	name   string
+}
+
+// NewConfig creates a Config using the given build pack without any overrides
+func NewConfig(buildPack string) *Config {
+	return &Config{buildPack: buildPack, pipelines: map[string]*syntax.ParsedPipeline{}}
+}
+
+// Env sets an environment variable for every step of every pipeline
+func (c *Config) Env(name string, value string) *Config {
+	for i := range c.env {
+		if c.env[i].Name == name {
+			c.env[i].Value = value
+			return c
+		}
+	}
+	c.env = append(c.env, corev1.EnvVar{Name: name, Value: value})
+	return c
+}
+
+// Pipeline returns the builder for the overrides of the pipeline with the given name, such as PullRequest or Release
+func (c *Config) Pipeline(name string) *Pipeline {
+	return &Pipeline{config: c, name: name}
+}
+
+// PullRequest returns the builder for the overrides of the pull request pipeline
+func (c *Config) PullRequest() *Pipeline {
+	return c.Pipeline(PullRequest)
+}
+
+// Release returns the builder for the overrides of the release pipeline
+func (c *Config) Release() *Pipeline {
+	return c.Pipeline(Release)
+}
+
+// ProjectConfig returns the project configuration for the jenkins-x.yml
+func (c *Config) ProjectConfig() *config.ProjectConfig {
+	answer := &config.ProjectConfig{
+		BuildPack: c.buildPack,
+	}
+	if len(c.env) > 0 || len(c.overrides) > 0 || len(c.pipelines) > 0 {
+		answer.PipelineConfig = &jenkinsfile.PipelineConfig{
+			Env: c.env,
+			Pipelines: jenkinsfile.Pipelines{
+				PullRequest: c.lifecycles(PullRequest),
+				Release:     c.lifecycles(Release),
+				Overrides:   c.overrides,
+			},
+		}
+	}
+	return answer
+}
+
+func (c *Config) lifecycles(name string) *jenkinsfile.PipelineLifecycles {
+	if parsed, ok := c.pipelines[name]; ok {
+		return &jenkinsfile.PipelineLifecycles{Pipeline: parsed}
+	}
+	return nil
+}
+
+// Marshal returns the YAML of the jenkins-x.yml, failing if it does not validate against the project config schema
+func (c *Config) Marshal() ([]byte, error) {
+	data, err := yaml.Marshal(c.ProjectConfig())
+	if err != nil {
+		return nil, errors.Wrapf(err, "marshalling %s", ProjectConfigFileName)
+	}
+	validationErrors, err := util.ValidateYaml(&config.ProjectConfig{}, data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "validating %s", ProjectConfigFileName)
+	}
+	if len(validationErrors) > 0 {
+		return nil, fmt.Errorf("invalid %s:\n%s", ProjectConfigFileName, strings.Join(validationErrors, "\n"))
+	}
+	return data, nil
+}
+
+// WriteTo writes the jenkins-x.yml into the given repo directory, replacing any existing one, and returns its path
+// relative to the directory
+func (c *Config) WriteTo(dir string) (string, error) {
+	data, err := c.Marshal()
+	if err != nil {
+		return "", err
+	}
+	fileName := filepath.Join(dir, ProjectConfigFileName)
+	err = ioutil.WriteFile(fileName, data, utils.DefaultWritePermissions)
+	if err != nil {
+		return "", errors.Wrapf(err, "writing %s", fileName)
+	}
+	return ProjectConfigFileName, nil
+}
+
+// ReplaceStep replaces the step with the given name in every lifecycle of the pipeline with the given steps
+func (p *Pipeline) ReplaceStep(name string, steps ...*syntax.Step) *Pipeline {
+	return p.override("", name, syntax.StepOverrideReplace, steps)
+}
+
+// BeforeStep adds the given steps before the step with the given name in every lifecycle of the pipeline
+func (p *Pipeline) BeforeStep(name string, steps ...*syntax.Step) *Pipeline {
+	return p.override("", name, syntax.StepOverrideBefore, steps)
+}
+
+// AfterStep adds the given steps after the step with the given name in every lifecycle of the pipeline
+func (p *Pipeline) AfterStep(name string, steps ...*syntax.Step) *Pipeline {
+	return p.override("", name, syntax.StepOverrideAfter, steps)
+}
+
+// ReplaceLifecycle replaces all the steps of the given lifecycle of the pipeline, such as Build, with the given steps
+func (p *Pipeline) ReplaceLifecycle(lifecycle string, steps ...*syntax.Step) *Pipeline {
+	return p.override(lifecycle, "", syntax.StepOverrideReplace, steps)
+}
+
+// Before adds the given steps before the steps of the given lifecycle of the pipeline
+func (p *Pipeline) Before(lifecycle string, steps ...*syntax.Step) *Pipeline {
+	return p.override(lifecycle, "", syntax.StepOverrideBefore, steps)
+}
+
+// After adds the given steps after the steps of the given lifecycle of the pipeline
+func (p *Pipeline) After(lifecycle string, steps ...*syntax.Step) *Pipeline {
+	return p.override(lifecycle, "", syntax.StepOverrideAfter, steps)
+}
+
+// Fail makes the given lifecycle of the pipeline fail after running its steps. As it does not depend on the names of
+// the steps of a build pack it works the same for every build pack.
+func (p *Pipeline) Fail(lifecycle string) *Pipeline {
+	return p.After(lifecycle, Sh("fail", "exit 1"))
+}
+
+// FailStep replaces the step with the given name with one which fails after the given delay
+func (p *Pipeline) FailStep(name string, delay time.Duration) *Pipeline {
+	return p.ReplaceStep(name, Sh("fail", fmt.Sprintf("sleep %d && exit 1", seconds(delay))))
+}
+
+// FailBuildsBefore makes the given lifecycle of the pipeline fail for the builds numbered lower than the given build
+// number, so that retrying a failed build eventually succeeds
+func (p *Pipeline) FailBuildsBefore(lifecycle string, build int) *Pipeline {
+	return p.Before(lifecycle, Sh("fail-before-build", fmt.Sprintf(`[ "${BUILD_NUMBER:-0}" -ge %d ] || exit 1`, build)))
+}
+
+// Delay makes the given lifecycle of the pipeline wait for the given duration before running its steps
+func (p *Pipeline) Delay(lifecycle string, delay time.Duration) *Pipeline {
+	return p.Before(lifecycle, Sh("delay", fmt.Sprintf("sleep %d", seconds(delay))))
+}
+
+// ExceedTimeout replaces the pipeline with one which has the given timeout and a step sleeping for longer, so that
+// Tekton times out its PipelineRun. jx only supports timeouts of whole pipelines, not of build pack pipelines or of
+// stages, so the pipeline does not use the build pack.
+func (p *Pipeline) ExceedTimeout(timeout time.Duration) *Pipeline {
+	limit := seconds(timeout)
+	p.config.pipelines[p.name] = &syntax.ParsedPipeline{
+		Agent: &syntax.Agent{Image: timeoutImage},
+		Options: &syntax.RootOptions{
+			Timeout: &syntax.Timeout{Time: limit, Unit: syntax.TimeoutUnitSeconds},
+		},
+		Stages: []syntax.Stage{{
+			Name:  StepPrefix + "timeout",
+			Steps: []syntax.Step{*Sh("timeout", fmt.Sprintf("sleep %d", limit*10))},
+		}},
+	}
+	return p
+}
+
+// ExpectEnv makes the given lifecycle of the pipeline fail unless its steps see the environment variable with the
+// given value
+func (p *Pipeline) ExpectEnv(lifecycle string, name string, value string) *Pipeline {
+	return p.Before(lifecycle, Sh("expect-env", fmt.Sprintf(`[ "${%s}" = %s ] || exit 1`, name, shellQuote(value))))
+}
+
+func (p *Pipeline) override(lifecycle string, name string, overrideType syntax.StepOverrideType, steps []*syntax.Step) *Pipeline {
+	p.config.overrides = append(p.config.overrides, &syntax.PipelineOverride{
+		Pipeline: p.name,
+		Stage:    lifecycle,
+		Name:     name,
+		// jx only adds the steps of overrides of whole lifecycles after them when they are given as a list
+		Steps: steps,
+		Type:  &overrideType,
+	})
+	return p
+}
+
+// Sh returns a step running the given shell command, with its name prefixed by StepPrefix
+func Sh(name string, command string) *syntax.Step {
+	return &syntax.Step{
+		Name: StepPrefix + name,
+		Sh:   command,
+	}
+}
+
+func seconds(d time.Duration) int64 {
+	answer := int64(d / time.Second)
+	if answer < 1 {
+		answer = 1
+	}
+	return answer
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
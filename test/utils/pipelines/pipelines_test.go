@@ -0,0 +1,177 @@
+package pipelines_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/pipelines"
+	"github.com/jenkins-x/jx/v2/pkg/config"
+	"github.com/jenkins-x/jx/v2/pkg/jenkinsfile"
+	"github.com/jenkins-x/jx/v2/pkg/tekton/syntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteToLoadsAsProjectConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipelines")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := pipelines.NewConfig("go").Env("GREETING", "hello").Env("GREETING", "it's me")
+	c.PullRequest().FailStep("make-linux", 15*time.Second)
+
+	written, err := c.WriteTo(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "jenkins-x.yml", written)
+
+	loaded, err := config.LoadProjectConfigFile(filepath.Join(dir, written))
+	require.NoError(t, err)
+	assert.Equal(t, "go", loaded.BuildPack)
+	require.NotNil(t, loaded.PipelineConfig)
+	assert.Equal(t, "it's me", loaded.PipelineConfig.Env[0].Value)
+	assert.Len(t, loaded.PipelineConfig.Env, 1)
+
+	overrides := loaded.PipelineConfig.Pipelines.Overrides
+	require.Len(t, overrides, 1)
+	assert.Equal(t, "pullRequest", overrides[0].Pipeline)
+	assert.Equal(t, "make-linux", overrides[0].Name)
+	require.Len(t, overrides[0].Steps, 1)
+	assert.Equal(t, "sleep 15 && exit 1", overrides[0].Steps[0].Sh)
+}
+
+func TestWithoutOverrides(t *testing.T) {
+	data, err := pipelines.NewConfig("javascript").Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, "buildPack: javascript\n", string(data))
+}
+
+func TestOverridesApplyToBuildPack(t *testing.T) {
+	tests := []struct {
+		name        string
+		build       func(c *pipelines.Config)
+		pullRequest []string
+		release     []string
+	}{
+		{
+			name:        "fail lifecycle",
+			build:       func(c *pipelines.Config) { c.PullRequest().Fail(pipelines.Build) },
+			pullRequest: []string{"make-linux", "skaffold", "bdd-fail"},
+			release:     []string{"make-linux", "skaffold"},
+		},
+		{
+			name:        "fail step",
+			build:       func(c *pipelines.Config) { c.Release().FailStep("make-linux", 0) },
+			pullRequest: []string{"make-linux", "skaffold"},
+			release:     []string{"bdd-fail", "skaffold"},
+		},
+		{
+			name: "retry and delay",
+			build: func(c *pipelines.Config) {
+				c.PullRequest().FailBuildsBefore(pipelines.Build, 2).Delay(pipelines.Build, time.Minute)
+			},
+			pullRequest: []string{"bdd-delay", "bdd-fail-before-build", "make-linux", "skaffold"},
+			release:     []string{"make-linux", "skaffold"},
+		},
+		{
+			name: "steps around a step",
+			build: func(c *pipelines.Config) {
+				c.PullRequest().BeforeStep("skaffold", pipelines.Sh("before", "true")).AfterStep("make-linux", pipelines.Sh("after", "true"))
+			},
+			pullRequest: []string{"make-linux", "bdd-after", "bdd-before", "skaffold"},
+			release:     []string{"make-linux", "skaffold"},
+		},
+		{
+			name: "replace lifecycle in both pipelines",
+			build: func(c *pipelines.Config) {
+				c.PullRequest().ReplaceLifecycle(pipelines.Build, pipelines.Sh("build", "make"))
+				c.Release().ExpectEnv(pipelines.Build, "GREETING", "hello")
+			},
+			pullRequest: []string{"bdd-build"},
+			release:     []string{"bdd-expect-env", "make-linux", "skaffold"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := pipelines.NewConfig("go")
+			tt.build(c)
+			project := c.ProjectConfig()
+			require.NotNil(t, project.PipelineConfig)
+
+			pipes := project.PipelineConfig.Pipelines
+			err := pipes.Extend(buildPack())
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.pullRequest, stepNames(pipes.PullRequest.Build), "pullRequest build steps")
+			assert.Equal(t, tt.release, stepNames(pipes.Release.Build), "release build steps")
+		})
+	}
+}
+
+func TestExceedTimeoutReplacesPipeline(t *testing.T) {
+	dir := t.TempDir()
+	c := pipelines.NewConfig("go")
+	c.Release().ExceedTimeout(90 * time.Second)
+	written, err := c.WriteTo(dir)
+	require.NoError(t, err)
+
+	loaded, err := config.LoadProjectConfigFile(filepath.Join(dir, written))
+	require.NoError(t, err)
+	pipes := loaded.PipelineConfig.Pipelines
+	assert.Nil(t, pipes.PullRequest)
+	require.NotNil(t, pipes.Release)
+	parsed := pipes.Release.Pipeline
+	require.NotNil(t, parsed)
+
+	timeout, err := parsed.Options.Timeout.ToDuration()
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, timeout.Duration)
+	require.Len(t, parsed.Stages, 1)
+	require.Len(t, parsed.Stages[0].Steps, 1)
+	assert.Equal(t, "sleep 900", parsed.Stages[0].Steps[0].Sh)
+
+	// the pipeline replaces the one of the build pack
+	err = pipes.Extend(buildPack())
+	require.NoError(t, err)
+	assert.Equal(t, parsed, pipes.Release.Pipeline)
+	assert.Nil(t, pipes.PullRequest.Pipeline)
+}
+
+func TestExpectEnvQuotesValue(t *testing.T) {
+	c := pipelines.NewConfig("go")
+	c.Release().ExpectEnv(pipelines.Build, "NAME", "it's")
+	overrides := c.ProjectConfig().PipelineConfig.Pipelines.Overrides
+	require.Len(t, overrides, 1)
+	assert.Equal(t, `[ "${NAME}" = 'it'\''s' ] || exit 1`, overrides[0].Steps[0].Sh)
+}
+
+// buildPack returns pipelines like those of the go build pack
+func buildPack() *jenkinsfile.Pipelines {
+	lifecycles := func() *jenkinsfile.PipelineLifecycles {
+		return &jenkinsfile.PipelineLifecycles{
+			Build: &jenkinsfile.PipelineLifecycle{
+				Steps: []*syntax.Step{
+					{Name: "make-linux", Sh: "make linux"},
+					{Name: "skaffold", Sh: "skaffold build"},
+				},
+			},
+		}
+	}
+	return &jenkinsfile.Pipelines{
+		PullRequest: lifecycles(),
+		Release:     lifecycles(),
+	}
+}
+
+func stepNames(lifecycle *jenkinsfile.PipelineLifecycle) []string {
+	var answer []string
+	if lifecycle != nil {
+		for _, s := range lifecycle.Steps {
+			answer = append(answer, s.Name)
+		}
+	}
+	return answer
+}
@@ -0,0 +1,56 @@
+package pipelines
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/jx/v2/pkg/tekton"
+	tektonv1alpha1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"knative.dev/pkg/apis"
+)
+
+// ReasonTimedOut is the reason of the Succeeded condition of a PipelineRun which Tekton stopped as it ran for longer
+// than its timeout
+const ReasonTimedOut = "PipelineRunTimeout"
+
+// BuildSelector returns the label selector of the PipelineRuns of a build of a branch of a repository, or of all the
+// builds of the branch if the build is empty
+func BuildSelector(owner string, repo string, branch string, build string) string {
+	set := labels.Set{
+		tekton.LabelOwner:  owner,
+		tekton.LabelRepo:   repo,
+		tekton.LabelBranch: branch,
+	}
+	if build != "" {
+		set[tekton.LabelBuild] = build
+	}
+	return labels.SelectorFromSet(set).String()
+}
+
+// VerifyTimedOut returns an error unless one of the PipelineRuns of a build was timed out by Tekton. The second result
+// is true once one was timed out or all of them have finished, so that callers know whether waiting longer could
+// change the outcome.
+func VerifyTimedOut(runs []tektonv1alpha1.PipelineRun) (bool, error) {
+	if len(runs) == 0 {
+		return false, fmt.Errorf("no PipelineRuns found")
+	}
+	var descriptions []string
+	finished := true
+	for _, run := range runs {
+		condition := run.Status.GetCondition(apis.ConditionSucceeded)
+		if condition == nil || condition.Status == corev1.ConditionUnknown {
+			finished = false
+			descriptions = append(descriptions, fmt.Sprintf("%s is running", run.Name))
+			continue
+		}
+		if condition.Status == corev1.ConditionFalse && condition.Reason == ReasonTimedOut {
+			return true, nil
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%s finished with reason %s: %s", run.Name, condition.Reason, condition.Message))
+	}
+	sort.Strings(descriptions)
+	return finished, fmt.Errorf("no PipelineRun timed out: %s", strings.Join(descriptions, ", "))
+}
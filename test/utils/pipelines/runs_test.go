@@ -0,0 +1,74 @@
+package pipelines_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/pipelines"
+	"github.com/stretchr/testify/assert"
+	tektonv1alpha1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"knative.dev/pkg/apis"
+)
+
+func pipelineRun(name string, status corev1.ConditionStatus, reason string) tektonv1alpha1.PipelineRun {
+	run := tektonv1alpha1.PipelineRun{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if status != "" {
+		run.Status.SetCondition(&apis.Condition{Type: apis.ConditionSucceeded, Status: status, Reason: reason})
+	}
+	return run
+}
+
+func TestBuildSelector(t *testing.T) {
+	selector, err := labels.Parse(pipelines.BuildSelector("jenkins-x-tests", "bdd-app", "master", "3"))
+	assert.NoError(t, err)
+	assert.True(t, selector.Matches(labels.Set{"owner": "jenkins-x-tests", "repository": "bdd-app", "branch": "master", "build": "3", "context": "release"}))
+	assert.False(t, selector.Matches(labels.Set{"owner": "jenkins-x-tests", "repository": "bdd-app", "branch": "master", "build": "4"}))
+
+	selector, err = labels.Parse(pipelines.BuildSelector("jenkins-x-tests", "bdd-app", "PR-1", ""))
+	assert.NoError(t, err)
+	assert.True(t, selector.Matches(labels.Set{"owner": "jenkins-x-tests", "repository": "bdd-app", "branch": "PR-1", "build": "4"}))
+	assert.False(t, selector.Matches(labels.Set{"owner": "jenkins-x-tests", "repository": "bdd-app", "branch": "master", "build": "4"}))
+}
+
+func TestVerifyTimedOut(t *testing.T) {
+	tests := []struct {
+		name     string
+		runs     []tektonv1alpha1.PipelineRun
+		finished bool
+		timedOut bool
+	}{
+		{name: "no runs"},
+		{
+			name:     "timed out",
+			runs:     []tektonv1alpha1.PipelineRun{pipelineRun("meta", corev1.ConditionTrue, "Succeeded"), pipelineRun("release", corev1.ConditionFalse, pipelines.ReasonTimedOut)},
+			finished: true,
+			timedOut: true,
+		},
+		{
+			name: "still running",
+			runs: []tektonv1alpha1.PipelineRun{pipelineRun("meta", corev1.ConditionTrue, "Succeeded"), pipelineRun("release", corev1.ConditionUnknown, "Running")},
+		},
+		{
+			name: "not started",
+			runs: []tektonv1alpha1.PipelineRun{pipelineRun("release", "", "")},
+		},
+		{
+			name:     "failed without timing out",
+			runs:     []tektonv1alpha1.PipelineRun{pipelineRun("release", corev1.ConditionFalse, "Failed")},
+			finished: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finished, err := pipelines.VerifyTimedOut(tt.runs)
+			assert.Equal(t, tt.finished, finished)
+			if tt.timedOut {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
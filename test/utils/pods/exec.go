@@ -0,0 +1,42 @@
+package pods
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// Exec runs a command in a container of a pod, returning its standard output and error. It fails if the command
+// cannot be run or exits with a non zero exit code.
+func Exec(config *rest.Config, client kubernetes.Interface, ns string, pod string, container string, command ...string) (string, string, error) {
+	req := client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(ns).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+	if err != nil {
+		return "", "", errors.Wrapf(err, "creating an executor for pod %s in namespace %s", pod, ns)
+	}
+	var stdout, stderr bytes.Buffer
+	err = executor.Stream(remotecommand.StreamOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return stdout.String(), stderr.String(), errors.Wrapf(err, "running %s in container %s of pod %s: %s", strings.Join(command, " "), container, pod, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), stderr.String(), nil
+}
@@ -0,0 +1,221 @@
+package pods
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// WaitForReady watches the pods in the namespace matching the label selector, and whose name ends with the suffix if
+// any, until one of them is ready, returning it. It fails if a matching pod terminates or none is ready within the
+// timeout, describing the matching pods and their events.
+func WaitForReady(client kubernetes.Interface, ns string, selector string, suffix string, timeout time.Duration) (*corev1.Pod, error) {
+	var ready *corev1.Pod
+	hasSuffix := func(pod *corev1.Pod) bool {
+		return strings.HasSuffix(pod.Name, suffix)
+	}
+	err := waitFor(client, ns, selector, hasSuffix, timeout, func(pods map[string]*corev1.Pod) (bool, error) {
+		for _, name := range sortedNames(pods) {
+			pod := pods[name]
+			if pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded {
+				return false, errors.Errorf("pod %s terminated before it was ready: %s", name, Describe(pod))
+			}
+			if IsReady(pod) {
+				ready = pod
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "waiting for a pod matching %s to be ready in namespace %s", describeSelection(selector, suffix), ns)
+	}
+	return ready, nil
+}
+
+// WaitForDeleted watches the pod with the given name in the namespace until it has been deleted. It fails if the pod
+// still exists after the timeout, describing it and its events.
+func WaitForDeleted(client kubernetes.Interface, ns string, name string, timeout time.Duration) error {
+	named := func(pod *corev1.Pod) bool {
+		return pod.Name == name
+	}
+	err := waitFor(client, ns, "", named, timeout, func(pods map[string]*corev1.Pod) (bool, error) {
+		_, ok := pods[name]
+		return !ok, nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "waiting for pod %s to be deleted from namespace %s", name, ns)
+	}
+	return nil
+}
+
+// IsReady returns true if the pod has the Ready condition
+func IsReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// Describe describes the status of a pod, such as "phase Pending, Ready False, container go waiting: ImagePullBackOff"
+func Describe(pod *corev1.Pod) string {
+	parts := []string{"phase " + string(pod.Status.Phase)}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			parts = append(parts, fmt.Sprintf("Ready %s", condition.Status))
+		}
+	}
+	if pod.DeletionTimestamp != nil {
+		parts = append(parts, "terminating")
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		switch {
+		case status.State.Waiting != nil:
+			parts = append(parts, fmt.Sprintf("container %s waiting: %s", status.Name, status.State.Waiting.Reason))
+		case status.State.Terminated != nil:
+			parts = append(parts, fmt.Sprintf("container %s terminated: %s", status.Name, status.State.Terminated.Reason))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Events returns the events of the pod with the given name in the namespace, oldest first, such as
+// "Warning Failed: Error: ImagePullBackOff"
+func Events(client kubernetes.Interface, ns string, name string) ([]string, error) {
+	list, err := client.CoreV1().Events(ns).List(metav1.ListOptions{FieldSelector: "involvedObject.name=" + name})
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing the events of pod %s in namespace %s", name, ns)
+	}
+	var events []corev1.Event
+	for _, event := range list.Items {
+		// not every client filters by field
+		if event.InvolvedObject.Name == name {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastTimestamp.Before(&events[j].LastTimestamp)
+	})
+	var answer []string
+	for _, event := range events {
+		answer = append(answer, fmt.Sprintf("%s %s: %s", event.Type, event.Reason, event.Message))
+	}
+	return answer, nil
+}
+
+// waitFor lists the pods in the namespace matching the label selector and the filter, then watches them until done
+// returns true or an error, or the timeout expires. The watch is restarted if the API server closes it.
+func waitFor(client kubernetes.Interface, ns string, selector string, filter func(*corev1.Pod) bool, timeout time.Duration, done func(map[string]*corev1.Pod) (bool, error)) error {
+	matcher, err := labels.Parse(selector)
+	if err != nil {
+		return errors.Wrapf(err, "parsing label selector %s", selector)
+	}
+	matches := func(pod *corev1.Pod) bool {
+		// not every client filters watch events by label
+		return matcher.Matches(labels.Set(pod.Labels)) && filter(pod)
+	}
+	deadline := time.After(timeout)
+	for {
+		list, err := client.CoreV1().Pods(ns).List(metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return errors.Wrapf(err, "listing pods in namespace %s", ns)
+		}
+		pods := map[string]*corev1.Pod{}
+		for i := range list.Items {
+			if matches(&list.Items[i]) {
+				pods[list.Items[i].Name] = &list.Items[i]
+			}
+		}
+		finished, err := done(pods)
+		if finished || err != nil {
+			return withEvents(client, ns, pods, err)
+		}
+
+		watcher, err := client.CoreV1().Pods(ns).Watch(metav1.ListOptions{LabelSelector: selector, ResourceVersion: list.ResourceVersion})
+		if err != nil {
+			return errors.Wrapf(err, "watching pods in namespace %s", ns)
+		}
+		finished, err = watchUntil(watcher, deadline, pods, matches, done)
+		watcher.Stop()
+		if finished || err != nil {
+			return withEvents(client, ns, pods, err)
+		}
+	}
+}
+
+// watchUntil applies the events of the watcher to the pods until done returns true or an error, the deadline passes,
+// or the watcher is closed, in which case it returns false and no error
+func watchUntil(watcher watch.Interface, deadline <-chan time.Time, pods map[string]*corev1.Pod, matches func(*corev1.Pod) bool, done func(map[string]*corev1.Pod) (bool, error)) (bool, error) {
+	for {
+		select {
+		case <-deadline:
+			return false, errors.New("timed out")
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return false, nil
+			}
+			pod, isPod := event.Object.(*corev1.Pod)
+			if !isPod || !matches(pod) {
+				continue
+			}
+			if event.Type == watch.Deleted {
+				delete(pods, pod.Name)
+			} else {
+				pods[pod.Name] = pod
+			}
+			finished, err := done(pods)
+			if finished || err != nil {
+				return finished, err
+			}
+		}
+	}
+}
+
+// withEvents adds the status and events of the pods to an error
+func withEvents(client kubernetes.Interface, ns string, pods map[string]*corev1.Pod, err error) error {
+	if err == nil {
+		return nil
+	}
+	if len(pods) == 0 {
+		return errors.Wrap(err, "no pods found")
+	}
+	var descriptions []string
+	for _, name := range sortedNames(pods) {
+		description := fmt.Sprintf("pod %s: %s", name, Describe(pods[name]))
+		events, eventsErr := Events(client, ns, name)
+		if eventsErr != nil {
+			description += "\n  " + eventsErr.Error()
+		}
+		for _, event := range events {
+			description += "\n  " + event
+		}
+		descriptions = append(descriptions, description)
+	}
+	return errors.Errorf("%s\n%s", err, strings.Join(descriptions, "\n"))
+}
+
+func describeSelection(selector string, suffix string) string {
+	if suffix == "" {
+		return selector
+	}
+	return fmt.Sprintf("%s with the suffix %s", selector, suffix)
+}
+
+func sortedNames(pods map[string]*corev1.Pod) []string {
+	var names []string
+	for name := range pods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,130 @@
+package pods_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/pods"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const ns = "jx"
+
+func pod(name string, label string, ready corev1.ConditionStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			Labels:    map[string]string{"jenkins.io/pod_template": label},
+		},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+		},
+	}
+}
+
+// fakeClient returns a client holding the objects whose pod watches are driven by the returned watcher
+func fakeClient(objects ...runtime.Object) (*fake.Clientset, *watch.FakeWatcher) {
+	client := fake.NewSimpleClientset(objects...)
+	watcher := watch.NewFake()
+	client.PrependWatchReactor("pods", k8stesting.DefaultWatchReactor(watcher, nil))
+	return client, watcher
+}
+
+func TestWaitForReady(t *testing.T) {
+	client, watcher := fakeClient(
+		pod("bdd-go-devpod", "go", corev1.ConditionFalse),
+		pod("bdd-maven-devpod", "maven", corev1.ConditionTrue),
+		pod("someone-go", "go", corev1.ConditionTrue),
+	)
+	go func() {
+		// changes to pods which do not match are ignored
+		watcher.Modify(pod("bdd-maven-devpod", "maven", corev1.ConditionTrue))
+		watcher.Modify(pod("bdd-go-devpod", "go", corev1.ConditionTrue))
+	}()
+
+	ready, err := pods.WaitForReady(client, ns, "jenkins.io/pod_template=go", "-devpod", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "bdd-go-devpod", ready.Name)
+}
+
+func TestWaitForReadyAlreadyReady(t *testing.T) {
+	client, _ := fakeClient(pod("bdd-go-devpod", "go", corev1.ConditionTrue))
+	ready, err := pods.WaitForReady(client, ns, "jenkins.io/pod_template=go", "", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "bdd-go-devpod", ready.Name)
+}
+
+func TestWaitForReadyFailed(t *testing.T) {
+	client, watcher := fakeClient(pod("bdd-go-devpod", "go", corev1.ConditionFalse))
+	go func() {
+		failed := pod("bdd-go-devpod", "go", corev1.ConditionFalse)
+		failed.Status.Phase = corev1.PodFailed
+		watcher.Modify(failed)
+	}()
+
+	_, err := pods.WaitForReady(client, ns, "jenkins.io/pod_template=go", "-devpod", time.Minute)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pod bdd-go-devpod terminated before it was ready: phase Failed, Ready False")
+}
+
+func TestWaitForReadyTimeout(t *testing.T) {
+	waiting := pod("bdd-go-devpod", "go", corev1.ConditionFalse)
+	waiting.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  "go",
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+	}}
+	event := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "bdd-go-devpod.1", Namespace: ns},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "bdd-go-devpod", Namespace: ns},
+		Type:           corev1.EventTypeWarning,
+		Reason:         "Failed",
+		Message:        "Error: ImagePullBackOff",
+	}
+	client, _ := fakeClient(waiting, event)
+
+	_, err := pods.WaitForReady(client, ns, "jenkins.io/pod_template=go", "-devpod", 50*time.Millisecond)
+	require.Error(t, err)
+	assert.Equal(t, `waiting for a pod matching jenkins.io/pod_template=go with the suffix -devpod to be ready in namespace jx: timed out
+pod bdd-go-devpod: phase Running, Ready False, container go waiting: ImagePullBackOff
+  Warning Failed: Error: ImagePullBackOff`, err.Error())
+}
+
+func TestWaitForReadyNoPods(t *testing.T) {
+	client, _ := fakeClient()
+	_, err := pods.WaitForReady(client, ns, "jenkins.io/pod_template=go", "", 50*time.Millisecond)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no pods found")
+}
+
+func TestWaitForDeleted(t *testing.T) {
+	devpod := pod("bdd-go-devpod", "go", corev1.ConditionTrue)
+	client, watcher := fakeClient(devpod, pod("bdd-maven-devpod", "maven", corev1.ConditionTrue))
+	go func() {
+		watcher.Delete(devpod)
+	}()
+	assert.NoError(t, pods.WaitForDeleted(client, ns, "bdd-go-devpod", time.Minute))
+
+	client, _ = fakeClient()
+	assert.NoError(t, pods.WaitForDeleted(client, ns, "bdd-go-devpod", time.Minute))
+}
+
+func TestWaitForDeletedTimeout(t *testing.T) {
+	terminating := pod("bdd-go-devpod", "go", corev1.ConditionTrue)
+	now := metav1.Now()
+	terminating.DeletionTimestamp = &now
+	client, _ := fakeClient(terminating)
+
+	err := pods.WaitForDeleted(client, ns, "bdd-go-devpod", 50*time.Millisecond)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "waiting for pod bdd-go-devpod to be deleted from namespace jx: timed out")
+	assert.Contains(t, err.Error(), "pod bdd-go-devpod: phase Running, Ready True, terminating")
+}
@@ -0,0 +1,176 @@
+package podtemplates
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// Selector is the label selector of the ConfigMaps holding pod templates
+	Selector = "jenkins.io/kind=podTemplate"
+	// ConfigMapPrefix is the prefix of the names of the ConfigMaps holding pod templates, followed by their label
+	ConfigMapPrefix = "jenkins-x-pod-template-"
+)
+
+// DefaultExclude are the labels of the pod templates which are not devpods, fail to create as devpods, or take
+// extremely long to provision, such as the machine learning ones
+var DefaultExclude = []string{"terraform", "packer", "jx-base", "promote", "swift", "ruby", "*machine-learning*"}
+
+// Selection selects the labels of the pod templates to create devpods with, and how long to wait for each of them
+type Selection struct {
+	// Include are the glob patterns of the labels to select, all labels if empty
+	Include []string
+	// Exclude are the glob patterns of the labels not to select even if included
+	Exclude []string
+	// MaxConcurrency is how many devpods may exist at the same time
+	MaxConcurrency int
+	// Timeout is the timeout of the labels without their own
+	Timeout time.Duration
+	// Timeouts are the timeouts of the labels matching each glob pattern
+	Timeouts map[string]time.Duration
+}
+
+// ParseSelection parses a selection from comma separated include and exclude glob patterns, the maximum concurrency
+// and comma separated pattern=timeout pairs such as maven*=20,go=10, where timeouts are in minutes unless they have a
+// unit such as 90s
+func ParseSelection(include string, exclude string, maxConcurrency string, timeouts string, timeout time.Duration) (*Selection, error) {
+	s := &Selection{
+		Include:        splitList(include),
+		Exclude:        splitList(exclude),
+		MaxConcurrency: 1,
+		Timeout:        timeout,
+		Timeouts:       map[string]time.Duration{},
+	}
+	for _, pattern := range append(append([]string{}, s.Include...), s.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "parsing pattern %s", pattern)
+		}
+	}
+	if maxConcurrency != "" {
+		n, err := strconv.Atoi(maxConcurrency)
+		if err != nil || n < 1 {
+			return nil, errors.Errorf("the maximum concurrency %s is not a positive number", maxConcurrency)
+		}
+		s.MaxConcurrency = n
+	}
+	for _, pair := range splitList(timeouts) {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, errors.Errorf("the timeout %s is not of the form pattern=timeout", pair)
+		}
+		pattern := strings.TrimSpace(parts[0])
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "parsing pattern %s", pattern)
+		}
+		d, err := parseTimeout(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing the timeout of %s", pattern)
+		}
+		s.Timeouts[pattern] = d
+	}
+	return s, nil
+}
+
+// Selects returns true if the label matches an include pattern, or there are none, and no exclude pattern
+func (s *Selection) Selects(label string) bool {
+	return (len(s.Include) == 0 || matchesAny(s.Include, label)) && !matchesAny(s.Exclude, label)
+}
+
+// Labels returns the include patterns which are labels rather than glob patterns, so they are known without
+// discovering the pod templates
+func (s *Selection) Labels() []string {
+	var answer []string
+	for _, pattern := range s.Include {
+		if !strings.ContainsAny(pattern, `*?[\`) {
+			answer = append(answer, pattern)
+		}
+	}
+	return answer
+}
+
+// Select returns the selected labels, sorted and without duplicates
+func (s *Selection) Select(labels []string) []string {
+	var answer []string
+	seen := map[string]bool{}
+	for _, label := range labels {
+		if s.Selects(label) && !seen[label] {
+			seen[label] = true
+			answer = append(answer, label)
+		}
+	}
+	sort.Strings(answer)
+	return answer
+}
+
+// TimeoutFor returns the timeout of the label: the timeout of the label itself, else of the longest pattern matching
+// it, else the default timeout
+func (s *Selection) TimeoutFor(label string) time.Duration {
+	if d, ok := s.Timeouts[label]; ok {
+		return d
+	}
+	longest := ""
+	for pattern := range s.Timeouts {
+		if matched, _ := path.Match(pattern, label); matched && (len(pattern) > len(longest) || len(pattern) == len(longest) && pattern < longest) {
+			longest = pattern
+		}
+	}
+	if longest == "" {
+		return s.Timeout
+	}
+	return s.Timeouts[longest]
+}
+
+// Discover returns the labels of the pod templates in the namespace, sorted
+func Discover(client kubernetes.Interface, ns string) ([]string, error) {
+	list, err := client.CoreV1().ConfigMaps(ns).List(metav1.ListOptions{LabelSelector: Selector})
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing the pod templates in namespace %s", ns)
+	}
+	var labels []string
+	for _, cm := range list.Items {
+		if strings.HasPrefix(cm.Name, ConfigMapPrefix) {
+			labels = append(labels, strings.TrimPrefix(cm.Name, ConfigMapPrefix))
+		}
+	}
+	sort.Strings(labels)
+	return labels, nil
+}
+
+func parseTimeout(text string) (time.Duration, error) {
+	var d time.Duration
+	if minutes, err := strconv.Atoi(text); err == nil {
+		d = time.Duration(minutes) * time.Minute
+	} else if d, err = time.ParseDuration(text); err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.Errorf("%s is not positive", text)
+	}
+	return d, nil
+}
+
+func matchesAny(patterns []string, label string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, label); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(text string) []string {
+	var answer []string
+	for _, item := range strings.Split(text, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			answer = append(answer, item)
+		}
+	}
+	return answer
+}
@@ -0,0 +1,108 @@
+package podtemplates_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/podtemplates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const ns = "jx-edit"
+
+func podTemplate(name string, kind string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			Labels:    map[string]string{"jenkins.io/kind": kind},
+		},
+	}
+}
+
+func TestSelectWithDefaultExclusions(t *testing.T) {
+	s, err := podtemplates.ParseSelection("", strings.Join(podtemplates.DefaultExclude, ","), "", "", time.Minute)
+	require.NoError(t, err)
+
+	labels := []string{"maven", "terraform", "go", "machine-learning-gpu", "python-machine-learning", "ruby", "nodejs"}
+	assert.Equal(t, []string{"go", "maven", "nodejs"}, s.Select(labels))
+}
+
+func TestSelectWithIncludeAndExclude(t *testing.T) {
+	s, err := podtemplates.ParseSelection(" maven* , go ", "maven-java11", "", "", time.Minute)
+	require.NoError(t, err)
+
+	labels := []string{"maven-java11", "maven", "go", "gradle", "maven-nodejs"}
+	assert.Equal(t, []string{"go", "maven", "maven-nodejs"}, s.Select(labels))
+	assert.Empty(t, s.Select([]string{"nodejs"}))
+}
+
+func TestLabels(t *testing.T) {
+	s, err := podtemplates.ParseSelection("go,maven*,nodejs,python?,[rs]ust", "", "", "", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "nodejs"}, s.Labels())
+
+	assert.Equal(t, []string{"go", "nodejs"}, s.Select(append([]string{"nodejs", "go", "scala"}, s.Labels()...)))
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	s, err := podtemplates.ParseSelection("", "", "", "", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.MaxConcurrency)
+
+	s, err = podtemplates.ParseSelection("", "", "3", "", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 3, s.MaxConcurrency)
+
+	for _, invalid := range []string{"0", "-1", "many"} {
+		_, err = podtemplates.ParseSelection("", "", invalid, "", time.Minute)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestTimeoutFor(t *testing.T) {
+	s, err := podtemplates.ParseSelection("", "", "", "maven*=20, maven-java11=90s, *=12, go=5", 15*time.Minute)
+	require.NoError(t, err)
+
+	assert.Equal(t, 90*time.Second, s.TimeoutFor("maven-java11"))
+	assert.Equal(t, 20*time.Minute, s.TimeoutFor("maven-nodejs"))
+	assert.Equal(t, 5*time.Minute, s.TimeoutFor("go"))
+	assert.Equal(t, 12*time.Minute, s.TimeoutFor("python"))
+
+	s, err = podtemplates.ParseSelection("", "", "", "go=5", 15*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, s.TimeoutFor("python"))
+}
+
+func TestParseSelectionErrors(t *testing.T) {
+	for _, timeouts := range []string{"maven", "=5", "maven=soon", "maven=0", "[=5"} {
+		_, err := podtemplates.ParseSelection("", "", "", timeouts, time.Minute)
+		assert.Error(t, err, timeouts)
+	}
+	_, err := podtemplates.ParseSelection("[", "", "", "", time.Minute)
+	assert.Error(t, err)
+	_, err = podtemplates.ParseSelection("", "ma[ven", "", "", time.Minute)
+	assert.Error(t, err)
+}
+
+func TestDiscover(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		podTemplate("jenkins-x-pod-template-maven", "podTemplate"),
+		podTemplate("jenkins-x-pod-template-go", "podTemplate"),
+		podTemplate("jenkins-x-pod-template-nodejs", "other"),
+		podTemplate("not-a-pod-template", "podTemplate"),
+	)
+
+	labels, err := podtemplates.Discover(client, ns)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "maven"}, labels)
+
+	labels, err = podtemplates.Discover(client, "jx")
+	require.NoError(t, err)
+	assert.Empty(t, labels)
+}
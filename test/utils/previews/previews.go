@@ -0,0 +1,75 @@
+package previews
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// AnnotationReleaseName is the annotation of a preview Environment holding the name of its helm release
+	AnnotationReleaseName = "jenkins.io/chart-release"
+	// LabelEnvironment is the label of an environment namespace holding the name of its Environment
+	LabelEnvironment = "env"
+	// LabelTeam is the label of an environment namespace holding the namespace of its team
+	LabelTeam = "team"
+)
+
+// FindForPullRequest returns the preview environment of the pull request at the given URL, or nil if there is none
+func FindForPullRequest(environments []v1.Environment, pullRequestURL string) *v1.Environment {
+	for i := range environments {
+		env := &environments[i]
+		if env.Spec.Kind != v1.EnvironmentKindTypePreview {
+			continue
+		}
+		if sameURL(env.Spec.PullRequestURL, pullRequestURL) || sameURL(env.Spec.PreviewGitSpec.URL, pullRequestURL) {
+			return env
+		}
+	}
+	return nil
+}
+
+// VerifyEnvironment returns an error unless the Environment is the preview of the application for the pull request at
+// the given URL, with the annotation naming its helm release and a namespace to deploy to
+func VerifyEnvironment(env *v1.Environment, pullRequestURL string, app string) error {
+	if env.Spec.Kind != v1.EnvironmentKindTypePreview {
+		return fmt.Errorf("environment %s has kind %s rather than %s", env.Name, env.Spec.Kind, v1.EnvironmentKindTypePreview)
+	}
+	if !sameURL(env.Spec.PullRequestURL, pullRequestURL) && !sameURL(env.Spec.PreviewGitSpec.URL, pullRequestURL) {
+		return fmt.Errorf("preview environment %s is for pull request %s rather than %s", env.Name, env.Spec.PullRequestURL, pullRequestURL)
+	}
+	if name := env.Spec.PreviewGitSpec.ApplicationName; name != "" && !strings.EqualFold(name, app) {
+		return fmt.Errorf("preview environment %s is for application %s rather than %s", env.Name, name, app)
+	}
+	if env.Annotations[AnnotationReleaseName] == "" {
+		return fmt.Errorf("preview environment %s has no %s annotation", env.Name, AnnotationReleaseName)
+	}
+	if env.Spec.Namespace == "" {
+		return fmt.Errorf("preview environment %s has no namespace", env.Name)
+	}
+	return nil
+}
+
+// VerifyNamespace returns an error unless the namespace is the one of the preview environment, labelled with the
+// environment and the team namespace it belongs to
+func VerifyNamespace(namespace *corev1.Namespace, env *v1.Environment, team string) error {
+	if namespace.Name != env.Spec.Namespace {
+		return fmt.Errorf("namespace %s is not the namespace %s of preview environment %s", namespace.Name, env.Spec.Namespace, env.Name)
+	}
+	if actual := namespace.Labels[LabelEnvironment]; actual != env.Name {
+		return fmt.Errorf("expected namespace %s to have label %s=%s but found '%s'", namespace.Name, LabelEnvironment, env.Name, actual)
+	}
+	if actual := namespace.Labels[LabelTeam]; actual != team {
+		return fmt.Errorf("expected namespace %s to have label %s=%s but found '%s'", namespace.Name, LabelTeam, team, actual)
+	}
+	return nil
+}
+
+func sameURL(a string, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSuffix(a, "/"), strings.TrimSuffix(b, "/"))
+}
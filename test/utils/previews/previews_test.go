@@ -0,0 +1,96 @@
+package previews_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/previews"
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFindForPullRequest(t *testing.T) {
+	prURL := "https://github.com/jenkins-x-tests/bdd-nh-123/pull/1"
+	environments := []v1.Environment{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "staging"},
+			Spec:       v1.EnvironmentSpec{Kind: v1.EnvironmentKindTypePermanent, PullRequestURL: prURL},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "jenkins-x-tests-bdd-nh-123-pr-2"},
+			Spec:       v1.EnvironmentSpec{Kind: v1.EnvironmentKindTypePreview, PullRequestURL: "https://github.com/jenkins-x-tests/bdd-nh-123/pull/2"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "jenkins-x-tests-bdd-nh-123-pr-1"},
+			Spec: v1.EnvironmentSpec{
+				Kind:           v1.EnvironmentKindTypePreview,
+				Namespace:      "jx-jenkins-x-tests-bdd-nh-123-pr-1",
+				PreviewGitSpec: v1.PreviewGitSpec{URL: prURL + "/"},
+			},
+		},
+	}
+	env := previews.FindForPullRequest(environments, prURL)
+	require.NotNil(t, env)
+	assert.Equal(t, "jenkins-x-tests-bdd-nh-123-pr-1", env.Name)
+
+	assert.Nil(t, previews.FindForPullRequest(environments, "https://github.com/jenkins-x-tests/bdd-nh-123/pull/3"))
+}
+
+func TestVerifyEnvironment(t *testing.T) {
+	prURL := "https://github.com/jenkins-x-tests/bdd-nh-123/pull/1"
+	preview := func() *v1.Environment {
+		return &v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "jenkins-x-tests-bdd-nh-123-pr-1",
+				Annotations: map[string]string{previews.AnnotationReleaseName: "preview"},
+			},
+			Spec: v1.EnvironmentSpec{
+				Kind:           v1.EnvironmentKindTypePreview,
+				Namespace:      "jx-jenkins-x-tests-bdd-nh-123-pr-1",
+				PullRequestURL: prURL,
+				PreviewGitSpec: v1.PreviewGitSpec{ApplicationName: "bdd-nh-123", URL: prURL},
+			},
+		}
+	}
+	assert.NoError(t, previews.VerifyEnvironment(preview(), prURL, "bdd-nh-123"))
+
+	tests := []struct {
+		name   string
+		modify func(env *v1.Environment)
+	}{
+		{name: "not a preview", modify: func(env *v1.Environment) { env.Spec.Kind = v1.EnvironmentKindTypePermanent }},
+		{name: "other pull request", modify: func(env *v1.Environment) {
+			env.Spec.PullRequestURL = prURL + "0"
+			env.Spec.PreviewGitSpec.URL = ""
+		}},
+		{name: "other application", modify: func(env *v1.Environment) { env.Spec.PreviewGitSpec.ApplicationName = "bdd-other" }},
+		{name: "no release annotation", modify: func(env *v1.Environment) { env.Annotations = nil }},
+		{name: "no namespace", modify: func(env *v1.Environment) { env.Spec.Namespace = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := preview()
+			tt.modify(env)
+			assert.Error(t, previews.VerifyEnvironment(env, prURL, "bdd-nh-123"))
+		})
+	}
+}
+
+func TestVerifyNamespace(t *testing.T) {
+	env := &v1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "jenkins-x-tests-bdd-nh-123-pr-1"},
+		Spec:       v1.EnvironmentSpec{Namespace: "jx-jenkins-x-tests-bdd-nh-123-pr-1"},
+	}
+	namespace := func(labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: env.Spec.Namespace, Labels: labels}}
+	}
+	assert.NoError(t, previews.VerifyNamespace(namespace(map[string]string{"env": env.Name, "team": "jx"}), env, "jx"))
+	assert.Error(t, previews.VerifyNamespace(namespace(map[string]string{"env": env.Name, "team": "other"}), env, "jx"))
+	assert.Error(t, previews.VerifyNamespace(namespace(map[string]string{"team": "jx"}), env, "jx"))
+
+	other := namespace(map[string]string{"env": env.Name, "team": "jx"})
+	other.Name = "jx-staging"
+	assert.Error(t, previews.VerifyNamespace(other, env, "jx"))
+}
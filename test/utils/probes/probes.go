@@ -0,0 +1,443 @@
+package probes
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/jenkins-x/bdd-jx/test/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultRequestTimeout is the timeout of a single request if the probe does not specify one
+	DefaultRequestTimeout = 30 * time.Second
+	// DefaultMaxInterval is the maximum interval between attempts if the retry policy does not specify one
+	DefaultMaxInterval = 20 * time.Second
+)
+
+// Duration is a time.Duration which is read from a string such as 500ms or 2m in scenario files
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON parses the duration from a string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	err := json.Unmarshal(data, &text)
+	if err != nil {
+		return errors.Wrapf(err, "durations must be strings such as 2s")
+	}
+	d.Duration, err = time.ParseDuration(text)
+	return err
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Duration.String())
+}
+
+// Check is what a single path of an application is expected to return
+type Check struct {
+	// Path is appended to the URL of the application, such as /actuator/health
+	Path string `json:"path,omitempty"`
+	// Status is the expected HTTP status code, defaulting to 200
+	Status int `json:"status,omitempty"`
+	// BodyContains is text the response body must contain, if set
+	BodyContains string `json:"bodyContains,omitempty"`
+	// BodyMatches is a regular expression the response body must match, if set
+	BodyMatches string `json:"bodyMatches,omitempty"`
+	// BodyNotContains is text the response body must not contain, if set
+	BodyNotContains string `json:"bodyNotContains,omitempty"`
+	// JSONPaths are dotted paths into a JSON response body, such as components.db.status or items[0].name, and the
+	// values they must have
+	JSONPaths map[string]string `json:"jsonPaths,omitempty"`
+	// Headers are response headers and regular expressions their values must match
+	Headers map[string]string `json:"headers,omitempty"`
+	// MaxLatency is the longest the response may take, if set
+	MaxLatency *Duration `json:"maxLatency,omitempty"`
+}
+
+// RetryPolicy configures how long and how often a probe is retried
+type RetryPolicy struct {
+	// Timeout is how long to keep retrying for
+	Timeout *Duration `json:"timeout,omitempty"`
+	// MaxInterval is the longest wait between attempts, defaulting to DefaultMaxInterval
+	MaxInterval *Duration `json:"maxInterval,omitempty"`
+	// Successes is how many consecutive attempts must pass, defaulting to 1. Raising it guards against a response
+	// which is still flipping between an old and new deployment.
+	Successes int `json:"successes,omitempty"`
+}
+
+// Probe checks one or more paths of an application
+type Probe struct {
+	Checks []Check
+	// InsecureSkipVerify skips verifying the TLS certificate of https URLs
+	InsecureSkipVerify bool
+	// RequestTimeout is the timeout of each request, defaulting to DefaultRequestTimeout
+	RequestTimeout time.Duration
+	Retry          RetryPolicy
+	// Client is the HTTP client to use instead of creating one
+	Client *http.Client
+}
+
+// Response is what was returned for a check
+type Response struct {
+	URL        string
+	StatusCode int
+	Header     http.Header
+	Body       string
+	Latency    time.Duration
+	TLS        *tls.ConnectionState
+}
+
+// StatusProbe returns a probe which only checks the status code of the URL itself
+func StatusProbe(statusCode int) *Probe {
+	return &Probe{Checks: []Check{{Status: statusCode}}}
+}
+
+// ExpectedStatus returns the expected HTTP status code
+func (c *Check) ExpectedStatus() int {
+	if c.Status == 0 {
+		return http.StatusOK
+	}
+	return c.Status
+}
+
+// Validate returns an error if the check cannot be evaluated
+func (c *Check) Validate() error {
+	if c.Status < 0 || c.Status > 599 {
+		return fmt.Errorf("invalid status %d", c.Status)
+	}
+	if c.BodyMatches != "" {
+		if _, err := regexp.Compile(c.BodyMatches); err != nil {
+			return errors.Wrapf(err, "invalid bodyMatches")
+		}
+	}
+	for name, pattern := range c.Headers {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return errors.Wrapf(err, "invalid pattern for header %s", name)
+		}
+	}
+	for path := range c.JSONPaths {
+		if _, err := parseJSONPath(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify returns an error describing the first way the response does not meet the check
+func (c *Check) Verify(response *Response) error {
+	if response.StatusCode != c.ExpectedStatus() {
+		return fmt.Errorf("invalid HTTP status code for %s expected %d but got %d", response.URL, c.ExpectedStatus(), response.StatusCode)
+	}
+	if c.MaxLatency != nil && response.Latency > c.MaxLatency.Duration {
+		return fmt.Errorf("%s took %s which is longer than %s", response.URL, response.Latency, c.MaxLatency.Duration)
+	}
+	for name, pattern := range c.Headers {
+		value := response.Header.Get(name)
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("expected header %s of %s to match '%s' but was '%s'", name, response.URL, pattern, value)
+		}
+	}
+	err := c.CheckBody(response.Body)
+	if err != nil {
+		return errors.Wrapf(err, "invalid response body for %s", response.URL)
+	}
+	return nil
+}
+
+// CheckBody returns an error if the body does not contain, match or have the JSON values expected
+func (c *Check) CheckBody(body string) error {
+	if c.BodyContains != "" && !strings.Contains(body, c.BodyContains) {
+		return fmt.Errorf("expected body to contain '%s'", c.BodyContains)
+	}
+	if c.BodyNotContains != "" && strings.Contains(body, c.BodyNotContains) {
+		return fmt.Errorf("expected body not to contain '%s'", c.BodyNotContains)
+	}
+	if c.BodyMatches != "" {
+		re, err := regexp.Compile(c.BodyMatches)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(body) {
+			return fmt.Errorf("expected body to match '%s'", c.BodyMatches)
+		}
+	}
+	if len(c.JSONPaths) == 0 {
+		return nil
+	}
+	var doc interface{}
+	err := json.Unmarshal([]byte(body), &doc)
+	if err != nil {
+		return errors.Wrap(err, "expected a JSON body")
+	}
+	for path, expected := range c.JSONPaths {
+		value, err := LookupJSONPath(doc, path)
+		if err != nil {
+			return err
+		}
+		if actual := jsonValueString(value); actual != expected {
+			return fmt.Errorf("expected %s to be '%s' but was '%s'", path, expected, actual)
+		}
+	}
+	return nil
+}
+
+// Validate returns an error if any of the checks cannot be evaluated
+func (p *Probe) Validate() error {
+	if len(p.Checks) == 0 {
+		return fmt.Errorf("probe has no checks")
+	}
+	for _, c := range p.Checks {
+		if err := c.Validate(); err != nil {
+			return errors.Wrapf(err, "check of path '%s'", c.Path)
+		}
+	}
+	return nil
+}
+
+// Once runs each of the checks against the application at the base URL once, returning the responses so far and an
+// error for the first check which fails
+func (p *Probe) Once(baseURL string) ([]*Response, error) {
+	client := p.httpClient()
+	var responses []*Response
+	for i := range p.Checks {
+		check := &p.Checks[i]
+		u, err := JoinPath(baseURL, check.Path)
+		if err != nil {
+			return responses, err
+		}
+		response, err := get(client, u)
+		if err != nil {
+			return responses, err
+		}
+		responses = append(responses, response)
+		if !p.InsecureSkipVerify {
+			err = VerifyTLS(response, time.Now())
+			if err != nil {
+				return responses, err
+			}
+		}
+		err = check.Verify(response)
+		if err != nil {
+			return responses, err
+		}
+	}
+	return responses, nil
+}
+
+// Run retries the checks against the application at the base URL with exponential backoff until they pass as many
+// consecutive times as the retry policy requires, or its timeout expires
+func (p *Probe) Run(baseURL string) error {
+	err := p.Validate()
+	if err != nil {
+		return err
+	}
+	successes := 0
+	lastLoggedStatus := map[string]int{}
+	f := func() error {
+		responses, err := p.Once(baseURL)
+		for _, r := range responses {
+			if lastLoggedStatus[r.URL] != r.StatusCode {
+				lastLoggedStatus[r.URL] = r.StatusCode
+				utils.LogInfof("Invoked %s and got return code: %s in %s\n", utils.ColorInfo(r.URL), utils.ColorInfo(strconv.Itoa(r.StatusCode)), r.Latency)
+			}
+		}
+		if err != nil {
+			successes = 0
+			return err
+		}
+		successes++
+		if successes < p.Retry.requiredSuccesses() {
+			return fmt.Errorf("passed %d of %d consecutive times", successes, p.Retry.requiredSuccesses())
+		}
+		return nil
+	}
+	return backoff.Retry(f, p.Retry.backOff())
+}
+
+func (p *Probe) httpClient() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	timeout := p.RequestTimeout
+	if timeout == 0 {
+		timeout = DefaultRequestTimeout
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: p.InsecureSkipVerify,
+			},
+		},
+	}
+}
+
+func (r *RetryPolicy) requiredSuccesses() int {
+	if r.Successes < 1 {
+		return 1
+	}
+	return r.Successes
+}
+
+func (r *RetryPolicy) backOff() backoff.BackOff {
+	exponentialBackOff := backoff.NewExponentialBackOff()
+	if r.Timeout != nil {
+		exponentialBackOff.MaxElapsedTime = r.Timeout.Duration
+	}
+	exponentialBackOff.MaxInterval = DefaultMaxInterval
+	if r.MaxInterval != nil {
+		exponentialBackOff.MaxInterval = r.MaxInterval.Duration
+	}
+	exponentialBackOff.Reset()
+	return exponentialBackOff
+}
+
+func get(client *http.Client, u string) (*Response, error) {
+	start := time.Now()
+	response, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading the response body of %s", u)
+	}
+	return &Response{
+		URL:        u,
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       string(body),
+		Latency:    time.Since(start),
+		TLS:        response.TLS,
+	}, nil
+}
+
+// VerifyTLS returns an error if the response came from an https URL without a certificate which is valid at the given
+// time. The HTTP client already rejects untrusted chains; this makes sure the certificate was actually checked and
+// reports expiry clearly.
+func VerifyTLS(response *Response, now time.Time) error {
+	if !strings.HasPrefix(strings.ToLower(response.URL), "https://") {
+		return nil
+	}
+	if response.TLS == nil || len(response.TLS.PeerCertificates) == 0 {
+		return fmt.Errorf("no TLS certificate was presented by %s", response.URL)
+	}
+	cert := response.TLS.PeerCertificates[0]
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("the TLS certificate of %s is not valid until %s", response.URL, cert.NotBefore)
+	}
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("the TLS certificate of %s expired at %s", response.URL, cert.NotAfter)
+	}
+	return nil
+}
+
+// JoinPath appends the path to the base URL, keeping any path the base URL already has
+func JoinPath(baseURL string, path string) (string, error) {
+	if path == "" {
+		return baseURL, nil
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing URL %s", baseURL)
+	}
+	p, err := url.Parse(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing path %s", path)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.TrimPrefix(p.Path, "/")
+	if p.RawQuery != "" {
+		u.RawQuery = p.RawQuery
+	}
+	return u.String(), nil
+}
+
+type jsonPathSegment struct {
+	key   string
+	index int
+}
+
+var jsonPathSegmentRegex = regexp.MustCompile(`^([^\[\]]*)((?:\[\d+\])*)$`)
+var jsonPathIndexRegex = regexp.MustCompile(`\[(\d+)\]`)
+
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	var segments []jsonPathSegment
+	for _, part := range strings.Split(strings.TrimPrefix(path, "$."), ".") {
+		m := jsonPathSegmentRegex.FindStringSubmatch(part)
+		if m == nil || (m[1] == "" && m[2] == "") {
+			return nil, fmt.Errorf("invalid JSON path %s", path)
+		}
+		if m[1] != "" {
+			segments = append(segments, jsonPathSegment{key: m[1], index: -1})
+		}
+		for _, idx := range jsonPathIndexRegex.FindAllStringSubmatch(m[2], -1) {
+			i, _ := strconv.Atoi(idx[1])
+			segments = append(segments, jsonPathSegment{index: i})
+		}
+	}
+	return segments, nil
+}
+
+// LookupJSONPath returns the value at the dotted path in the unmarshalled JSON document. Array elements are selected
+// with [n], for example items[0].name.
+func LookupJSONPath(doc interface{}, path string) (interface{}, error) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	value := doc
+	for _, s := range segments {
+		if s.index < 0 {
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot get %s of %s as it is not an object", s.key, path)
+			}
+			value, ok = m[s.key]
+			if !ok {
+				return nil, fmt.Errorf("no %s found for %s", s.key, path)
+			}
+			continue
+		}
+		a, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot get [%d] of %s as it is not an array", s.index, path)
+		}
+		if s.index >= len(a) {
+			return nil, fmt.Errorf("index [%d] of %s is out of range as it has %d elements", s.index, path, len(a))
+		}
+		value = a[s.index]
+	}
+	return value, nil
+}
+
+func jsonValueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return "null"
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	}
+}
@@ -0,0 +1,225 @@
+package probes_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/probes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newApplication() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, "Hello from:  golang-http\n")
+	})
+	mux.HandleFunc("/actuator/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status":"UP","components":{"diskSpace":{"status":"UP","details":{"free":1024}}},"groups":["liveness","readiness"]}`)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestOnce(t *testing.T) {
+	server := newApplication()
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		check probes.Check
+		valid bool
+	}{
+		{name: "status", check: probes.Check{}, valid: true},
+		{name: "wrong status", check: probes.Check{Status: 404}},
+		{name: "missing path", check: probes.Check{Path: "/missing", Status: 404}, valid: true},
+		{name: "body contains", check: probes.Check{BodyContains: "Hello from"}, valid: true},
+		{name: "body does not contain", check: probes.Check{BodyContains: "Goodbye"}},
+		{name: "body not contains", check: probes.Check{BodyNotContains: "Goodbye"}, valid: true},
+		{name: "body contains unwanted", check: probes.Check{BodyNotContains: "golang"}},
+		{name: "body matches", check: probes.Check{BodyMatches: `^Hello from:\s+golang-http`}, valid: true},
+		{name: "body does not match", check: probes.Check{BodyMatches: `^Goodbye`}},
+		{name: "header", check: probes.Check{Headers: map[string]string{"Content-Type": "^text/plain"}}, valid: true},
+		{name: "wrong header", check: probes.Check{Headers: map[string]string{"Content-Type": "json"}}},
+		{
+			name:  "JSON paths",
+			check: probes.Check{Path: "/actuator/health", JSONPaths: map[string]string{"status": "UP", "components.diskSpace.details.free": "1024", "groups[1]": "readiness"}},
+			valid: true,
+		},
+		{name: "wrong JSON value", check: probes.Check{Path: "/actuator/health", JSONPaths: map[string]string{"status": "DOWN"}}},
+		{name: "missing JSON path", check: probes.Check{Path: "/actuator/health", JSONPaths: map[string]string{"components.db.status": "UP"}}},
+		{name: "JSON path of text body", check: probes.Check{JSONPaths: map[string]string{"status": "UP"}}},
+		{name: "latency", check: probes.Check{MaxLatency: &probes.Duration{Duration: 10 * time.Second}}, valid: true},
+		{name: "too slow", check: probes.Check{Path: "/slow", MaxLatency: &probes.Duration{Duration: 50 * time.Millisecond}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probe := &probes.Probe{Checks: []probes.Check{tt.check}}
+			require.NoError(t, probe.Validate())
+			_, err := probe.Once(server.URL)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestOnceChecksEachPath(t *testing.T) {
+	server := newApplication()
+	defer server.Close()
+
+	probe := &probes.Probe{Checks: []probes.Check{
+		{BodyContains: "Hello"},
+		{Path: "/actuator/health", JSONPaths: map[string]string{"status": "UP"}},
+		{Path: "/actuator/info"},
+	}}
+	responses, err := probe.Once(server.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/actuator/info")
+	require.Len(t, responses, 3)
+	assert.Equal(t, server.URL, responses[0].URL)
+	assert.Equal(t, server.URL+"/actuator/health", responses[1].URL)
+}
+
+func TestValidate(t *testing.T) {
+	invalid := []probes.Probe{
+		{},
+		{Checks: []probes.Check{{Status: 1000}}},
+		{Checks: []probes.Check{{BodyMatches: "("}}},
+		{Checks: []probes.Check{{Headers: map[string]string{"Content-Type": "["}}}},
+		{Checks: []probes.Check{{JSONPaths: map[string]string{"a..b": "c"}}}},
+	}
+	for i, probe := range invalid {
+		assert.Error(t, probe.Validate(), "probe %d", i)
+	}
+}
+
+func TestRunRetriesUntilPassing(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ready")
+	}))
+	defer server.Close()
+
+	probe := &probes.Probe{
+		Checks: []probes.Check{{BodyContains: "ready"}},
+		Retry: probes.RetryPolicy{
+			Timeout:     &probes.Duration{Duration: 30 * time.Second},
+			MaxInterval: &probes.Duration{Duration: 10 * time.Millisecond},
+			Successes:   2,
+		},
+	}
+	err := probe.Run(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, 4, requests)
+}
+
+func TestRunTimesOut(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	probe := &probes.Probe{
+		Checks: []probes.Check{{}},
+		Retry: probes.RetryPolicy{
+			Timeout:     &probes.Duration{Duration: 100 * time.Millisecond},
+			MaxInterval: &probes.Duration{Duration: 10 * time.Millisecond},
+		},
+	}
+	err := probe.Run(server.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected 200 but got 503")
+}
+
+func TestTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secure")
+	}))
+	defer server.Close()
+
+	probe := &probes.Probe{Checks: []probes.Check{{BodyContains: "secure"}}}
+	_, err := probe.Once(server.URL)
+	assert.Error(t, err, "the certificate of the test server is not trusted")
+
+	probe.InsecureSkipVerify = true
+	_, err = probe.Once(server.URL)
+	assert.NoError(t, err)
+
+	probe = &probes.Probe{Checks: []probes.Check{{BodyContains: "secure"}}, Client: server.Client()}
+	responses, err := probe.Once(server.URL)
+	require.NoError(t, err)
+
+	cert := responses[0].TLS.PeerCertificates[0]
+	assert.NoError(t, probes.VerifyTLS(responses[0], cert.NotBefore.Add(time.Hour)))
+	assert.Error(t, probes.VerifyTLS(responses[0], cert.NotAfter.Add(time.Hour)))
+	assert.Error(t, probes.VerifyTLS(responses[0], cert.NotBefore.Add(-time.Hour)))
+	assert.Error(t, probes.VerifyTLS(&probes.Response{URL: server.URL}, time.Now()))
+	assert.NoError(t, probes.VerifyTLS(&probes.Response{URL: "http://example.com"}, time.Now()))
+}
+
+func TestJoinPath(t *testing.T) {
+	tests := []struct {
+		base     string
+		path     string
+		expected string
+	}{
+		{base: "http://app.jx-staging.example.com", path: "", expected: "http://app.jx-staging.example.com"},
+		{base: "http://app.jx-staging.example.com", path: "/actuator/health", expected: "http://app.jx-staging.example.com/actuator/health"},
+		{base: "http://app.jx-staging.example.com/", path: "actuator/health", expected: "http://app.jx-staging.example.com/actuator/health"},
+		{base: "http://example.com/app/", path: "/health?full=true", expected: "http://example.com/app/health?full=true"},
+	}
+	for _, tt := range tests {
+		actual, err := probes.JoinPath(tt.base, tt.path)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, actual)
+	}
+}
+
+func TestLookupJSONPath(t *testing.T) {
+	var doc interface{}
+	err := json.Unmarshal([]byte(`{"items":[{"name":"a"},{"name":"b","tags":["x","y"]}],"count":2,"ok":true}`), &doc)
+	require.NoError(t, err)
+
+	value, err := probes.LookupJSONPath(doc, "items[1].tags[0]")
+	require.NoError(t, err)
+	assert.Equal(t, "x", value)
+
+	value, err = probes.LookupJSONPath(doc, "$.count")
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), value)
+
+	_, err = probes.LookupJSONPath(doc, "items[2].name")
+	assert.Error(t, err)
+	_, err = probes.LookupJSONPath(doc, "count.value")
+	assert.Error(t, err)
+}
+
+func TestDurationUnmarshal(t *testing.T) {
+	var policy probes.RetryPolicy
+	err := json.Unmarshal([]byte(`{"timeout":"90s"}`), &policy)
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, policy.Timeout.Duration)
+
+	err = json.Unmarshal([]byte(`{"timeout":90}`), &policy)
+	assert.Error(t, err)
+}
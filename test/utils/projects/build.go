@@ -0,0 +1,66 @@
+package projects
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	goModuleDirective = regexp.MustCompile(`(?m)^module\s+("[^"]+"|\S+)`)
+	gradleRootProject = regexp.MustCompile(`(?m)^\s*rootProject\.name\s*=\s*(['"])([^'"]*)['"]`)
+)
+
+// goModulePath returns the start and end of the module path in the go.mod, without any quotes
+func goModulePath(data []byte) (int, int, string, error) {
+	m := goModuleDirective.FindSubmatchIndex(data)
+	if m == nil {
+		return 0, 0, "", fmt.Errorf("no module directive found")
+	}
+	start, end := m[2], m[3]
+	path := string(data[start:end])
+	if strings.HasPrefix(path, `"`) {
+		unquoted, err := strconv.Unquote(path)
+		if err != nil {
+			return 0, 0, "", err
+		}
+		return start + 1, end - 1, unquoted, nil
+	}
+	return start, end, path, nil
+}
+
+// goModuleName returns the last element of the module path
+func goModuleName(data []byte) (string, error) {
+	_, _, path, err := goModulePath(data)
+	if err != nil {
+		return "", err
+	}
+	return path[strings.LastIndex(path, "/")+1:], nil
+}
+
+// setGoModuleName replaces the last element of the module path
+func setGoModuleName(data []byte, name string) ([]byte, error) {
+	_, end, path, err := goModulePath(data)
+	if err != nil {
+		return nil, err
+	}
+	last := path[strings.LastIndex(path, "/")+1:]
+	return splice(data, end-len(last), end, name), nil
+}
+
+func gradleProjectName(data []byte) (string, error) {
+	m := gradleRootProject.FindSubmatch(data)
+	if m == nil {
+		return "", fmt.Errorf("no rootProject.name found")
+	}
+	return string(m[2]), nil
+}
+
+func setGradleProjectName(data []byte, name string) ([]byte, error) {
+	m := gradleRootProject.FindSubmatchIndex(data)
+	if m == nil {
+		return nil, fmt.Errorf("no rootProject.name found")
+	}
+	return splice(data, m[4], m[5], name), nil
+}
@@ -0,0 +1,102 @@
+package projects
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonFrame is an object or array being decoded
+type jsonFrame struct {
+	object    bool
+	expectKey bool
+	key       string
+}
+
+// findJSON returns the start and end of the string value at the path of object keys, and the value
+func findJSON(data []byte, path ...string) (int, int, string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var stack []*jsonFrame
+	for {
+		before := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return 0, 0, "", fmt.Errorf("no value %s found", strings.Join(path, "."))
+		}
+		if err != nil {
+			return 0, 0, "", err
+		}
+		var top *jsonFrame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if top != nil && top.object && top.expectKey {
+			if delim, ok := token.(json.Delim); ok && delim == '}' {
+				stack = stack[:len(stack)-1]
+				valueDecoded(stack)
+				continue
+			}
+			top.key = token.(string)
+			top.expectKey = false
+			continue
+		}
+		if matchesJSONPath(stack, path) {
+			value, ok := token.(string)
+			if !ok {
+				return 0, 0, "", fmt.Errorf("value %s is not a string", strings.Join(path, "."))
+			}
+			start := before + bytes.IndexByte(data[before:], '"')
+			return start, int(decoder.InputOffset()), value, nil
+		}
+		switch token {
+		case json.Delim('{'):
+			stack = append(stack, &jsonFrame{object: true, expectKey: true})
+		case json.Delim('['):
+			stack = append(stack, &jsonFrame{})
+		case json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			valueDecoded(stack)
+		default:
+			valueDecoded(stack)
+		}
+	}
+}
+
+// valueDecoded expects the next key of the enclosing object once one of its values has been decoded
+func valueDecoded(stack []*jsonFrame) {
+	if len(stack) > 0 && stack[len(stack)-1].object {
+		stack[len(stack)-1].expectKey = true
+	}
+}
+
+// matchesJSONPath returns true if the stack is the nested objects of the path
+func matchesJSONPath(stack []*jsonFrame, path []string) bool {
+	if len(stack) != len(path) {
+		return false
+	}
+	for i, frame := range stack {
+		if !frame.object || frame.key != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func getJSON(data []byte, path ...string) (string, error) {
+	_, _, value, err := findJSON(data, path...)
+	return value, err
+}
+
+func setJSON(data []byte, value string, path ...string) ([]byte, error) {
+	start, end, _, err := findJSON(data, path...)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return splice(data, start, end, string(encoded)), nil
+}
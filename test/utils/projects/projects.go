@@ -0,0 +1,308 @@
+package projects
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// PomFile is the Maven project file, named by its artifactId
+	PomFile = "pom.xml"
+	// PackageJSONFile is the Node project file, named by its name
+	PackageJSONFile = "package.json"
+	// GoModFile is the Go module file, named by the last element of its module path
+	GoModFile = "go.mod"
+	// GradleBuildFile is the Gradle build file, whose project is named by rootProject.name in GradleSettingsFile
+	GradleBuildFile = "build.gradle"
+	// GradleSettingsFile is the Gradle settings file
+	GradleSettingsFile = "settings.gradle"
+	// ChartsDir is the directory of the Helm charts of a project, with a chart named after the project
+	ChartsDir = "charts"
+	// ChartFile is the file of a Helm chart holding its name
+	ChartFile = "Chart.yaml"
+	// ValuesFile is the file of a Helm chart holding its values
+	ValuesFile = "values.yaml"
+	// JenkinsXYmlFile is the Jenkins X pipeline configuration of a project
+	JenkinsXYmlFile = "jenkins-x.yml"
+
+	previewChart = "preview"
+)
+
+// Edit is a verified change of a value in a file of a project
+type Edit struct {
+	// File is the path of the file relative to the project directory
+	File string
+	Old  string
+	New  string
+}
+
+// String describes the edit
+func (e Edit) String() string {
+	return fmt.Sprintf("%s: '%s' -> '%s'", e.File, e.Old, e.New)
+}
+
+// Value is a value to set in a file of a project
+type Value struct {
+	// File is the path of the XML, JSON or YAML file relative to the project directory
+	File string `json:"file"`
+	// Path is the path of keys or elements of the value, starting below the root element of XML files
+	Path  []string `json:"path"`
+	Value string   `json:"value"`
+}
+
+// Names returns the name of the project in each of its files which names it, keyed by the path of the file relative
+// to the project directory
+func Names(dir string) (map[string]string, error) {
+	names := map[string]string{}
+	add := func(file string, read func([]byte) (string, error)) error {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, err := read(data)
+		if err != nil {
+			return errors.Wrapf(err, "reading the project name from %s", file)
+		}
+		names[file] = name
+		return nil
+	}
+	err := add(PomFile, func(data []byte) (string, error) { return getXML(data, "artifactId") })
+	if err == nil {
+		err = add(PackageJSONFile, func(data []byte) (string, error) { return getJSON(data, "name") })
+	}
+	if err == nil {
+		err = add(GoModFile, goModuleName)
+	}
+	if err == nil {
+		err = add(GradleSettingsFile, gradleProjectName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	charts, err := chartFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, chart := range charts {
+		err = add(chart, func(data []byte) (string, error) { return getYAML(data, "name") })
+		if err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// Rename names the project in the directory name in its pom.xml, package.json, go.mod, Gradle settings and Helm chart,
+// renaming the chart directory too. The chart of the project is the one named or in a directory named like the project
+// in its other files, or the only chart if they do not name the project, so that other charts are left alone. The old
+// names are also replaced in the values.yaml of the chart and jenkins-x.yml. Each edit is verified by reading the file
+// again, and the edits made are returned.
+func Rename(dir string, name string) ([]Edit, error) {
+	oldNames, err := Names(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, chart := range otherCharts(oldNames) {
+		delete(oldNames, chart)
+	}
+	var edits []Edit
+	apply := func(file string, edit func(data []byte) ([]byte, error), read func([]byte) (string, error)) error {
+		e, err := editFile(dir, file, name, edit, read)
+		if e != nil {
+			edits = append(edits, *e)
+		}
+		return err
+	}
+
+	files := make([]string, 0, len(oldNames))
+	for file := range oldNames {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	old := map[string]bool{}
+	for _, file := range files {
+		old[oldNames[file]] = true
+		var err error
+		switch {
+		case file == PomFile:
+			err = apply(file, func(data []byte) ([]byte, error) { return setXML(data, name, "artifactId") }, func(data []byte) (string, error) { return getXML(data, "artifactId") })
+		case file == PackageJSONFile:
+			err = apply(file, func(data []byte) ([]byte, error) { return setJSON(data, name, "name") }, func(data []byte) (string, error) { return getJSON(data, "name") })
+		case file == GoModFile:
+			err = apply(file, func(data []byte) ([]byte, error) { return setGoModuleName(data, name) }, goModuleName)
+		case file == GradleSettingsFile:
+			err = apply(file, func(data []byte) ([]byte, error) { return setGradleProjectName(data, name) }, gradleProjectName)
+		default:
+			err = apply(file, func(data []byte) ([]byte, error) { return setYAML(data, name, "name") }, func(data []byte) (string, error) { return getYAML(data, "name") })
+		}
+		if err != nil {
+			return edits, err
+		}
+	}
+
+	if _, ok := oldNames[GradleSettingsFile]; !ok && fileExists(filepath.Join(dir, GradleBuildFile)) {
+		err = ioutil.WriteFile(filepath.Join(dir, GradleSettingsFile), []byte(fmt.Sprintf("rootProject.name = '%s'\n", name)), 0644)
+		if err != nil {
+			return edits, err
+		}
+		edits = append(edits, Edit{File: GradleSettingsFile, New: name})
+	}
+
+	for _, file := range files {
+		if filepath.Base(file) != ChartFile {
+			continue
+		}
+		chartDir := filepath.Dir(file)
+		valuesFile := filepath.Join(chartDir, ValuesFile)
+		if fileExists(filepath.Join(dir, valuesFile)) {
+			e, err := renameYAMLValues(dir, valuesFile, old, name)
+			edits = append(edits, e...)
+			if err != nil {
+				return edits, err
+			}
+		}
+		if filepath.Base(chartDir) != name {
+			newDir := filepath.Join(ChartsDir, name)
+			err = os.Rename(filepath.Join(dir, chartDir), filepath.Join(dir, newDir))
+			if err != nil {
+				return edits, errors.Wrapf(err, "renaming chart %s", chartDir)
+			}
+			edits = append(edits, Edit{File: chartDir, Old: chartDir, New: newDir})
+		}
+	}
+	if fileExists(filepath.Join(dir, JenkinsXYmlFile)) {
+		e, err := renameYAMLValues(dir, JenkinsXYmlFile, old, name)
+		edits = append(edits, e...)
+		if err != nil {
+			return edits, err
+		}
+	}
+	return edits, nil
+}
+
+// Set sets the value at the path of keys or elements in the XML, JSON or YAML file of the project, verifying the
+// edit. The path of an XML file starts below its root element. The value must already exist in the file so that the
+// rest of the file keeps its formatting.
+func Set(dir string, file string, value string, path ...string) (*Edit, error) {
+	var set func([]byte, string, ...string) ([]byte, error)
+	var get func([]byte, ...string) (string, error)
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".xml":
+		set, get = setXML, getXML
+	case ".json":
+		set, get = setJSON, getJSON
+	case ".yaml", ".yml":
+		set, get = setYAML, getYAML
+	default:
+		return nil, fmt.Errorf("cannot set a value in %s as it is not XML, JSON or YAML", file)
+	}
+	return editFile(dir, file, value, func(data []byte) ([]byte, error) {
+		return set(data, value, path...)
+	}, func(data []byte) (string, error) {
+		return get(data, path...)
+	})
+}
+
+// editFile applies the edit to the file and verifies that reading the file again returns the value
+func editFile(dir string, file string, value string, edit func([]byte) ([]byte, error), read func([]byte) (string, error)) (*Edit, error) {
+	fileName := filepath.Join(dir, file)
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	old, err := read(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", file)
+	}
+	if old == value {
+		return nil, nil
+	}
+	data, err = edit(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "editing %s", file)
+	}
+	actual, err := read(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "verifying the edit of %s", file)
+	}
+	if actual != value {
+		return nil, fmt.Errorf("expected the edit of %s to change '%s' to '%s' but found '%s'", file, old, value, actual)
+	}
+	err = ioutil.WriteFile(fileName, data, info.Mode().Perm())
+	if err != nil {
+		return nil, err
+	}
+	return &Edit{File: file, Old: old, New: value}, nil
+}
+
+// otherCharts returns the Chart.yaml files among the names of a project which are not of the chart of the project
+func otherCharts(names map[string]string) []string {
+	appNames := map[string]bool{}
+	var charts []string
+	for file, name := range names {
+		if filepath.Base(file) == ChartFile {
+			charts = append(charts, file)
+		} else {
+			appNames[name] = true
+		}
+	}
+	if len(appNames) == 0 && len(charts) == 1 {
+		return nil
+	}
+	var others []string
+	for _, chart := range charts {
+		if !appNames[names[chart]] && !appNames[filepath.Base(filepath.Dir(chart))] {
+			others = append(others, chart)
+		}
+	}
+	sort.Strings(others)
+	return others
+}
+
+// chartFiles returns the Chart.yaml files of the charts of the project other than the preview chart
+func chartFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(dir, ChartsDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, info := range infos {
+		if !info.IsDir() || info.Name() == previewChart {
+			continue
+		}
+		file := filepath.Join(ChartsDir, info.Name(), ChartFile)
+		if fileExists(filepath.Join(dir, file)) {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// splice returns the data with the bytes between start and end replaced
+func splice(data []byte, start int, end int, replacement string) []byte {
+	answer := make([]byte, 0, len(data)-(end-start)+len(replacement))
+	answer = append(answer, data[:start]...)
+	answer = append(answer, replacement...)
+	return append(answer, data[end:]...)
+}
+
+func fileExists(fileName string) bool {
+	info, err := os.Stat(fileName)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,194 @@
+package projects_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/projects"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// copyProject copies the test project to a temporary directory which the caller must remove
+func copyProject(t *testing.T, name string) string {
+	dir, err := ioutil.TempDir("", "projects")
+	require.NoError(t, err)
+	src := filepath.Join("testdata", name)
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dir, rel), 0755)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dir, rel), data, 0644)
+	})
+	require.NoError(t, err)
+	return dir
+}
+
+func readFile(t *testing.T, dir string, name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		project  string
+		expected map[string]string
+	}{
+		{project: "spring", expected: map[string]string{
+			"pom.xml": "spring-boot-http",
+			filepath.Join("charts", "spring-boot-http", "Chart.yaml"): "spring-boot-http",
+			filepath.Join("charts", "postgresql", "Chart.yaml"):       "postgresql",
+		}},
+		{project: "node", expected: map[string]string{"package.json": "node-http"}},
+		{project: "golang", expected: map[string]string{"go.mod": "golang-http"}},
+		{project: "gradle", expected: map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.project, func(t *testing.T) {
+			names, err := projects.Names(filepath.Join("testdata", tt.project))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestRenameSpring(t *testing.T) {
+	dir := copyProject(t, "spring")
+	defer os.RemoveAll(dir)
+
+	edits, err := projects.Rename(dir, "bdd-app")
+	require.NoError(t, err)
+	assert.NotEmpty(t, edits)
+
+	pom := readFile(t, dir, "pom.xml")
+	assert.Contains(t, pom, "<artifactId>\n    bdd-app\n  </artifactId>")
+	assert.Contains(t, pom, "<artifactId>spring-boot-starter-parent</artifactId>")
+	assert.Contains(t, pom, "<artifactId>spring-boot-starter-web</artifactId>")
+
+	assert.NoDirExists(t, filepath.Join(dir, "charts", "spring-boot-http"))
+	chart := readFile(t, dir, "charts/bdd-app/Chart.yaml")
+	assert.Contains(t, chart, "\nname: bdd-app\n")
+	assert.Contains(t, chart, "icon: https://raw.githubusercontent.com/jenkins-x/jenkins-x-platform/master/images/java.png\n")
+
+	values := readFile(t, dir, "charts/bdd-app/values.yaml")
+	assert.Contains(t, values, "# Default values for the chart\n")
+	assert.Contains(t, values, "  repository: draft/bdd-app\n")
+	assert.Contains(t, values, "  name: \"bdd-app\"\n")
+	assert.Contains(t, values, "  # not the name of the project\n")
+
+	assert.Contains(t, readFile(t, dir, "charts/preview/Chart.yaml"), "name: preview\n")
+	assert.Contains(t, readFile(t, dir, "charts/postgresql/Chart.yaml"), "\nname: postgresql\n", "only the chart of the project is renamed")
+	assert.Contains(t, readFile(t, dir, "jenkins-x.yml"), "value: 'bdd-app'\n")
+
+	names, err := projects.Names(dir)
+	require.NoError(t, err)
+	for file, name := range names {
+		if file != filepath.Join("charts", "postgresql", "Chart.yaml") {
+			assert.Equal(t, "bdd-app", name, file)
+		}
+	}
+
+	edits, err = projects.Rename(dir, "bdd-app")
+	require.NoError(t, err)
+	assert.Empty(t, edits, "renaming again changes nothing")
+}
+
+func TestRenameOnlyChart(t *testing.T) {
+	dir := copyProject(t, "spring")
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.Remove(filepath.Join(dir, "pom.xml")))
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "charts", "postgresql")))
+
+	_, err := projects.Rename(dir, "bdd-app")
+	require.NoError(t, err)
+	assert.Contains(t, readFile(t, dir, "charts/bdd-app/Chart.yaml"), "\nname: bdd-app\n", "the only chart is the chart of the project")
+}
+
+func TestRenameNode(t *testing.T) {
+	dir := copyProject(t, "node")
+	defer os.RemoveAll(dir)
+
+	edits, err := projects.Rename(dir, "bdd-app")
+	require.NoError(t, err)
+	assert.Equal(t, []projects.Edit{{File: "package.json", Old: "node-http", New: "bdd-app"}}, edits)
+
+	data := readFile(t, dir, "package.json")
+	assert.Contains(t, data, "  \"name\": \"bdd-app\",\n")
+	assert.Contains(t, data, "\"name\": \"not-the-project\"")
+	assert.Contains(t, data, "[\"node-http\", {\"name\": \"other\"}]")
+}
+
+func TestRenameGo(t *testing.T) {
+	dir := copyProject(t, "golang")
+	defer os.RemoveAll(dir)
+
+	_, err := projects.Rename(dir, "bdd-app")
+	require.NoError(t, err)
+	assert.Equal(t, "// the quickstart module\nmodule github.com/jenkins-x-quickstarts/bdd-app\n\ngo 1.12\n", readFile(t, dir, "go.mod"))
+}
+
+func TestRenameGradle(t *testing.T) {
+	dir := copyProject(t, "gradle")
+	defer os.RemoveAll(dir)
+
+	_, err := projects.Rename(dir, "bdd-app")
+	require.NoError(t, err)
+	assert.Equal(t, "rootProject.name = 'bdd-app'\n", readFile(t, dir, "settings.gradle"))
+
+	_, err = projects.Rename(dir, "bdd-other")
+	require.NoError(t, err)
+	assert.Equal(t, "rootProject.name = 'bdd-other'\n", readFile(t, dir, "settings.gradle"))
+}
+
+func TestSet(t *testing.T) {
+	dir := copyProject(t, "spring")
+	defer os.RemoveAll(dir)
+	nodeDir := copyProject(t, "node")
+	defer os.RemoveAll(nodeDir)
+
+	edit, err := projects.Set(dir, "pom.xml", "Changed <in> a PR", "description")
+	require.NoError(t, err)
+	assert.Equal(t, "An application & its tests", edit.Old)
+	assert.Contains(t, readFile(t, dir, "pom.xml"), "<description>Changed &lt;in&gt; a PR</description>")
+
+	_, err = projects.Set(dir, "charts/spring-boot-http/values.yaml", "NodePort", "service", "type")
+	require.NoError(t, err)
+	assert.Contains(t, readFile(t, dir, "charts/spring-boot-http/values.yaml"), "  type: NodePort\n")
+
+	_, err = projects.Set(dir, "charts/spring-boot-http/values.yaml", "a value: with a colon", "image", "tag")
+	require.NoError(t, err)
+	assert.Contains(t, readFile(t, dir, "charts/spring-boot-http/values.yaml"), "  tag: \"a value: with a colon\"\n")
+
+	_, err = projects.Set(nodeDir, "package.json", "Changed in a PR", "description")
+	require.NoError(t, err)
+	assert.Contains(t, readFile(t, nodeDir, "package.json"), "\"description\": \"Changed in a PR\"\n")
+
+	_, err = projects.Set(nodeDir, "package.json", "other", "config", "name")
+	require.NoError(t, err)
+	assert.Contains(t, readFile(t, nodeDir, "package.json"), "\"name\": \"other\"\n  },")
+
+	for _, invalid := range [][]string{{"pom.xml", "missing"}, {"pom.xml", "parent"}, {"package.json", "config"}, {"jenkins-x.yml", "pipelineConfig", "env"}} {
+		target := dir
+		if invalid[0] == "package.json" {
+			target = nodeDir
+		}
+		_, err = projects.Set(target, invalid[0], "value", invalid[1:]...)
+		assert.Error(t, err, "%v", invalid)
+	}
+	_, err = projects.Set(dir, "README.md", "value", "title")
+	assert.Error(t, err)
+}
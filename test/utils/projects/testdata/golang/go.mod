@@ -0,0 +1,4 @@
+// the quickstart module
+module github.com/jenkins-x-quickstarts/golang-http
+
+go 1.12
@@ -0,0 +1,108 @@
+package projects
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xmlSpan is where the text of an element is in an XML document
+type xmlSpan struct {
+	start int
+	end   int
+	text  string
+}
+
+// findXML returns the span of the text of the element at the path of element names below the root element. The text
+// may span several lines, and the element must not have child elements.
+func findXML(data []byte, path ...string) (*xmlSpan, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("no XML path")
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var stack []string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no element %s found", strings.Join(path, "/"))
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			if !matchesXMLPath(stack, path) {
+				continue
+			}
+			span := &xmlSpan{start: int(decoder.InputOffset())}
+			if data[span.start-2] == '/' {
+				return nil, fmt.Errorf("element %s is empty", strings.Join(path, "/"))
+			}
+			var text strings.Builder
+			for {
+				end := int(decoder.InputOffset())
+				token, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				switch t := token.(type) {
+				case xml.CharData:
+					text.Write(t)
+				case xml.StartElement:
+					return nil, fmt.Errorf("element %s has child elements", strings.Join(path, "/"))
+				case xml.EndElement:
+					span.end = end
+					span.text = strings.TrimSpace(text.String())
+					return span, nil
+				}
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+// matchesXMLPath returns true if the stack of elements is the root element followed by the path
+func matchesXMLPath(stack []string, path []string) bool {
+	if len(stack) != len(path)+1 {
+		return false
+	}
+	for i, name := range path {
+		if stack[i+1] != name {
+			return false
+		}
+	}
+	return true
+}
+
+func getXML(data []byte, path ...string) (string, error) {
+	span, err := findXML(data, path...)
+	if err != nil {
+		return "", err
+	}
+	return span.text, nil
+}
+
+// setXML sets the text of the element, keeping the whitespace around the text
+func setXML(data []byte, value string, path ...string) ([]byte, error) {
+	span, err := findXML(data, path...)
+	if err != nil {
+		return nil, err
+	}
+	raw := string(data[span.start:span.end])
+	if strings.Contains(raw, "<") {
+		return nil, fmt.Errorf("element %s has comments or CDATA which cannot be edited", strings.Join(path, "/"))
+	}
+	trimmed := strings.TrimLeft(raw, " \t\r\n")
+	start := span.start + len(raw) - len(trimmed)
+	end := start + len(strings.TrimRight(trimmed, " \t\r\n"))
+	var escaped bytes.Buffer
+	err = xml.EscapeText(&escaped, []byte(value))
+	if err != nil {
+		return nil, err
+	}
+	return splice(data, start, end, escaped.String()), nil
+}
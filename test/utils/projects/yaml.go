@@ -0,0 +1,193 @@
+package projects
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var plainYAMLScalar = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+func parseYAML(data []byte) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	err := yaml.Unmarshal(data, doc)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("no YAML document")
+	}
+	return doc.Content[0], nil
+}
+
+// findYAML returns the scalar node at the path of mapping keys
+func findYAML(data []byte, path ...string) (*yaml.Node, error) {
+	node, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("no value %s found", strings.Join(path, "."))
+		}
+		var value *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				value = node.Content[i+1]
+			}
+		}
+		if value == nil {
+			return nil, fmt.Errorf("no value %s found", strings.Join(path, "."))
+		}
+		node = value
+	}
+	if node.Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("value %s is not a scalar", strings.Join(path, "."))
+	}
+	return node, nil
+}
+
+func getYAML(data []byte, path ...string) (string, error) {
+	node, err := findYAML(data, path...)
+	if err != nil {
+		return "", err
+	}
+	return node.Value, nil
+}
+
+func setYAML(data []byte, value string, path ...string) ([]byte, error) {
+	node, err := findYAML(data, path...)
+	if err != nil {
+		return nil, err
+	}
+	return setYAMLScalars(data, map[*yaml.Node]string{node: value})
+}
+
+// setYAMLScalars replaces the scalars in the data, keeping their style where possible. Block scalars are not supported.
+func setYAMLScalars(data []byte, values map[*yaml.Node]string) ([]byte, error) {
+	lineStarts := []int{0}
+	for i, b := range data {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	type replacement struct {
+		start int
+		end   int
+		text  string
+	}
+	var replacements []replacement
+	for node, value := range values {
+		if node.Line < 1 || node.Line > len(lineStarts) {
+			return nil, fmt.Errorf("scalar '%s' has no position", node.Value)
+		}
+		start := lineStarts[node.Line-1] + node.Column - 1
+		var raw, text string
+		switch node.Style {
+		case 0:
+			raw = node.Value
+			text = value
+			if !plainYAMLScalar.MatchString(value) {
+				encoded, _ := json.Marshal(value)
+				text = string(encoded)
+			}
+		case yaml.SingleQuotedStyle:
+			raw = "'" + strings.Replace(node.Value, "'", "''", -1) + "'"
+			text = "'" + strings.Replace(value, "'", "''", -1) + "'"
+		case yaml.DoubleQuotedStyle:
+			encoded, _ := json.Marshal(node.Value)
+			raw = string(encoded)
+			encoded, _ = json.Marshal(value)
+			text = string(encoded)
+		default:
+			return nil, fmt.Errorf("cannot edit the block scalar '%s' on line %d", node.Value, node.Line)
+		}
+		end := start + len(raw)
+		if end > len(data) || string(data[start:end]) != raw {
+			return nil, fmt.Errorf("cannot find the scalar '%s' on line %d", node.Value, node.Line)
+		}
+		replacements = append(replacements, replacement{start: start, end: end, text: text})
+	}
+	// replace from the end so the earlier offsets stay valid
+	sort.Slice(replacements, func(i, j int) bool {
+		return replacements[i].start > replacements[j].start
+	})
+	for _, r := range replacements {
+		data = splice(data, r.start, r.end, r.text)
+	}
+	return data, nil
+}
+
+// renameYAMLValues replaces the scalar values of the YAML file which are one of the old names, or a path ending with
+// one, with the new name. It verifies none of the old names remain.
+func renameYAMLValues(dir string, file string, old map[string]bool, name string) ([]Edit, error) {
+	var edits []Edit
+	renamed := func(value string) (string, bool) {
+		i := strings.LastIndex(value, "/")
+		if old[value[i+1:]] && value[i+1:] != name {
+			return value[:i+1] + name, true
+		}
+		return "", false
+	}
+	read := func(data []byte) (map[*yaml.Node]string, error) {
+		root, err := parseYAML(data)
+		if err != nil {
+			return nil, err
+		}
+		values := map[*yaml.Node]string{}
+		walkYAMLValues(root, func(node *yaml.Node) {
+			if value, ok := renamed(node.Value); ok {
+				values[node] = value
+			}
+		})
+		return values, nil
+	}
+	_, err := editFile(dir, file, "", func(data []byte) ([]byte, error) {
+		values, err := read(data)
+		if err != nil {
+			return nil, err
+		}
+		for node, value := range values {
+			edits = append(edits, Edit{File: file, Old: node.Value, New: value})
+		}
+		return setYAMLScalars(data, values)
+	}, func(data []byte) (string, error) {
+		values, err := read(data)
+		if err != nil {
+			return "", err
+		}
+		var remaining []string
+		for node := range values {
+			remaining = append(remaining, node.Value)
+		}
+		sort.Strings(remaining)
+		return strings.Join(remaining, ", "), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].Old < edits[j].Old
+	})
+	return edits, nil
+}
+
+// walkYAMLValues calls the function with each scalar which is a value rather than a key
+func walkYAMLValues(node *yaml.Node, f func(*yaml.Node)) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		f(node)
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			walkYAMLValues(node.Content[i], f)
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, child := range node.Content {
+			walkYAMLValues(child, f)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package promotion
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver"
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// RequirementsFile is the file in an environment git repository which lists the versions of the applications deployed
+// to the environment
+const RequirementsFile = "env/requirements.yaml"
+
+// Requirements is the subset of the requirements.yaml of an environment the tests need
+type Requirements struct {
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+}
+
+// Dependency is an application deployed to an environment
+type Dependency struct {
+	Name       string `json:"name"`
+	Version    string `json:"version,omitempty"`
+	Repository string `json:"repository,omitempty"`
+	Alias      string `json:"alias,omitempty"`
+}
+
+// PullRequestRef identifies a pull request from its URL
+type PullRequestRef struct {
+	Owner  string
+	Repo   string
+	Number int
+}
+
+// ParseRequirements parses the requirements.yaml of an environment
+func ParseRequirements(data []byte) (*Requirements, error) {
+	requirements := &Requirements{}
+	err := yaml.Unmarshal(data, requirements)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing requirements")
+	}
+	return requirements, nil
+}
+
+// VersionOf returns the version of the application in the requirements, matching it with or without the jx- prefix
+// charts are sometimes given. The second result is false if the application is not a dependency.
+func (r *Requirements) VersionOf(app string) (string, bool) {
+	for _, d := range r.Dependencies {
+		if sameApp(d.Name, app) || (d.Alias != "" && sameApp(d.Alias, app)) {
+			return d.Version, true
+		}
+	}
+	return "", false
+}
+
+// LatestActivity returns the activity of the given repository and branch with the highest build number, or nil if
+// there is none
+func LatestActivity(activities []v1.PipelineActivity, owner string, repo string, branch string) *v1.PipelineActivity {
+	var latest *v1.PipelineActivity
+	latestBuild := -1
+	for i := range activities {
+		a := &activities[i]
+		if !strings.EqualFold(a.Spec.GitOwner, owner) || !strings.EqualFold(a.Spec.GitRepository, repo) || !strings.EqualFold(a.Spec.GitBranch, branch) {
+			continue
+		}
+		build, err := strconv.Atoi(a.Spec.Build)
+		if err != nil {
+			continue
+		}
+		if build > latestBuild {
+			latest = a
+			latestBuild = build
+		}
+	}
+	return latest
+}
+
+// ActivityForVersion returns the activity of the given repository and branch which released the version, or nil if
+// there is none
+func ActivityForVersion(activities []v1.PipelineActivity, owner string, repo string, branch string, version string) *v1.PipelineActivity {
+	for i := range activities {
+		a := &activities[i]
+		if strings.EqualFold(a.Spec.GitOwner, owner) && strings.EqualFold(a.Spec.GitRepository, repo) && strings.EqualFold(a.Spec.GitBranch, branch) && ActivityVersion(a) == version {
+			return a
+		}
+	}
+	return nil
+}
+
+// ActivityForBuild returns the activity of the given build of the repository and branch, or nil if there is none
+func ActivityForBuild(activities []v1.PipelineActivity, owner string, repo string, branch string, build string) *v1.PipelineActivity {
+	for i := range activities {
+		a := &activities[i]
+		if strings.EqualFold(a.Spec.GitOwner, owner) && strings.EqualFold(a.Spec.GitRepository, repo) && strings.EqualFold(a.Spec.GitBranch, branch) && a.Spec.Build == build {
+			return a
+		}
+	}
+	return nil
+}
+
+// ExpectVersionIncremented returns an error unless the next version is a semantic version greater than the previous
+func ExpectVersionIncremented(previous string, next string) error {
+	p, err := semver.ParseTolerant(previous)
+	if err != nil {
+		return errors.Wrapf(err, "parsing previous version %s", previous)
+	}
+	n, err := semver.ParseTolerant(next)
+	if err != nil {
+		return errors.Wrapf(err, "parsing next version %s", next)
+	}
+	if !n.GT(p) {
+		return fmt.Errorf("expected version %s to be greater than %s", next, previous)
+	}
+	return nil
+}
+
+// ActivityVersion returns the version released by the activity, or an empty string if it has not released one yet
+func ActivityVersion(activity *v1.PipelineActivity) string {
+	if activity.Spec.Version != "" {
+		return activity.Spec.Version
+	}
+	return activity.Status.Version
+}
+
+// FindPromoteStep returns the step of the activity promoting to the given environment, or nil if there is none
+func FindPromoteStep(activity *v1.PipelineActivity, environment string) *v1.PromoteActivityStep {
+	for _, step := range activity.Spec.Steps {
+		if step.Promote != nil && strings.EqualFold(step.Promote.Environment, environment) {
+			return step.Promote
+		}
+	}
+	return nil
+}
+
+// VerifyPromoteStep returns an error unless the promotion succeeded via a merged pull request on the environment
+// repository and the update step after the merge succeeded
+func VerifyPromoteStep(step *v1.PromoteActivityStep) error {
+	if step.Status != v1.ActivityStatusTypeSucceeded {
+		return fmt.Errorf("promotion to %s has status %s", step.Environment, statusOrPending(step.Status))
+	}
+	pr := step.PullRequest
+	if pr == nil {
+		return fmt.Errorf("promotion to %s has no pull request step", step.Environment)
+	}
+	if pr.Status != v1.ActivityStatusTypeSucceeded {
+		return fmt.Errorf("pull request step of the promotion to %s has status %s", step.Environment, statusOrPending(pr.Status))
+	}
+	if pr.PullRequestURL == "" {
+		return fmt.Errorf("pull request step of the promotion to %s has no pull request URL", step.Environment)
+	}
+	if pr.MergeCommitSHA == "" {
+		return fmt.Errorf("pull request %s promoting to %s has no merge commit", pr.PullRequestURL, step.Environment)
+	}
+	if step.Update != nil && step.Update.Status != v1.ActivityStatusTypeSucceeded {
+		return fmt.Errorf("update step of the promotion to %s has status %s", step.Environment, statusOrPending(step.Update.Status))
+	}
+	return nil
+}
+
+// FindRelease returns the release of the given version of the application, or nil if there is none
+func FindRelease(releases []v1.Release, app string, version string) *v1.Release {
+	for i := range releases {
+		r := &releases[i]
+		if r.Spec.Version != version {
+			continue
+		}
+		if sameApp(r.Spec.Name, app) || sameApp(r.Spec.GitRepository, app) {
+			return r
+		}
+	}
+	return nil
+}
+
+// ParsePullRequestURL returns the owner, repository and number of the pull request at the given URL of GitHub, GitLab
+// or Bitbucket Server
+func ParsePullRequestURL(pullRequestURL string) (*PullRequestRef, error) {
+	u, err := url.Parse(pullRequestURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing pull request URL %s", pullRequestURL)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for k := len(parts) - 2; k >= 1; k-- {
+		switch parts[k] {
+		case "pull", "pulls", "merge_requests", "pull-requests":
+		default:
+			continue
+		}
+		number, err := strconv.Atoi(parts[k+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pull request number in %s", pullRequestURL)
+		}
+		repoIndex := k - 1
+		// GitLab puts a - between the project and merge_requests
+		if parts[repoIndex] == "-" {
+			repoIndex--
+		}
+		if repoIndex < 1 {
+			break
+		}
+		owner := parts[:repoIndex]
+		// Bitbucket Server puts the repository under projects/<project>/repos
+		if len(owner) == 3 && owner[0] == "projects" && owner[2] == "repos" {
+			owner = owner[1:2]
+		}
+		return &PullRequestRef{Owner: strings.Join(owner, "/"), Repo: parts[repoIndex], Number: number}, nil
+	}
+	return nil, fmt.Errorf("could not find the pull request in %s", pullRequestURL)
+}
+
+func sameApp(name string, app string) bool {
+	return strings.EqualFold(strings.TrimPrefix(name, "jx-"), strings.TrimPrefix(app, "jx-"))
+}
+
+func statusOrPending(status v1.ActivityStatusType) string {
+	if status == "" {
+		return string(v1.ActivityStatusTypePending)
+	}
+	return string(status)
+}
@@ -0,0 +1,199 @@
+package promotion_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/promotion"
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func activity(repo string, branch string, build string, version string) v1.PipelineActivity {
+	return v1.PipelineActivity{
+		ObjectMeta: metav1.ObjectMeta{Name: "jenkins-x-tests-" + repo + "-" + branch + "-" + build},
+		Spec: v1.PipelineActivitySpec{
+			GitOwner:      "jenkins-x-tests",
+			GitRepository: repo,
+			GitBranch:     branch,
+			Build:         build,
+			Version:       version,
+		},
+	}
+}
+
+func TestLatestActivity(t *testing.T) {
+	activities := []v1.PipelineActivity{
+		activity("bdd-gh-123", "master", "2", "0.0.2"),
+		activity("bdd-gh-123", "master", "10", "0.0.10"),
+		activity("bdd-gh-123", "PR-1", "11", ""),
+		activity("bdd-other", "master", "12", "0.0.12"),
+		activity("bdd-gh-123", "master", "1", "0.0.1"),
+	}
+	latest := promotion.LatestActivity(activities, "jenkins-x-tests", "bdd-gh-123", "master")
+	require.NotNil(t, latest)
+	assert.Equal(t, "10", latest.Spec.Build)
+	assert.Equal(t, "0.0.10", promotion.ActivityVersion(latest))
+
+	assert.Nil(t, promotion.LatestActivity(activities, "jenkins-x-tests", "bdd-missing", "master"))
+
+	released := promotion.ActivityForVersion(activities, "jenkins-x-tests", "bdd-gh-123", "master", "0.0.2")
+	require.NotNil(t, released)
+	assert.Equal(t, "2", released.Spec.Build)
+	assert.Nil(t, promotion.ActivityForVersion(activities, "jenkins-x-tests", "bdd-gh-123", "master", "0.0.12"))
+
+	build := promotion.ActivityForBuild(activities, "jenkins-x-tests", "bdd-gh-123", "master", "10")
+	require.NotNil(t, build)
+	assert.Equal(t, "0.0.10", build.Spec.Version)
+	assert.Nil(t, promotion.ActivityForBuild(activities, "jenkins-x-tests", "bdd-gh-123", "master", "11"))
+}
+
+func TestVerifyPromoteStep(t *testing.T) {
+	succeeded := v1.CoreActivityStep{Status: v1.ActivityStatusTypeSucceeded}
+	running := v1.CoreActivityStep{Status: v1.ActivityStatusTypeRunning}
+	prURL := "https://github.com/jenkins-x-tests/environment-bdd-staging/pull/3"
+
+	tests := []struct {
+		name  string
+		step  v1.PromoteActivityStep
+		valid bool
+	}{
+		{
+			name: "merged and updated",
+			step: v1.PromoteActivityStep{
+				CoreActivityStep: succeeded,
+				Environment:      "staging",
+				PullRequest:      &v1.PromotePullRequestStep{CoreActivityStep: succeeded, PullRequestURL: prURL, MergeCommitSHA: "abc123"},
+				Update:           &v1.PromoteUpdateStep{CoreActivityStep: succeeded},
+			},
+			valid: true,
+		},
+		{
+			name: "still running",
+			step: v1.PromoteActivityStep{
+				CoreActivityStep: running,
+				Environment:      "staging",
+				PullRequest:      &v1.PromotePullRequestStep{CoreActivityStep: running, PullRequestURL: prURL},
+			},
+		},
+		{
+			name:  "no pull request",
+			step:  v1.PromoteActivityStep{CoreActivityStep: succeeded, Environment: "staging"},
+			valid: false,
+		},
+		{
+			name: "not merged",
+			step: v1.PromoteActivityStep{
+				CoreActivityStep: succeeded,
+				Environment:      "staging",
+				PullRequest:      &v1.PromotePullRequestStep{CoreActivityStep: succeeded, PullRequestURL: prURL},
+			},
+		},
+		{
+			name: "update failed",
+			step: v1.PromoteActivityStep{
+				CoreActivityStep: succeeded,
+				Environment:      "staging",
+				PullRequest:      &v1.PromotePullRequestStep{CoreActivityStep: succeeded, PullRequestURL: prURL, MergeCommitSHA: "abc123"},
+				Update:           &v1.PromoteUpdateStep{CoreActivityStep: v1.CoreActivityStep{Status: v1.ActivityStatusTypeFailed}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := promotion.VerifyPromoteStep(&tt.step)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestFindPromoteStep(t *testing.T) {
+	a := activity("bdd-gh-123", "master", "1", "0.0.1")
+	a.Spec.Steps = []v1.PipelineActivityStep{
+		{Kind: v1.ActivityStepKindTypeStage, Stage: &v1.StageActivityStep{}},
+		{Kind: v1.ActivityStepKindTypePromote, Promote: &v1.PromoteActivityStep{Environment: "staging"}},
+		{Kind: v1.ActivityStepKindTypePromote, Promote: &v1.PromoteActivityStep{Environment: "production"}},
+	}
+	step := promotion.FindPromoteStep(&a, "production")
+	require.NotNil(t, step)
+	assert.Equal(t, "production", step.Environment)
+	assert.Nil(t, promotion.FindPromoteStep(&a, "qa"))
+}
+
+func TestRequirements(t *testing.T) {
+	data := []byte(`dependencies:
+- name: exposecontroller
+  version: 2.3.118
+  repository: http://chartmuseum.jenkins-x.io
+- name: bdd-gh-123
+  version: 0.0.2
+  repository: http://jenkins-x-chartmuseum:8080
+- name: jx-bdd-spring-456
+  version: 0.0.1
+  repository: http://jenkins-x-chartmuseum:8080
+`)
+	requirements, err := promotion.ParseRequirements(data)
+	require.NoError(t, err)
+
+	version, ok := requirements.VersionOf("bdd-gh-123")
+	assert.True(t, ok)
+	assert.Equal(t, "0.0.2", version)
+
+	version, ok = requirements.VersionOf("bdd-spring-456")
+	assert.True(t, ok)
+	assert.Equal(t, "0.0.1", version)
+
+	_, ok = requirements.VersionOf("bdd-missing")
+	assert.False(t, ok)
+}
+
+func TestFindRelease(t *testing.T) {
+	releases := []v1.Release{
+		{Spec: v1.ReleaseSpec{Name: "bdd-gh-123", Version: "0.0.1"}},
+		{Spec: v1.ReleaseSpec{Name: "bdd-gh-123", Version: "0.0.2"}},
+		{Spec: v1.ReleaseSpec{Name: "jx-bdd-spring-456", Version: "0.0.1"}},
+	}
+	release := promotion.FindRelease(releases, "bdd-gh-123", "0.0.2")
+	require.NotNil(t, release)
+	assert.Equal(t, "0.0.2", release.Spec.Version)
+	assert.NotNil(t, promotion.FindRelease(releases, "bdd-spring-456", "0.0.1"))
+	assert.Nil(t, promotion.FindRelease(releases, "bdd-gh-123", "0.0.3"))
+}
+
+func TestParsePullRequestURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected promotion.PullRequestRef
+	}{
+		{url: "https://github.com/jenkins-x-tests/environment-bdd-staging/pull/3", expected: promotion.PullRequestRef{Owner: "jenkins-x-tests", Repo: "environment-bdd-staging", Number: 3}},
+		{url: "https://gitlab.com/group/sub/environment-bdd-staging/-/merge_requests/14", expected: promotion.PullRequestRef{Owner: "group/sub", Repo: "environment-bdd-staging", Number: 14}},
+		{url: "https://gitlab.com/group/environment-bdd-staging/merge_requests/2", expected: promotion.PullRequestRef{Owner: "group", Repo: "environment-bdd-staging", Number: 2}},
+		{url: "https://bitbucket.example.com/projects/PRJ/repos/environment-bdd-staging/pull-requests/7", expected: promotion.PullRequestRef{Owner: "PRJ", Repo: "environment-bdd-staging", Number: 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			ref, err := promotion.ParsePullRequestURL(tt.url)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, *ref)
+		})
+	}
+
+	for _, invalid := range []string{"https://github.com/jenkins-x-tests", "https://github.com/pull/3", "https://github.com/o/r/pull/abc"} {
+		_, err := promotion.ParsePullRequestURL(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestExpectVersionIncremented(t *testing.T) {
+	assert.NoError(t, promotion.ExpectVersionIncremented("0.0.1", "0.0.2"))
+	assert.NoError(t, promotion.ExpectVersionIncremented("0.0.9", "0.0.10"))
+	assert.NoError(t, promotion.ExpectVersionIncremented("v0.1.3", "0.2.0"))
+	assert.Error(t, promotion.ExpectVersionIncremented("0.0.2", "0.0.2"))
+	assert.Error(t, promotion.ExpectVersionIncremented("0.0.10", "0.0.9"))
+	assert.Error(t, promotion.ExpectVersionIncremented("0.0.1", "latest"))
+}
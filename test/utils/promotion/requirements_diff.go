@@ -0,0 +1,124 @@
+package promotion
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RequirementsDiff is the difference between two versions of the requirements.yaml of an environment, such as the
+// base and head of a pull request on the environment repository
+type RequirementsDiff struct {
+	Added   []Dependency
+	Removed []Dependency
+	Changed []DependencyChange
+}
+
+// DependencyChange is a dependency whose version or repository changed
+type DependencyChange struct {
+	From Dependency
+	To   Dependency
+}
+
+// DiffRequirements returns the dependencies added to, removed from and changed in the base requirements by the head
+// requirements. Dependencies are matched by their alias, or by their name if they have none.
+func DiffRequirements(base *Requirements, head *Requirements) *RequirementsDiff {
+	diff := &RequirementsDiff{}
+	before := map[string]Dependency{}
+	for _, d := range base.Dependencies {
+		before[d.key()] = d
+	}
+	after := map[string]bool{}
+	for _, d := range head.Dependencies {
+		after[d.key()] = true
+		previous, ok := before[d.key()]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, d)
+		case previous.Version != d.Version || previous.Repository != d.Repository:
+			diff.Changed = append(diff.Changed, DependencyChange{From: previous, To: d})
+		}
+	}
+	for _, d := range base.Dependencies {
+		if !after[d.key()] {
+			diff.Removed = append(diff.Removed, d)
+		}
+	}
+	return diff
+}
+
+// ExpectAdded returns an error unless the only change is the app being added in the given version from the given
+// chart repository
+func (d *RequirementsDiff) ExpectAdded(app string, version string, repository string) error {
+	if len(d.Added) != 1 || len(d.Removed) != 0 || len(d.Changed) != 0 || !d.Added[0].is(app) {
+		return fmt.Errorf("expected only %s to be added to the requirements but %s", app, d)
+	}
+	added := d.Added[0]
+	if version != "" && added.Version != version {
+		return fmt.Errorf("expected %s to be added in version %s but it was added in version %s", app, version, added.Version)
+	}
+	if repository != "" && !sameURL(added.Repository, repository) {
+		return fmt.Errorf("expected %s to be added from %s but it was added from %s", app, repository, added.Repository)
+	}
+	return nil
+}
+
+// ExpectUpgraded returns an error unless the only change is the app being upgraded from the given version to the
+// other, or to any other version if it is empty
+func (d *RequirementsDiff) ExpectUpgraded(app string, from string, to string) error {
+	if len(d.Added) != 0 || len(d.Removed) != 0 || len(d.Changed) != 1 || !d.Changed[0].To.is(app) {
+		return fmt.Errorf("expected only %s to be upgraded in the requirements but %s", app, d)
+	}
+	change := d.Changed[0]
+	if change.From.Version != from || change.To.Version == from || (to != "" && change.To.Version != to) {
+		return fmt.Errorf("expected %s to be upgraded from version %s to %s but %s", app, from, orAny(to), d)
+	}
+	return nil
+}
+
+// ExpectRemoved returns an error unless the only change is the app being removed
+func (d *RequirementsDiff) ExpectRemoved(app string) error {
+	if len(d.Added) != 0 || len(d.Removed) != 1 || len(d.Changed) != 0 || !d.Removed[0].is(app) {
+		return fmt.Errorf("expected only %s to be removed from the requirements but %s", app, d)
+	}
+	return nil
+}
+
+// String describes the changes, such as "added foo 0.0.1, removed bar 1.0.0"
+func (d *RequirementsDiff) String() string {
+	var changes []string
+	for _, a := range d.Added {
+		changes = append(changes, fmt.Sprintf("added %s %s", a.key(), a.Version))
+	}
+	for _, r := range d.Removed {
+		changes = append(changes, fmt.Sprintf("removed %s %s", r.key(), r.Version))
+	}
+	for _, c := range d.Changed {
+		changes = append(changes, fmt.Sprintf("changed %s from %s %s to %s %s", c.To.key(), c.From.Version, c.From.Repository, c.To.Version, c.To.Repository))
+	}
+	if len(changes) == 0 {
+		return "nothing changed"
+	}
+	return strings.Join(changes, ", ")
+}
+
+func (d Dependency) key() string {
+	if d.Alias != "" {
+		return d.Alias
+	}
+	return d.Name
+}
+
+func (d Dependency) is(app string) bool {
+	return sameApp(d.Name, app) || (d.Alias != "" && sameApp(d.Alias, app))
+}
+
+func sameURL(a string, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+func orAny(version string) string {
+	if version == "" {
+		return "any other version"
+	}
+	return version
+}
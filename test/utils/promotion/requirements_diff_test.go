@@ -0,0 +1,90 @@
+package promotion_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/promotion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const baseRequirements = `dependencies:
+- name: exposecontroller
+  version: 2.3.118
+  repository: http://chartmuseum.jenkins-x.io
+- name: bdd-app
+  version: 0.0.1
+  repository: http://charts.example.com/
+`
+
+func diff(t *testing.T, base string, head string) *promotion.RequirementsDiff {
+	b, err := promotion.ParseRequirements([]byte(base))
+	require.NoError(t, err)
+	h, err := promotion.ParseRequirements([]byte(head))
+	require.NoError(t, err)
+	return promotion.DiffRequirements(b, h)
+}
+
+func TestDiffRequirements(t *testing.T) {
+	d := diff(t, baseRequirements, `dependencies:
+- name: exposecontroller
+  version: 2.3.119
+  repository: http://chartmuseum.jenkins-x.io
+- name: jx-app-jacoco
+  version: 0.0.139
+  repository: http://chartmuseum.jenkins-x.io
+`)
+	require.Len(t, d.Added, 1)
+	assert.Equal(t, "jx-app-jacoco", d.Added[0].Name)
+	require.Len(t, d.Removed, 1)
+	assert.Equal(t, "bdd-app", d.Removed[0].Name)
+	require.Len(t, d.Changed, 1)
+	assert.Equal(t, "2.3.118", d.Changed[0].From.Version)
+	assert.Equal(t, "2.3.119", d.Changed[0].To.Version)
+	assert.Equal(t, "added jx-app-jacoco 0.0.139, removed bdd-app 0.0.1, changed exposecontroller from 2.3.118 http://chartmuseum.jenkins-x.io to 2.3.119 http://chartmuseum.jenkins-x.io", d.String())
+}
+
+func TestExpectAdded(t *testing.T) {
+	d := diff(t, "dependencies: []\n", baseRequirements)
+	assert.EqualError(t, d.ExpectAdded("bdd-app", "0.0.1", ""), "expected only bdd-app to be added to the requirements but added exposecontroller 2.3.118, added bdd-app 0.0.1")
+
+	d = diff(t, `dependencies:
+- name: exposecontroller
+  version: 2.3.118
+  repository: http://chartmuseum.jenkins-x.io
+`, baseRequirements)
+	assert.NoError(t, d.ExpectAdded("bdd-app", "0.0.1", "http://charts.example.com"))
+	assert.NoError(t, d.ExpectAdded("bdd-app", "", ""))
+	assert.EqualError(t, d.ExpectAdded("bdd-app", "0.0.2", ""), "expected bdd-app to be added in version 0.0.2 but it was added in version 0.0.1")
+	assert.EqualError(t, d.ExpectAdded("bdd-app", "0.0.1", "http://chartmuseum.jenkins-x.io"), "expected bdd-app to be added from http://chartmuseum.jenkins-x.io but it was added from http://charts.example.com/")
+	assert.Error(t, d.ExpectRemoved("bdd-app"))
+}
+
+func TestExpectUpgraded(t *testing.T) {
+	d := diff(t, baseRequirements, `dependencies:
+- name: exposecontroller
+  version: 2.3.118
+  repository: http://chartmuseum.jenkins-x.io
+- name: bdd-app
+  version: 0.0.2
+  repository: http://charts.example.com/
+`)
+	assert.NoError(t, d.ExpectUpgraded("bdd-app", "0.0.1", "0.0.2"))
+	assert.NoError(t, d.ExpectUpgraded("bdd-app", "0.0.1", ""))
+	assert.Error(t, d.ExpectUpgraded("bdd-app", "0.0.1", "0.0.3"))
+	assert.Error(t, d.ExpectUpgraded("bdd-app", "0.0.2", ""))
+
+	unchanged := diff(t, baseRequirements, baseRequirements)
+	assert.EqualError(t, unchanged.ExpectUpgraded("bdd-app", "0.0.1", ""), "expected only bdd-app to be upgraded in the requirements but nothing changed")
+}
+
+func TestExpectRemoved(t *testing.T) {
+	d := diff(t, baseRequirements, `dependencies:
+- name: exposecontroller
+  version: 2.3.118
+  repository: http://chartmuseum.jenkins-x.io
+`)
+	assert.NoError(t, d.ExpectRemoved("bdd-app"))
+	assert.Error(t, d.ExpectRemoved("exposecontroller"))
+	assert.Error(t, d.ExpectAdded("bdd-app", "", ""))
+}
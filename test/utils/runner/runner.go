@@ -1,12 +1,15 @@
 package runner
 
 import (
+	"bytes"
+	"context"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -33,6 +36,7 @@ type JxRunner struct {
 	cwd      string
 	timeout  time.Duration
 	exitCode int
+	env      []string
 }
 
 // New creates a new jx command runnner
@@ -47,6 +51,12 @@ func New(cwd string, timeout *time.Duration, exitCode int) *JxRunner {
 	}
 }
 
+// WithEnv adds environment variables, such as NAME=value, to those of the process that jx commands are run with
+func (r *JxRunner) WithEnv(env ...string) *JxRunner {
+	r.env = append(r.env, env...)
+	return r
+}
+
 // Run runs a jx command
 func (r *JxRunner) Run(args ...string) {
 	err := r.run(GinkgoWriter, GinkgoWriter, args...)
@@ -60,6 +70,9 @@ func (r *JxRunner) run(out io.Writer, errOut io.Writer, args ...string) error {
 
 	command := exec.Command(JxBin(), args...)
 	command.Dir = r.cwd
+	if len(r.env) > 0 {
+		command.Env = append(os.Environ(), r.env...)
+	}
 	session, err := gexec.Start(command, out, errOut)
 	if err != nil {
 		return errors.WithStack(err)
@@ -75,6 +88,24 @@ func (r *JxRunner) run(out io.Writer, errOut io.Writer, args ...string) error {
 	return nil
 }
 
+// Start starts a jx command which runs until it is terminated, such as jx sync, returning its session. The timeout and
+// exit code of the runner do not apply.
+func (r *JxRunner) Start(args ...string) (*gexec.Session, error) {
+	if testing.Verbose() {
+		utils.LogInfof("\033[1mRUNNER:\033[0mStarting jx %s in %s\n", strings.Join(args, " "), r.cwd)
+	}
+	command := exec.Command(JxBin(), args...)
+	command.Dir = r.cwd
+	if len(r.env) > 0 {
+		command.Env = append(os.Environ(), r.env...)
+	}
+	session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return session, nil
+}
+
 // Run runs a jx command
 func (r *JxRunner) RunWithOutput(args ...string) (string, error) {
 	rOut, out, err := os.Pipe()
@@ -103,6 +134,66 @@ func (r *JxRunner) RunWithOutput(args ...string) (string, error) {
 	return strings.TrimSpace(RemoveCoverageText(answer, args...)), nil
 }
 
+// RunWithBufferedOutput runs a jx command, buffering its combined output in memory rather than in a pipe so that
+// commands printing a lot, such as jx get build logs, cannot block on writing it. The output is returned even if the
+// command does not exit with the expected exit code.
+func (r *JxRunner) RunWithBufferedOutput(args ...string) (string, error) {
+	out := &lockedBuffer{}
+	err := r.run(io.MultiWriter(out, GinkgoWriter), out, args...)
+	return strings.TrimSpace(RemoveCoverageText(out.String(), args...)), err
+}
+
+// RunInBackground runs a jx command with the timeout of the runner, returning its combined output and an error if it
+// does not exit with the expected exit code in time. Unlike the other methods it makes no Gomega assertions, so it can
+// be called from goroutines other than the one of the spec.
+func (r *JxRunner) RunInBackground(args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	command := exec.CommandContext(ctx, JxBin(), args...)
+	command.Dir = r.cwd
+	if len(r.env) > 0 {
+		command.Env = append(os.Environ(), r.env...)
+	}
+	// don't wait for the output of processes jx started which outlive it once it is killed
+	command.WaitDelay = time.Second
+	out := &lockedBuffer{}
+	command.Stdout = io.MultiWriter(out, GinkgoWriter)
+	command.Stderr = command.Stdout
+	err := command.Run()
+	answer := strings.TrimSpace(RemoveCoverageText(out.String(), args...))
+	if ctx.Err() == context.DeadlineExceeded {
+		return answer, errors.Errorf("timed out after %s running %s %s", r.timeout, Jx, strings.Join(args, " "))
+	}
+	exitCode := 0
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		return answer, errors.Wrapf(err, "running %s %s", Jx, strings.Join(args, " "))
+	}
+	if exitCode != r.exitCode {
+		return answer, errors.Errorf("expected exit code %d but got %d whilst running command %s %s: %s", r.exitCode, exitCode, Jx, strings.Join(args, " "), answer)
+	}
+	return answer, nil
+}
+
+// lockedBuffer is a buffer the output and error output of a command can both be written to concurrently
+type lockedBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
 // Run runs a jx command
 func (r *JxRunner) RunWithOutputNoTimeout(args ...string) (string, error) {
 	argsStr := strings.Join(args, " ")
@@ -1,10 +1,13 @@
 package runner_test
 
 import (
+	"os"
 	"testing"
+	"time"
 
 	"github.com/jenkins-x/bdd-jx/test/utils/runner"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCoverageRegex(t *testing.T) {
@@ -18,3 +21,25 @@ bdd-spring-1562755897 0.0.1   1/1  http://bdd-spring-1562755897.jx-staging.35.20
 `, out)
 
 }
+
+func TestRunInBackground(t *testing.T) {
+	os.Setenv("BDD_JX", "sh")
+	defer os.Unsetenv("BDD_JX")
+	timeout := 5 * time.Second
+
+	out, err := runner.New(t.TempDir(), &timeout, 0).RunInBackground("-c", "echo hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", out)
+
+	out, err = runner.New(t.TempDir(), &timeout, 3).RunInBackground("-c", "echo failed; exit 3")
+	require.NoError(t, err)
+	assert.Equal(t, "failed", out)
+
+	_, err = runner.New(t.TempDir(), &timeout, 0).RunInBackground("-c", "exit 1")
+	assert.Error(t, err)
+
+	timeout = 100 * time.Millisecond
+	_, err = runner.New(t.TempDir(), &timeout, 0).RunInBackground("-c", "sleep 5")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+}
@@ -0,0 +1,426 @@
+package scenarios
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/buildlogs"
+	"github.com/jenkins-x/bdd-jx/test/utils/imports"
+	"github.com/jenkins-x/bdd-jx/test/utils/pipelines"
+	"github.com/jenkins-x/bdd-jx/test/utils/probes"
+	"github.com/jenkins-x/bdd-jx/test/utils/projects"
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// SourceQuickstart creates the application with jx create quickstart
+	SourceQuickstart = "quickstart"
+	// SourceImport creates the application with jx import of a fixture project or a cloned git repository
+	SourceImport = "import"
+	// SourceSpring creates the application with jx create spring
+	SourceSpring = "spring"
+
+	// FailureFail adds a step which exits with an error to the release pipeline
+	FailureFail = "fail"
+	// FailureTimeout replaces the release pipeline with one which runs for longer than its timeout, so that Tekton
+	// times out its PipelineRun
+	FailureTimeout = "timeout"
+	// FailureStop stops the release pipeline with jx stop pipeline while it is running
+	FailureStop = "stop"
+
+	// DefaultFailureTimeout is the timeout of the release pipeline of a timeout failure by default
+	DefaultFailureTimeout = time.Minute
+	// StopDelay is how long the release pipeline of a stop failure waits, which is far longer than it takes to stop it
+	StopDelay = time.Hour
+)
+
+// Scenario describes how to create an application and what to expect once its pipelines have run
+type Scenario struct {
+	// Name of the scenario, defaulting to the name of the file without its extension
+	Name   string `json:"name,omitempty"`
+	Source Source `json:"source"`
+	// Environments the application is expected to be promoted to automatically by its release pipeline
+	Environments []Environment `json:"environments,omitempty"`
+	// PullRequest configures testing a pull request, which is skipped if not set
+	PullRequest *PullRequest `json:"pullRequest,omitempty"`
+	// Promotions are manual promotions to run after the release pipeline
+	Promotions []Promotion `json:"promotions,omitempty"`
+	// Cleanup deletes the application and repository at the end, defaulting to true
+	Cleanup *bool `json:"cleanup,omitempty"`
+	// SkipIfEnv skips the scenario if any of these environment variables are set
+	SkipIfEnv []string `json:"skipIfEnv,omitempty"`
+	// Imported is what jx import is expected to produce from the source, which is not checked if not set
+	Imported *imports.Expectation `json:"imported,omitempty"`
+	// BuildLog is what the log of the first release of master is expected to contain. It is always checked for
+	// buildlogs.DefaultForbidden patterns unless it lists its own forbidden patterns.
+	BuildLog *buildlogs.Expectation `json:"buildLog,omitempty"`
+	// Failures are commits pushed to master whose release pipelines go wrong, in order, after which a commit restoring
+	// the jenkins-x.yml is expected to release and promote again
+	Failures []Failure `json:"failures,omitempty"`
+}
+
+// Source is where the application is created from. Exactly one field must be set.
+type Source struct {
+	// Quickstart is the name of the quickstart to create
+	Quickstart string `json:"quickstart,omitempty"`
+	// Fixture is the name of a fixture project in the fixture directory of the suite to import
+	Fixture string `json:"fixture,omitempty"`
+	// Import is the URL of a git repository to clone and import, to import a project which is not a fixture
+	Import string `json:"import,omitempty"`
+	// Spring creates a spring application with the given dependencies
+	Spring *Spring `json:"spring,omitempty"`
+}
+
+// Spring configures jx create spring
+type Spring struct {
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+// Expectation is what the application is expected to return over HTTP. The inline check applies to the URL of the
+// application itself.
+type Expectation struct {
+	probes.Check `json:",inline"`
+	// Paths are further paths of the application to check, such as /actuator/health
+	Paths []probes.Check `json:"paths,omitempty"`
+	// Retry overrides how long and how often the checks are retried
+	Retry *probes.RetryPolicy `json:"retry,omitempty"`
+}
+
+// Environment is an environment the application is expected to run in
+type Environment struct {
+	Name   string      `json:"name"`
+	Expect Expectation `json:"expect,omitempty"`
+}
+
+// PullRequest configures testing a pull request and its preview environment
+type PullRequest struct {
+	Preview Expectation `json:"preview,omitempty"`
+	// ChangeResponse changes a string the application responds with in the pull request, rather than its README.md
+	ChangeResponse *ResponseChange `json:"changeResponse,omitempty"`
+	// Set sets values in XML, JSON or YAML files of the application in the pull request, rather than changing its
+	// README.md
+	Set []projects.Value `json:"set,omitempty"`
+	// Merge merges the pull request, then checks master releases a greater version which is promoted to staging with
+	// the change and that the preview environment is garbage collected
+	Merge bool `json:"merge,omitempty"`
+	// Close closes the pull request without merging it and checks the preview environment is garbage collected
+	Close bool `json:"close,omitempty"`
+}
+
+// ResponseChange configures which string the pull request changes and where it is served
+type ResponseChange struct {
+	// Match is a regular expression matching the string in the source to change, defaulting to one containing hello
+	Match string `json:"match,omitempty"`
+	// Path is the path of the application which responds with the string
+	Path string `json:"path,omitempty"`
+	// Environment is the environment which should still serve the old string, defaulting to staging
+	Environment string `json:"environment,omitempty"`
+}
+
+// Promotion is a promotion of a version of the application to an environment. If the environment is promoted to
+// automatically the promotion is only verified, otherwise it is run with jx promote.
+type Promotion struct {
+	Environment string `json:"environment"`
+	// Version to promote, defaulting to the version released by the master pipeline
+	Version string      `json:"version,omitempty"`
+	Expect  Expectation `json:"expect,omitempty"`
+	// SkipIfEnv skips the promotion if any of these environment variables are set
+	SkipIfEnv []string `json:"skipIfEnv,omitempty"`
+}
+
+// Failure is a commit pushed to master whose release pipeline goes wrong. The PipelineActivity of its build is
+// expected to finish with the failure status, the commit status on the git provider to report the failure and nothing
+// to be promoted.
+type Failure struct {
+	// Kind is how the release pipeline goes wrong: fail, timeout or stop
+	Kind string `json:"kind"`
+	// Lifecycle of the release pipeline which goes wrong, defaulting to build. Timeout failures replace the whole
+	// pipeline so they have no lifecycle.
+	Lifecycle string `json:"lifecycle,omitempty"`
+	// Timeout is the timeout of the release pipeline of a timeout failure, defaulting to 1m
+	Timeout string `json:"timeout,omitempty"`
+	// Status is the status the PipelineActivity is expected to finish with, defaulting to Failed, or Aborted when the
+	// pipeline is stopped
+	Status v1.ActivityStatusType `json:"status,omitempty"`
+}
+
+// Load loads and validates the scenario in the given YAML file
+func Load(path string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading scenario %s", path)
+	}
+	scenario := &Scenario{}
+	err = yaml.UnmarshalStrict(data, scenario)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing scenario %s", path)
+	}
+	if scenario.Name == "" {
+		scenario.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	err = scenario.Validate()
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid scenario %s", path)
+	}
+	return scenario, nil
+}
+
+// LoadDir loads and validates the scenarios in the YAML files in the given directory, ordered by file name
+func LoadDir(dir string) ([]*Scenario, error) {
+	var paths []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no scenario files found in %s", dir)
+	}
+	sort.Strings(paths)
+
+	var scenarios []*Scenario
+	for _, path := range paths {
+		scenario, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios, nil
+}
+
+// Validate returns an error if the scenario is not complete
+func (s *Scenario) Validate() error {
+	sources := 0
+	if s.Source.Quickstart != "" {
+		sources++
+	}
+	if s.Source.Fixture != "" {
+		sources++
+	}
+	if s.Source.Import != "" {
+		sources++
+	}
+	if s.Source.Spring != nil {
+		sources++
+	}
+	if sources != 1 {
+		return fmt.Errorf("scenario %s must have exactly one of source.quickstart, source.fixture, source.import or source.spring", s.Name)
+	}
+	if s.Imported != nil {
+		if s.SourceKind() != SourceImport {
+			return fmt.Errorf("scenario %s can only expect what is imported when importing", s.Name)
+		}
+		if len(s.Imported.Untouched) > 0 && s.Source.Fixture == "" {
+			return fmt.Errorf("scenario %s can only expect untouched files when importing a fixture", s.Name)
+		}
+	}
+	for i, e := range s.Environments {
+		if e.Name == "" {
+			return fmt.Errorf("environments[%d] has no name", i)
+		}
+		if err := e.Expect.validate(); err != nil {
+			return errors.Wrapf(err, "environment %s", e.Name)
+		}
+	}
+	if s.PullRequest != nil {
+		if err := s.PullRequest.Preview.validate(); err != nil {
+			return errors.Wrap(err, "pullRequest.preview")
+		}
+		if s.PullRequest.ChangeResponse != nil && len(s.PullRequest.Set) > 0 {
+			return fmt.Errorf("pullRequest cannot both change the response and set values")
+		}
+		for i, v := range s.PullRequest.Set {
+			if v.File == "" || len(v.Path) == 0 {
+				return fmt.Errorf("pullRequest.set[%d] must have a file and a path", i)
+			}
+		}
+		if s.PullRequest.Merge && s.PullRequest.Close {
+			return fmt.Errorf("pullRequest cannot both merge and close the pull request")
+		}
+		if c := s.PullRequest.ChangeResponse; c != nil && c.Match != "" {
+			if _, err := regexp.Compile(c.Match); err != nil {
+				return errors.Wrap(err, "invalid pullRequest.changeResponse.match")
+			}
+		}
+	}
+	if s.BuildLog != nil {
+		if err := s.BuildLog.Validate(); err != nil {
+			return errors.Wrap(err, "buildLog")
+		}
+	}
+	for i, f := range s.Failures {
+		if err := f.validate(); err != nil {
+			return errors.Wrapf(err, "failures[%d]", i)
+		}
+	}
+	for i, p := range s.Promotions {
+		if p.Environment == "" {
+			return fmt.Errorf("promotions[%d] must have an environment", i)
+		}
+		if err := p.Expect.validate(); err != nil {
+			return errors.Wrapf(err, "promotion to %s", p.Environment)
+		}
+	}
+	return nil
+}
+
+// SourceKind returns which of the sources the scenario creates the application from
+func (s *Scenario) SourceKind() string {
+	switch {
+	case s.Source.Fixture != "", s.Source.Import != "":
+		return SourceImport
+	case s.Source.Spring != nil:
+		return SourceSpring
+	default:
+		return SourceQuickstart
+	}
+}
+
+// ShouldCleanup returns true if the application and repository should be deleted at the end of the scenario
+func (s *Scenario) ShouldCleanup() bool {
+	return s.Cleanup == nil || *s.Cleanup
+}
+
+// SkipReason returns why the scenario should be skipped, or an empty string if it should run
+func (s *Scenario) SkipReason() string {
+	return skipReason(s.SkipIfEnv)
+}
+
+// SkipReason returns why the promotion should be skipped, or an empty string if it should run
+func (p *Promotion) SkipReason() string {
+	return skipReason(p.SkipIfEnv)
+}
+
+func skipReason(envVars []string) string {
+	for _, name := range envVars {
+		if _, ok := os.LookupEnv(name); ok {
+			return fmt.Sprintf("$%s is set", name)
+		}
+	}
+	return ""
+}
+
+// Description describes how the release pipeline goes wrong
+func (f *Failure) Description() string {
+	switch f.Kind {
+	case FailureTimeout:
+		return fmt.Sprintf("a pipeline exceeding its timeout of %s", f.TimeoutOrDefault())
+	case FailureStop:
+		return fmt.Sprintf("stopping the pipeline during the %s lifecycle", f.LifecycleOrDefault())
+	default:
+		return fmt.Sprintf("a failing step in the %s lifecycle", f.LifecycleOrDefault())
+	}
+}
+
+// LifecycleOrDefault returns the lifecycle of the release pipeline which goes wrong
+func (f *Failure) LifecycleOrDefault() string {
+	if f.Lifecycle == "" {
+		return pipelines.Build
+	}
+	return f.Lifecycle
+}
+
+// TimeoutOrDefault returns the timeout of the release pipeline of a timeout failure
+func (f *Failure) TimeoutOrDefault() time.Duration {
+	d, err := time.ParseDuration(f.Timeout)
+	if err != nil || d <= 0 {
+		return DefaultFailureTimeout
+	}
+	return d
+}
+
+// FailedStep returns the name of the step the build log is expected to report the failure on, or an empty string if
+// the pipeline is stopped or timed out rather than failing on a step
+func (f *Failure) FailedStep() string {
+	switch f.Kind {
+	case FailureTimeout, FailureStop:
+		return ""
+	default:
+		return f.LifecycleOrDefault() + "-" + pipelines.StepPrefix + "fail"
+	}
+}
+
+// StatusOrDefault returns the status the PipelineActivity is expected to finish with
+func (f *Failure) StatusOrDefault() v1.ActivityStatusType {
+	switch {
+	case f.Status != "":
+		return f.Status
+	case f.Kind == FailureStop:
+		return v1.ActivityStatusTypeAborted
+	default:
+		return v1.ActivityStatusTypeFailed
+	}
+}
+
+// Pipeline returns the jenkins-x.yml which makes the release pipeline of the build pack go wrong. The pipeline of a
+// stop failure waits for StopDelay so that it is still running when it is stopped.
+func (f *Failure) Pipeline(buildPack string) *pipelines.Config {
+	c := pipelines.NewConfig(buildPack)
+	switch f.Kind {
+	case FailureTimeout:
+		c.Release().ExceedTimeout(f.TimeoutOrDefault())
+	case FailureStop:
+		c.Release().Delay(f.LifecycleOrDefault(), StopDelay)
+	default:
+		c.Release().Fail(f.LifecycleOrDefault())
+	}
+	return c
+}
+
+func (f *Failure) validate() error {
+	switch f.Kind {
+	case FailureFail, FailureTimeout, FailureStop:
+	default:
+		return fmt.Errorf("kind must be one of %s, %s or %s but was '%s'", FailureFail, FailureTimeout, FailureStop, f.Kind)
+	}
+	if f.Kind == FailureTimeout && f.Lifecycle != "" {
+		return fmt.Errorf("%s failures replace the whole pipeline so cannot have a lifecycle", FailureTimeout)
+	}
+	if f.Timeout != "" {
+		if f.Kind != FailureTimeout {
+			return fmt.Errorf("only %s failures can have a timeout", FailureTimeout)
+		}
+		if d, err := time.ParseDuration(f.Timeout); err != nil || d <= 0 {
+			return fmt.Errorf("invalid timeout '%s'", f.Timeout)
+		}
+	}
+	if f.Status != "" && !pipelines.IsTerminal(f.Status) {
+		return fmt.Errorf("status %s is not one a PipelineActivity finishes with", f.Status)
+	}
+	if f.Status == v1.ActivityStatusTypeSucceeded {
+		return fmt.Errorf("status cannot be %s", f.Status)
+	}
+	return nil
+}
+
+// EnvironmentOrDefault returns the environment which should still serve the old string
+func (c *ResponseChange) EnvironmentOrDefault() string {
+	if c.Environment == "" {
+		return "staging"
+	}
+	return c.Environment
+}
+
+// Probe returns the probe which checks the application URL and each of the paths
+func (e *Expectation) Probe() *probes.Probe {
+	probe := &probes.Probe{Checks: append([]probes.Check{e.Check}, e.Paths...)}
+	if e.Retry != nil {
+		probe.Retry = *e.Retry
+	}
+	return probe
+}
+
+func (e *Expectation) validate() error {
+	return e.Probe().Validate()
+}
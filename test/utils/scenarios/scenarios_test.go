@@ -0,0 +1,295 @@
+package scenarios_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/buildlogs"
+	"github.com/jenkins-x/bdd-jx/test/utils/probes"
+	"github.com/jenkins-x/bdd-jx/test/utils/projects"
+	"github.com/jenkins-x/bdd-jx/test/utils/scenarios"
+	v1 "github.com/jenkins-x/jx-api/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadShippedScenarios(t *testing.T) {
+	dirs := []string{
+		"../../suite/quickstart/scenarios",
+		"../../suite/spring/scenarios",
+		"../../suite/_import/scenarios",
+	}
+	for _, dir := range dirs {
+		t.Run(dir, func(t *testing.T) {
+			loaded, err := scenarios.LoadDir(dir)
+			require.NoError(t, err)
+			assert.NotEmpty(t, loaded)
+		})
+	}
+}
+
+func TestLoadDefaultsNameToFileName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scenarios")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "node-http.yaml")
+	err = ioutil.WriteFile(path, []byte("source:\n  quickstart: node-http\nenvironments:\n- name: staging\n"), 0600)
+	require.NoError(t, err)
+
+	scenario, err := scenarios.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "node-http", scenario.Name)
+	assert.Equal(t, scenarios.SourceQuickstart, scenario.SourceKind())
+	assert.True(t, scenario.ShouldCleanup())
+	assert.Equal(t, 200, scenario.Environments[0].Expect.ExpectedStatus())
+}
+
+func TestLoadRejectsUnknownFields(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scenarios")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "typo.yaml")
+	err = ioutil.WriteFile(path, []byte("source:\n  quickstart: node-http\nenviroments: []\n"), 0600)
+	require.NoError(t, err)
+
+	_, err = scenarios.Load(path)
+	assert.Error(t, err)
+}
+
+func TestLoadDirWithoutScenarios(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scenarios")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = scenarios.LoadDir(dir)
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario scenarios.Scenario
+		valid    bool
+	}{
+		{
+			name:     "quickstart",
+			scenario: scenarios.Scenario{Source: scenarios.Source{Quickstart: "golang-http"}},
+			valid:    true,
+		},
+		{
+			name:     "spring",
+			scenario: scenarios.Scenario{Source: scenarios.Source{Spring: &scenarios.Spring{Dependencies: []string{"web"}}}},
+			valid:    true,
+		},
+		{
+			name:     "no source",
+			scenario: scenarios.Scenario{},
+		},
+		{
+			name:     "two sources",
+			scenario: scenarios.Scenario{Source: scenarios.Source{Quickstart: "golang-http", Import: "https://github.com/jenkins-x-quickstarts/golang-http"}},
+		},
+		{
+			name: "environment without name",
+			scenario: scenarios.Scenario{
+				Source:       scenarios.Source{Quickstart: "golang-http"},
+				Environments: []scenarios.Environment{{}},
+			},
+		},
+		{
+			name: "invalid regular expression",
+			scenario: scenarios.Scenario{
+				Source:      scenarios.Source{Quickstart: "golang-http"},
+				PullRequest: &scenarios.PullRequest{Preview: scenarios.Expectation{Check: probes.Check{BodyMatches: "("}}},
+			},
+		},
+		{
+			name: "merge and close pull request",
+			scenario: scenarios.Scenario{
+				Source:      scenarios.Source{Quickstart: "golang-http"},
+				PullRequest: &scenarios.PullRequest{Merge: true, Close: true},
+			},
+		},
+		{
+			name: "change response and set values",
+			scenario: scenarios.Scenario{
+				Source: scenarios.Source{Quickstart: "golang-http"},
+				PullRequest: &scenarios.PullRequest{
+					ChangeResponse: &scenarios.ResponseChange{},
+					Set:            []projects.Value{{File: "package.json", Path: []string{"description"}, Value: "changed"}},
+				},
+			},
+		},
+		{
+			name: "set value without path",
+			scenario: scenarios.Scenario{
+				Source:      scenarios.Source{Quickstart: "golang-http"},
+				PullRequest: &scenarios.PullRequest{Set: []projects.Value{{File: "package.json", Value: "changed"}}},
+			},
+		},
+		{
+			name: "failures",
+			scenario: scenarios.Scenario{
+				Source:   scenarios.Source{Quickstart: "golang-http"},
+				Failures: []scenarios.Failure{{Kind: "fail"}, {Kind: "timeout", Timeout: "30s"}, {Kind: "stop", Status: "Failed"}},
+			},
+			valid: true,
+		},
+		{
+			name: "invalid build log pattern",
+			scenario: scenarios.Scenario{
+				Source:   scenarios.Source{Quickstart: "golang-http"},
+				BuildLog: &buildlogs.Expectation{Forbidden: []string{"("}},
+			},
+		},
+		{
+			name: "unknown failure kind",
+			scenario: scenarios.Scenario{
+				Source:   scenarios.Source{Quickstart: "golang-http"},
+				Failures: []scenarios.Failure{{Kind: "explode"}},
+			},
+		},
+		{
+			name: "lifecycle of a timeout",
+			scenario: scenarios.Scenario{
+				Source:   scenarios.Source{Quickstart: "golang-http"},
+				Failures: []scenarios.Failure{{Kind: "timeout", Lifecycle: "build"}},
+			},
+		},
+		{
+			name: "timeout of a failing step",
+			scenario: scenarios.Scenario{
+				Source:   scenarios.Source{Quickstart: "golang-http"},
+				Failures: []scenarios.Failure{{Kind: "fail", Timeout: "1m"}},
+			},
+		},
+		{
+			name: "failure expected to succeed",
+			scenario: scenarios.Scenario{
+				Source:   scenarios.Source{Quickstart: "golang-http"},
+				Failures: []scenarios.Failure{{Kind: "fail", Status: "Succeeded"}},
+			},
+		},
+		{
+			name: "promotion without environment",
+			scenario: scenarios.Scenario{
+				Source:     scenarios.Source{Quickstart: "golang-http"},
+				Promotions: []scenarios.Promotion{{Version: "0.0.1"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.scenario.Validate()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestExpectationProbe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scenarios")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "spring.yaml")
+	data := `source:
+  spring:
+    dependencies: [web, actuator]
+environments:
+- name: staging
+  expect:
+    status: 404
+    paths:
+    - path: /actuator/health
+      jsonPaths:
+        status: UP
+      maxLatency: 2s
+    retry:
+      timeout: 5m
+      successes: 2
+`
+	err = ioutil.WriteFile(path, []byte(data), 0600)
+	require.NoError(t, err)
+
+	scenario, err := scenarios.Load(path)
+	require.NoError(t, err)
+
+	probe := scenario.Environments[0].Expect.Probe()
+	require.Len(t, probe.Checks, 2)
+	assert.Equal(t, 404, probe.Checks[0].ExpectedStatus())
+	assert.Equal(t, "/actuator/health", probe.Checks[1].Path)
+	assert.Equal(t, 200, probe.Checks[1].ExpectedStatus())
+	assert.Equal(t, map[string]string{"status": "UP"}, probe.Checks[1].JSONPaths)
+	assert.Equal(t, 2*time.Second, probe.Checks[1].MaxLatency.Duration)
+	assert.Equal(t, 5*time.Minute, probe.Retry.Timeout.Duration)
+	assert.Equal(t, 2, probe.Retry.Successes)
+}
+
+func TestSkipReason(t *testing.T) {
+	const envVar = "BDD_SCENARIOS_TEST_SKIP"
+	scenario := scenarios.Scenario{SkipIfEnv: []string{envVar}}
+	assert.Empty(t, scenario.SkipReason())
+
+	os.Setenv(envVar, "true")
+	defer os.Unsetenv(envVar)
+	assert.Contains(t, scenario.SkipReason(), envVar)
+}
+
+func TestLoadChangeResponse(t *testing.T) {
+	scenario, err := scenarios.Load("../../suite/quickstart/scenarios/golang-http.yaml")
+	require.NoError(t, err)
+	require.NotNil(t, scenario.PullRequest)
+	require.NotNil(t, scenario.PullRequest.ChangeResponse)
+	assert.Equal(t, "staging", scenario.PullRequest.ChangeResponse.EnvironmentOrDefault())
+	assert.True(t, scenario.PullRequest.Merge)
+	require.NotNil(t, scenario.BuildLog)
+	assert.Equal(t, []string{"Build Make Linux"}, scenario.BuildLog.Succeeded)
+}
+
+func TestLoadClosePullRequest(t *testing.T) {
+	scenario, err := scenarios.Load("../../suite/quickstart/scenarios/node-http-closed-pr.yaml")
+	require.NoError(t, err)
+	require.NotNil(t, scenario.PullRequest)
+	assert.True(t, scenario.PullRequest.Close)
+	require.Len(t, scenario.PullRequest.Set, 1)
+	assert.Equal(t, []string{"description"}, scenario.PullRequest.Set[0].Path)
+	assert.False(t, scenario.PullRequest.Merge)
+}
+
+func TestLoadFailures(t *testing.T) {
+	scenario, err := scenarios.Load("../../suite/quickstart/scenarios/golang-http-failures.yaml")
+	require.NoError(t, err)
+	require.Len(t, scenario.Failures, 3)
+
+	fail, timeout, stop := scenario.Failures[0], scenario.Failures[1], scenario.Failures[2]
+	assert.Equal(t, v1.ActivityStatusTypeFailed, fail.StatusOrDefault())
+	assert.Equal(t, "a failing step in the build lifecycle", fail.Description())
+	assert.Equal(t, time.Minute, timeout.TimeoutOrDefault())
+	assert.Equal(t, v1.ActivityStatusTypeFailed, timeout.StatusOrDefault())
+	assert.Equal(t, v1.ActivityStatusTypeAborted, stop.StatusOrDefault())
+	assert.Equal(t, "build-bdd-fail", fail.FailedStep())
+	assert.Empty(t, timeout.FailedStep())
+	assert.Empty(t, stop.FailedStep())
+
+	release := timeout.Pipeline("go").ProjectConfig().PipelineConfig.Pipelines.Release
+	require.NotNil(t, release)
+	require.NotNil(t, release.Pipeline)
+	assert.Equal(t, int64(60), release.Pipeline.Options.Timeout.Time)
+
+	project := stop.Pipeline("go").ProjectConfig()
+	require.NotNil(t, project.PipelineConfig)
+	overrides := project.PipelineConfig.Pipelines.Overrides
+	require.Len(t, overrides, 1)
+	assert.Equal(t, "release", overrides[0].Pipeline)
+	assert.Equal(t, "build", overrides[0].Stage)
+	assert.Equal(t, "sleep 3600", overrides[0].Steps[0].Sh)
+}
@@ -0,0 +1,99 @@
+package webhooks
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// HookLogEntry is a webhook which the Lighthouse webhooks pod logged while processing it
+type HookLogEntry struct {
+	Kind      scm.WebhookKind
+	Namespace string
+	Name      string
+	Message   string
+}
+
+// Repository returns the full name of the repository the webhook was for
+func (e *HookLogEntry) Repository() string {
+	return fmt.Sprintf("%s/%s", e.Namespace, e.Name)
+}
+
+// ParseHookLog returns the entries in a Lighthouse webhooks log which record a webhook being processed. Both the
+// default JSON log format and the logrus text format are understood; other lines are skipped.
+func ParseHookLog(log string) []*HookLogEntry {
+	var entries []*HookLogEntry
+	scanner := bufio.NewScanner(strings.NewReader(log))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		var fields map[string]string
+		if strings.HasPrefix(line, "{") {
+			fields = parseJSONFields(line)
+		} else {
+			fields = parseTextFields(line)
+		}
+		if fields["Webhook"] == "" || fields["Name"] == "" {
+			continue
+		}
+		entries = append(entries, &HookLogEntry{
+			Kind:      scm.WebhookKind(fields["Webhook"]),
+			Namespace: fields["Namespace"],
+			Name:      fields["Name"],
+			Message:   fields["msg"],
+		})
+	}
+	return entries
+}
+
+func parseJSONFields(line string) map[string]string {
+	values := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(line), &values); err != nil {
+		return nil
+	}
+	fields := map[string]string{}
+	for k, v := range values {
+		if s, ok := v.(string); ok {
+			fields[k] = s
+		}
+	}
+	return fields
+}
+
+// parseTextFields parses the key=value pairs of a logrus text formatted line, where values may be double quoted
+func parseTextFields(line string) map[string]string {
+	fields := map[string]string{}
+	for len(line) > 0 {
+		line = strings.TrimLeft(line, " ")
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			break
+		}
+		key := line[:eq]
+		line = line[eq+1:]
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			end := 1
+			for end < len(line) && (line[end] != '"' || line[end-1] == '\\') {
+				end++
+			}
+			value = strings.Replace(line[1:end], `\"`, `"`, -1)
+			if end < len(line) {
+				end++
+			}
+			line = line[end:]
+		} else {
+			end := strings.IndexByte(line, ' ')
+			if end < 0 {
+				end = len(line)
+			}
+			value = line[:end]
+			line = line[end:]
+		}
+		fields[key] = value
+	}
+	return fields
+}
@@ -0,0 +1,105 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// signatureHeaders are left out of recordings so that replaying a recording signs it with the token of the receiver
+var signatureHeaders = []string{"X-Hub-Signature", "X-Hub-Signature-256", "X-Gitlab-Token"}
+
+// Recording is a webhook captured from a git provider which can be replayed against a Receiver or Lighthouse
+type Recording struct {
+	Driver  string            `json:"driver"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// LoadRecording loads a recording from a JSON file
+func LoadRecording(path string) (*Recording, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading webhook recording %s", path)
+	}
+	recording := &Recording{}
+	err = json.Unmarshal(data, recording)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing webhook recording %s", path)
+	}
+	return recording, nil
+}
+
+// NewRecording creates a recording of a delivery sent by the given driver, without its signature
+func NewRecording(driver string, d *Delivery) *Recording {
+	recording := &Recording{
+		Driver:  driver,
+		Headers: map[string]string{},
+		Body:    json.RawMessage(d.Body),
+	}
+	for name := range d.Headers {
+		recording.Headers[name] = d.Headers.Get(name)
+	}
+	for _, name := range signatureHeaders {
+		delete(recording.Headers, http.CanonicalHeaderKey(name))
+	}
+	return recording
+}
+
+// Save writes the recording as JSON to the given path
+func (r *Recording) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Request returns a request delivering the recorded webhook to the given URL, signed with the given token
+func (r *Recording) Request(url string, token string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(r.Body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range r.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signature, err := Sign(r.Driver, r.Body, token)
+	if err != nil {
+		return nil, err
+	}
+	for name := range signature {
+		req.Header.Set(name, signature.Get(name))
+	}
+	return req, nil
+}
+
+// Replay delivers the recorded webhook to the given URL signed with the given token, returning an error unless it is
+// accepted
+func (r *Recording) Replay(url string, token string) error {
+	req, err := r.Request(url, token)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "replaying webhook to %s", url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("replaying webhook to %s returned %s: %s", url, resp.Status, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/github"
+	"github.com/jenkins-x/go-scm/scm/driver/gitlab"
+	"github.com/jenkins-x/go-scm/scm/driver/stash"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DriverGitHub is the go-scm driver for GitHub webhooks
+	DriverGitHub = "github"
+	// DriverGitLab is the go-scm driver for GitLab webhooks
+	DriverGitLab = "gitlab"
+	// DriverStash is the go-scm driver for Bitbucket Server webhooks
+	DriverStash = "stash"
+)
+
+// eventHeaders are the headers each driver uses to name the event being delivered
+var eventHeaders = map[string]string{
+	DriverGitHub: "X-GitHub-Event",
+	DriverGitLab: "X-Gitlab-Event",
+	DriverStash:  "X-Event-Key",
+}
+
+// Delivery is a webhook request captured by a Receiver
+type Delivery struct {
+	// Event is the provider specific event name from the request headers
+	Event string
+	// Kind is the go-scm kind of the parsed webhook, empty if it could not be parsed
+	Kind scm.WebhookKind
+	// Repository is the owner/name of the repository the webhook is for
+	Repository string
+	// Hook is the parsed webhook, nil if it could not be parsed
+	Hook       scm.Webhook
+	Headers    http.Header
+	Body       []byte
+	ReceivedAt time.Time
+	// Err is the error from parsing the webhook or validating its signature
+	Err error
+}
+
+// Matcher returns true if a delivery is the one being waited for
+type Matcher func(d *Delivery) bool
+
+// MatchKind matches deliveries of the given kind
+func MatchKind(kind scm.WebhookKind) Matcher {
+	return func(d *Delivery) bool {
+		return d.Kind == kind
+	}
+}
+
+// MatchRepository matches deliveries for the repository with the given owner/name
+func MatchRepository(fullName string) Matcher {
+	return func(d *Delivery) bool {
+		return d.Repository == fullName
+	}
+}
+
+// MatchReceivedAfter matches deliveries received after the given time
+func MatchReceivedAfter(since time.Time) Matcher {
+	return func(d *Delivery) bool {
+		return d.ReceivedAt.After(since)
+	}
+}
+
+// MatchAll matches deliveries which match all the given matchers
+func MatchAll(matchers ...Matcher) Matcher {
+	return func(d *Delivery) bool {
+		for _, m := range matchers {
+			if !m(d) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Receiver is an HTTP handler which records the webhooks delivered to it, validating each one against the HMAC token
+type Receiver struct {
+	driver  string
+	token   string
+	service scm.WebhookService
+
+	lock       sync.Mutex
+	deliveries []*Delivery
+	notify     chan struct{}
+
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewReceiver creates a receiver for webhooks sent by the given go-scm driver signed with the given token
+func NewReceiver(driver string, token string) (*Receiver, error) {
+	service, err := WebhookService(driver)
+	if err != nil {
+		return nil, err
+	}
+	return &Receiver{
+		driver:  driver,
+		token:   token,
+		service: service,
+		notify:  make(chan struct{}),
+	}, nil
+}
+
+// WebhookService returns the go-scm webhook parser for the given driver
+func WebhookService(driver string) (scm.WebhookService, error) {
+	switch driver {
+	case DriverGitHub:
+		return github.NewDefault().Webhooks, nil
+	case DriverGitLab:
+		return gitlab.NewDefault().Webhooks, nil
+	case DriverStash:
+		return stash.NewDefault().Webhooks, nil
+	default:
+		return nil, fmt.Errorf("unsupported webhook driver %s", driver)
+	}
+}
+
+// Start listens for webhooks on the given address, such as ":8888", until Close is called
+func (r *Receiver) Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return errors.Wrapf(err, "listening for webhooks on %s", address)
+	}
+	r.listener = listener
+	r.server = &http.Server{Handler: r}
+	go r.server.Serve(listener) //nolint:errcheck
+	return nil
+}
+
+// Address returns the address the receiver is listening on, empty if it has not been started
+func (r *Receiver) Address() string {
+	if r.listener == nil {
+		return ""
+	}
+	return r.listener.Addr().String()
+}
+
+// Close stops the receiver listening for webhooks
+func (r *Receiver) Close() error {
+	if r.server == nil {
+		return nil
+	}
+	return r.server.Close()
+}
+
+// ServeHTTP records the delivered webhook, responding with an error status if it is invalid
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d := &Delivery{
+		Event:      req.Header.Get(eventHeaders[r.driver]),
+		Headers:    req.Header,
+		Body:       body,
+		ReceivedAt: time.Now(),
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	d.Hook, d.Err = r.service.Parse(req, func(scm.Webhook) (string, error) {
+		return r.token, nil
+	})
+	if d.Hook != nil {
+		d.Kind = d.Hook.Kind()
+		repo := d.Hook.Repository()
+		d.Repository = scm.Join(repo.Namespace, repo.Name)
+	}
+	r.record(d)
+
+	if d.Err != nil {
+		http.Error(w, d.Err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *Receiver) record(d *Delivery) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.deliveries = append(r.deliveries, d)
+	close(r.notify)
+	r.notify = make(chan struct{})
+}
+
+// Deliveries returns the webhooks received so far, oldest first
+func (r *Receiver) Deliveries() []*Delivery {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]*Delivery{}, r.deliveries...)
+}
+
+// WaitFor waits for a delivery matching the given matcher, returning an error if none arrives within the timeout or the
+// matching delivery failed validation
+func (r *Receiver) WaitFor(timeout time.Duration, matcher Matcher) (*Delivery, error) {
+	deadline := time.After(timeout)
+	seen := 0
+	for {
+		r.lock.Lock()
+		deliveries := r.deliveries[seen:]
+		notify := r.notify
+		seen = len(r.deliveries)
+		r.lock.Unlock()
+
+		for _, d := range deliveries {
+			if matcher(d) {
+				if d.Err != nil {
+					return d, errors.Wrapf(d.Err, "invalid %s webhook for %s", d.Event, d.Repository)
+				}
+				return d, nil
+			}
+		}
+		select {
+		case <-notify:
+		case <-deadline:
+			return nil, fmt.Errorf("no matching webhook received within %s out of %d deliveries", timeout, seen)
+		}
+	}
+}
+
+// Sign returns the headers which authenticate a webhook body sent by the given driver with the given token
+func Sign(driver string, body []byte, token string) (http.Header, error) {
+	headers := http.Header{}
+	switch driver {
+	case DriverGitHub:
+		headers.Set("X-Hub-Signature", "sha1="+hexHMAC(sha1.New, body, token))
+	case DriverStash:
+		headers.Set("X-Hub-Signature", "sha256="+hexHMAC(sha256.New, body, token))
+	case DriverGitLab:
+		headers.Set("X-Gitlab-Token", token)
+	default:
+		return nil, fmt.Errorf("unsupported webhook driver %s", driver)
+	}
+	return headers, nil
+}
+
+func hexHMAC(h func() hash.Hash, body []byte, token string) string {
+	mac := hmac.New(h, []byte(token))
+	mac.Write(body) //nolint:errcheck
+	return hex.EncodeToString(mac.Sum(nil))
+}
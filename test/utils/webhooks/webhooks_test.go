@@ -0,0 +1,142 @@
+package webhooks_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/bdd-jx/test/utils/webhooks"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const token = "my-hmac-token"
+
+func TestReceiverParsesRecordedWebhooks(t *testing.T) {
+	tests := []struct {
+		file       string
+		kind       scm.WebhookKind
+		repository string
+	}{
+		{file: "github_pr_opened.json", kind: scm.WebhookKindPullRequest, repository: "bradrydzewski/drone-test-go"},
+		{file: "github_pr_labeled.json", kind: scm.WebhookKindPullRequest, repository: "bradrydzewski/drone-test-go"},
+		{file: "github_issue_comment.json", kind: scm.WebhookKindIssueComment, repository: "Codertocat/Hello-World"},
+		{file: "github_push.json", kind: scm.WebhookKindPush, repository: "Codertocat/Hello-World"},
+		{file: "gitlab_pr_comment.json", kind: scm.WebhookKindPullRequestComment, repository: "gitlab-org/hello-world"},
+		{file: "stash_pr_open.json", kind: scm.WebhookKindPullRequest, repository: "PRJ/my-repo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			recording, err := webhooks.LoadRecording(filepath.Join("testdata", tt.file))
+			require.NoError(t, err)
+
+			receiver, err := webhooks.NewReceiver(recording.Driver, token)
+			require.NoError(t, err)
+
+			req, err := recording.Request("http://localhost/hook", token)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			receiver.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			d, err := receiver.WaitFor(time.Second, webhooks.MatchAll(webhooks.MatchKind(tt.kind), webhooks.MatchRepository(tt.repository)))
+			require.NoError(t, err)
+			assert.NotNil(t, d.Hook)
+			assert.NotEmpty(t, d.Event)
+		})
+	}
+}
+
+func TestReceiverRejectsInvalidSignatures(t *testing.T) {
+	for _, file := range []string{"github_push.json", "gitlab_pr_comment.json", "stash_pr_open.json"} {
+		t.Run(file, func(t *testing.T) {
+			recording, err := webhooks.LoadRecording(filepath.Join("testdata", file))
+			require.NoError(t, err)
+
+			receiver, err := webhooks.NewReceiver(recording.Driver, token)
+			require.NoError(t, err)
+
+			req, err := recording.Request("http://localhost/hook", "wrong-token")
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			receiver.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			deliveries := receiver.Deliveries()
+			require.Len(t, deliveries, 1)
+			assert.Error(t, deliveries[0].Err)
+
+			_, err = receiver.WaitFor(time.Second, func(*webhooks.Delivery) bool { return true })
+			assert.Error(t, err, "an invalid delivery should fail the wait")
+		})
+	}
+}
+
+func TestReceiverRejectsTamperedBody(t *testing.T) {
+	recording, err := webhooks.LoadRecording(filepath.Join("testdata", "github_push.json"))
+	require.NoError(t, err)
+	receiver, err := webhooks.NewReceiver(recording.Driver, token)
+	require.NoError(t, err)
+
+	signature, err := webhooks.Sign(recording.Driver, recording.Body, token)
+	require.NoError(t, err)
+	recording.Body = append(recording.Body[:len(recording.Body)-1], []byte(`, "extra": true}`)...)
+	req, err := recording.Request("http://localhost/hook", token)
+	require.NoError(t, err)
+	req.Header.Set("X-Hub-Signature", signature.Get("X-Hub-Signature"))
+
+	w := httptest.NewRecorder()
+	receiver.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestReceiverWaitForTimesOut(t *testing.T) {
+	receiver, err := webhooks.NewReceiver(webhooks.DriverGitHub, token)
+	require.NoError(t, err)
+	_, err = receiver.WaitFor(10*time.Millisecond, webhooks.MatchKind(scm.WebhookKindPush))
+	assert.Error(t, err)
+}
+
+func TestReceiverReplayOverHTTP(t *testing.T) {
+	recording, err := webhooks.LoadRecording(filepath.Join("testdata", "github_pr_opened.json"))
+	require.NoError(t, err)
+	receiver, err := webhooks.NewReceiver(recording.Driver, token)
+	require.NoError(t, err)
+	require.NoError(t, receiver.Start("127.0.0.1:0"))
+	defer receiver.Close()
+
+	err = recording.Replay("http://"+receiver.Address()+"/hook", token)
+	require.NoError(t, err)
+
+	deliveries := receiver.Deliveries()
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, scm.WebhookKindPullRequest, deliveries[0].Kind)
+
+	saved := filepath.Join(t.TempDir(), "saved.json")
+	require.NoError(t, webhooks.NewRecording(recording.Driver, deliveries[0]).Save(saved))
+	reloaded, err := webhooks.LoadRecording(saved)
+	require.NoError(t, err)
+	assert.Empty(t, reloaded.Headers["X-Hub-Signature"], "signatures should not be recorded")
+	assert.Equal(t, "pull_request", reloaded.Headers["X-Github-Event"])
+}
+
+func TestParseHookLog(t *testing.T) {
+	log := `{"level":"info","msg":"Pull request opened.","Webhook":"pull_request","Namespace":"jenkins-x-bdd","Name":"bdd-gh-1","time":"2020-10-01T10:00:00Z"}
+{"level":"info","msg":"Lighthouse is now listening on path /hook and port 8080 for WebHooks"}
+not a log line
+time="2020-10-01T10:00:01Z" level=info msg="Issue comment created." Name=bdd-gh-1 Namespace=jenkins-x-bdd Webhook=issue_comment author="bot user"
+`
+	entries := webhooks.ParseHookLog(log)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, scm.WebhookKindPullRequest, entries[0].Kind)
+	assert.Equal(t, "jenkins-x-bdd/bdd-gh-1", entries[0].Repository())
+	assert.Equal(t, "Pull request opened.", entries[0].Message)
+
+	assert.Equal(t, scm.WebhookKindIssueComment, entries[1].Kind)
+	assert.Equal(t, "jenkins-x-bdd/bdd-gh-1", entries[1].Repository())
+	assert.Equal(t, "Issue comment created.", entries[1].Message)
+}